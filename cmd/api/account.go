@@ -0,0 +1,236 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/xtommas/food-backend/internal/data"
+	"github.com/xtommas/food-backend/internal/images"
+	"github.com/xtommas/food-backend/internal/storage"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+// exportedPhoto describes a photo included in a data export. File is its path
+// inside the ZIP
+type exportedPhoto struct {
+	Key  string `json:"key"`
+	File string `json:"file"`
+}
+
+// userExportTimeout is how long a data export may take to gather and send
+const userExportTimeout = 5 * time.Minute
+
+// userExport is everything stored about a user, gathered before the ZIP is
+// started so failures can still be reported as JSON errors
+type userExport struct {
+	profile   envelope
+	orders    []fullOrder
+	addresses []*data.Address
+	photos    []exportedPhoto
+	files     map[string]*storage.Object
+}
+
+func (app *application) exportUserDataHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	// exports with many orders or a large photo can take longer to send than
+	// the server's WriteTimeout allows
+	app.extendWriteDeadline(w, r, userExportTimeout)
+
+	ctx, cancel := context.WithTimeout(r.Context(), userExportTimeout)
+	defer cancel()
+
+	export, err := app.gatherUserExport(ctx, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer func() {
+		for _, object := range export.files {
+			object.Body.Close()
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-export-%s.zip"`, user.Id, time.Now().UTC().Format(time.DateOnly)))
+
+	err = writeUserExport(w, export)
+	if err != nil {
+		// part of the file has already been sent and the status can't change, so
+		// abort the connection like the order export does
+		app.logError(r, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (app *application) gatherUserExport(ctx context.Context, user *data.User) (*userExport, error) {
+	permissions, err := app.models.Permissions.GetAllForUser(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &userExport{
+		profile: envelope{"user": user, "permissions": permissions},
+		files:   map[string]*storage.Object{},
+	}
+
+	// orders are read a page at a time, as for the order list
+	filters := data.Filters{Page: 1, PageSize: 100, Sort: "id", SortSafelist: []string{"id"}}
+
+	for {
+		orders, metadata, err := app.models.Orders.GetAllForUser(ctx, user.Id, "", filters)
+		if err != nil {
+			return nil, err
+		}
+
+		fullOrders, err := app.withItems(ctx, orders)
+		if err != nil {
+			return nil, err
+		}
+
+		export.orders = append(export.orders, fullOrders...)
+
+		if filters.Page >= metadata.LastPage {
+			break
+		}
+		filters.Page++
+	}
+
+	export.addresses, err = app.models.Addresses.GetAllForUser(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Photo != "" {
+		object, err := app.storage.Get(ctx, images.VariantKey(user.Photo, images.SizeLarge))
+		if errors.Is(err, storage.ErrNotFound) {
+			// photos uploaded before sizes were introduced only have the original
+			object, err = app.storage.Get(ctx, user.Photo)
+		}
+
+		switch {
+		case err == nil:
+			file := "photos/" + path.Base(user.Photo)
+			export.files[file] = object
+			export.photos = append(export.photos, exportedPhoto{Key: user.Photo, File: file})
+		case !errors.Is(err, storage.ErrNotFound):
+			return nil, err
+		}
+	}
+
+	return export, nil
+}
+
+// writeUserExport writes the export as a ZIP with a JSON file for each kind of
+// data, plus the photo files themselves
+func writeUserExport(w io.Writer, export *userExport) error {
+	zw := zip.NewWriter(w)
+
+	documents := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", export.profile},
+		{"orders.json", envelope{"orders": export.orders}},
+		{"addresses.json", envelope{"addresses": export.addresses}},
+		{"photos.json", envelope{"photos": export.photos}},
+	}
+
+	for _, document := range documents {
+		fw, err := zw.Create(document.name)
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "\t")
+
+		err = enc.Encode(document.data)
+		if err != nil {
+			return err
+		}
+	}
+
+	for name, object := range export.files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: object.ModTime})
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(fw, object.Body)
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(input.Password != "", "password", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// a stolen token alone isn't enough to delete the account
+	match, err := user.Password.Matches(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !match {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	photo := user.Photo
+
+	err = app.models.Users.Anonymize(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrdersInProgress):
+			app.ordersInProgressResponse(w, r)
+		case errors.Is(err, data.ErrLastAdmin):
+			v.AddError("role", "the last admin cannot delete their account")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// the account is already gone, so a photo that can't be deleted is only logged
+	err = app.deletePhoto(context.WithoutCancel(r.Context()), photo)
+	if err != nil {
+		app.logError(r, fmt.Errorf("deleting photo of deleted user %d: %w", user.Id, err))
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "your account was successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/xtommas/food-backend/internal/data"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+func (app *application) listAddressesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	addresses, err := app.models.Addresses.GetAllForUser(r.Context(), user.Id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"addresses": addresses}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createAddressHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Label        string   `json:"label"`
+		Street       string   `json:"street"`
+		City         string   `json:"city"`
+		PostalCode   string   `json:"postal_code"`
+		Latitude     *float64 `json:"latitude"`
+		Longitude    *float64 `json:"longitude"`
+		Instructions string   `json:"instructions"`
+		IsDefault    bool     `json:"is_default"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	address := &data.Address{
+		UserID:       user.Id,
+		Label:        input.Label,
+		Street:       input.Street,
+		City:         input.City,
+		PostalCode:   input.PostalCode,
+		Instructions: input.Instructions,
+		IsDefault:    input.IsDefault,
+	}
+
+	v := validator.New()
+
+	v.Check((input.Latitude == nil) == (input.Longitude == nil), "latitude", "must be provided together with longitude")
+	if input.Latitude != nil && input.Longitude != nil {
+		address.Latitude = *input.Latitude
+		address.Longitude = *input.Longitude
+		address.HasCoordinates = true
+	}
+
+	if data.ValidateUserAddress(v, address); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Addresses.Insert(r.Context(), address)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/users/me/addresses/%d", address.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"address": address}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showAddressHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIdParam(r, "address_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	address, err := app.models.Addresses.Get(r.Context(), id, user.Id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"address": address}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateAddressHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIdParam(r, "address_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	address, err := app.models.Addresses.Get(r.Context(), id, user.Id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Label        *string  `json:"label"`
+		Street       *string  `json:"street"`
+		City         *string  `json:"city"`
+		PostalCode   *string  `json:"postal_code"`
+		Latitude     *float64 `json:"latitude"`
+		Longitude    *float64 `json:"longitude"`
+		Instructions *string  `json:"instructions"`
+		IsDefault    *bool    `json:"is_default"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Label != nil {
+		address.Label = *input.Label
+	}
+	if input.Street != nil {
+		address.Street = *input.Street
+	}
+	if input.City != nil {
+		address.City = *input.City
+	}
+	if input.PostalCode != nil {
+		address.PostalCode = *input.PostalCode
+	}
+	if input.Instructions != nil {
+		address.Instructions = *input.Instructions
+	}
+	if input.IsDefault != nil {
+		address.IsDefault = *input.IsDefault
+	}
+
+	v := validator.New()
+
+	// an address that already has coordinates can have either one corrected on its own
+	if input.Latitude != nil || input.Longitude != nil {
+		v.Check(address.HasCoordinates || (input.Latitude != nil && input.Longitude != nil), "latitude", "must be provided together with longitude")
+		if input.Latitude != nil {
+			address.Latitude = *input.Latitude
+		}
+		if input.Longitude != nil {
+			address.Longitude = *input.Longitude
+		}
+		address.HasCoordinates = true
+	}
+
+	if data.ValidateUserAddress(v, address); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Addresses.Update(r.Context(), address)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"address": address}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteAddressHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIdParam(r, "address_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Addresses.Delete(r.Context(), id, user.Id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "address successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/xtommas/food-backend/internal/data"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+// rolePermissions are the codes granted to a user when they're given a role.
+// Changing role only grants codes, so anything granted by hand or for
+// restaurant staff is kept
+var rolePermissions = map[string][]string{
+	"customer": {"dishes:read", "restaurants:read"},
+	"courier":  {"orders:read", "orders:write"},
+	"admin":    data.PermissionCodes,
+}
+
+func (app *application) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.UserFilters
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Search = app.readString(qs, "q", "")
+	input.Role = app.readString(qs, "role", "")
+	input.Status = app.readString(qs, "status", "")
+
+	if input.Role != "" {
+		data.ValidateRole(v, input.Role)
+	}
+	if input.Status != "" {
+		v.Check(validator.PermittedValue(input.Status, data.UserStatusActive, data.UserStatusSuspended, data.UserStatusBanned), "status", "must be one of active, suspended or banned")
+	}
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+
+	input.Filters.SortSafelist = []string{"id", "name", "email", "created_at", "-id", "-name", "-email", "-created_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	users, metadata, err := app.models.Users.GetAll(r.Context(), input.UserFilters, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"users": users, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readUser loads the user in the id path parameter, writing the error
+// response and returning nil when it can't
+func (app *application) readUser(w http.ResponseWriter, r *http.Request) *data.User {
+	id, err := app.readIdParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	user, err := app.models.Users.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	return user
+}
+
+func (app *application) showUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.readUser(w, r)
+	if user == nil {
+		return
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.Id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user, "permissions": permissions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) adminUpdateUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.readUser(w, r)
+	if user == nil {
+		return
+	}
+
+	before := *user
+
+	var input struct {
+		Name      *string `json:"name"`
+		Role      *string `json:"role"`
+		Activated *bool   `json:"activated"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+	if input.Role != nil {
+		user.Role = *input.Role
+	}
+	if input.Activated != nil {
+		user.Activated = *input.Activated
+	}
+
+	v := validator.New()
+
+	data.ValidateUser(v, user)
+	data.ValidateRole(v, user.Role)
+
+	if user.Id == app.contextGetUser(r).Id && user.Role != before.Role {
+		v.AddError("role", "you cannot change your own role")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if user.Role != before.Role {
+		err = app.models.Users.UpdateRole(r.Context(), user, rolePermissions[user.Role]...)
+	} else {
+		err = app.models.Users.Update(r.Context(), user)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrLastAdmin):
+			v.AddError("role", "cannot demote the last admin")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditUserUpdate, "user", user.Id, &before, user)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) suspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Reason string     `json:"reason"`
+		Until  *time.Time `json:"until"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateStatusReason(v, input.Reason)
+	if input.Until != nil {
+		v.Check(input.Until.After(time.Now()), "until", "must be in the future")
+	}
+
+	app.changeUserStatus(w, r, v, data.AuditUserSuspend, func(user *data.User) {
+		user.Suspend(input.Reason, input.Until)
+	})
+}
+
+func (app *application) banUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Reason string `json:"reason"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateStatusReason(v, input.Reason)
+
+	app.changeUserStatus(w, r, v, data.AuditUserBan, func(user *data.User) {
+		user.Ban(input.Reason)
+	})
+}
+
+func (app *application) reinstateUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.changeUserStatus(w, r, validator.New(), data.AuditUserReinstate, (*data.User).Reinstate)
+}
+
+// changeUserStatus applies change to the user in the id path parameter, once
+// the request input checked into v is valid. Admins have to be demoted before
+// they can be suspended or banned, which also keeps the last admin from being
+// locked out
+func (app *application) changeUserStatus(w http.ResponseWriter, r *http.Request, v *validator.Validator, action string, change func(*data.User)) {
+	user := app.readUser(w, r)
+	if user == nil {
+		return
+	}
+
+	before := *user
+	change(user)
+
+	if user.Status != data.UserStatusActive {
+		v.Check(user.Id != app.contextGetUser(r).Id, "id", "you cannot suspend or ban yourself")
+		v.Check(!user.IsAdmin(), "id", "admins must be demoted before they can be suspended or banned")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err := app.models.Users.Update(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, action, "user", user.Id, &before, user)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) addUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.readUser(w, r)
+	if user == nil {
+		return
+	}
+
+	var input struct {
+		Codes []string `json:"codes"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(len(input.Codes) > 0, "codes", "must contain at least 1 code")
+	v.Check(validator.Unique(input.Codes), "codes", "must not contain duplicate codes")
+	for _, code := range input.Codes {
+		if !validator.PermittedValue(code, data.PermissionCodes...) {
+			v.AddError("codes", "must only contain known permission codes")
+			break
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	before, err := app.models.Permissions.GetAllForUser(r.Context(), user.Id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Permissions.AddForUser(r.Context(), user.Id, input.Codes...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.Id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, data.AuditPermissionsGrant, "user", user.Id, envelope{"permissions": before}, envelope{"permissions": permissions})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"permissions": permissions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteUserPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.readUser(w, r)
+	if user == nil {
+		return
+	}
+
+	code := r.PathValue("code")
+	if !slices.Contains(data.PermissionCodes, code) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err := app.models.Permissions.DeleteForUser(r.Context(), user.Id, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditPermissionsRevoke, "user", user.Id, envelope{"permission": code}, nil)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "permission successfully removed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/xtommas/food-backend/internal/data"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+func (app *application) restaurantAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := app.readIdParam(r, "restaurant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Restaurants.Get(r.Context(), restaurantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	// both dates are inclusive and default to the last 30 days
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to := app.readDate(qs, "to", today, v)
+	from := app.readDate(qs, "from", to.AddDate(0, 0, -29), v)
+
+	filters := data.AnalyticsFilters{
+		From:      from,
+		To:        to.AddDate(0, 0, 1),
+		Interval:  app.readString(qs, "interval", data.IntervalDay),
+		TopDishes: app.readInt(qs, "top_dishes", 10, v),
+	}
+
+	if data.ValidateAnalyticsFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	analytics, err := app.models.Analytics.GetForRestaurant(r.Context(), restaurantID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"analytics": analytics}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tomasen/realip"
+	"github.com/xtommas/food-backend/internal/data"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+// audit records a privileged action taken by the authenticated user. before
+// and after are the target before and after the action, nil for creations and
+// deletions, and only the fields that differ between them are stored. The
+// action has already been carried out, so failures are logged rather than
+// returned
+func (app *application) audit(r *http.Request, action, targetType string, targetID int64, before, after any) {
+	entry := &data.AuditEntry{
+		ActorID:    app.contextGetUser(r).Id,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         realip.FromRequest(r),
+	}
+
+	err := entry.SetChanges(before, after)
+	if err == nil {
+		// still recorded when the client disconnects right after the action
+		err = app.models.AuditLog.Insert(context.WithoutCancel(r.Context()), entry)
+	}
+
+	if err != nil {
+		app.logError(r, fmt.Errorf("recording audit entry %q: %w", action, err))
+	}
+}
+
+func (app *application) listAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.AuditLogFilters
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.ActorID = int64(app.readInt(qs, "actor_id", 0, v))
+	input.Action = app.readString(qs, "action", "")
+
+	// both dates are inclusive, and the log isn't restricted when they're left out
+	input.From = app.readDate(qs, "from", time.Time{}, v)
+	to := app.readDate(qs, "to", time.Time{}, v)
+
+	if !to.IsZero() {
+		v.Check(input.From.IsZero() || !input.From.After(to), "to", "must not be before from")
+		input.To = to.AddDate(0, 0, 1)
+	}
+
+	if input.Action != "" {
+		v.Check(validator.PermittedValue(input.Action, data.AuditActions...), "action", "must be a known audit action")
+	}
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+
+	input.Filters.SortSafelist = []string{"id", "created_at", "-id", "-created_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	entries, metadata, err := app.models.AuditLog.GetAll(r.Context(), input.AuditLogFilters, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"audit_log": entries, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
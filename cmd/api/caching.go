@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xtommas/food-backend/internal/data"
+	"github.com/xtommas/food-backend/internal/storage"
+)
+
+// responses depend on the authenticated user, so shared caches must not store
+// them, and clients revalidate them on every use
+const cacheControl = "private, no-cache"
+
+// dishETag changes whenever the dish is updated, and whenever its rating or the
+// user's favorite flag, which are not part of the dishes row, change
+func dishETag(dish *data.Dish) string {
+	return fmt.Sprintf(`W/"dish-%d-%d-%d-%g-%t"`, dish.ID, dish.UpdatedAt.Unix(), dish.RatingCount, dish.Rating, dish.IsFavorite)
+}
+
+// restaurantETag is like dishETag, using the version restaurants keep for
+// optimistic locking in place of a modification time
+func restaurantETag(restaurant *data.Restaurant) string {
+	return fmt.Sprintf(`W/"restaurant-%d-%d-%d-%g-%t"`, restaurant.ID, restaurant.Version, restaurant.RatingCount, restaurant.Rating, restaurant.IsFavorite)
+}
+
+// objectETag identifies the contents of a stored file by when it was written and its size
+func objectETag(object *storage.Object) string {
+	return fmt.Sprintf(`"%x-%x"`, object.ModTime.UnixNano(), object.Size)
+}
+
+// checkNotModified sets the ETag, Last-Modified and Cache-Control headers of a
+// response. When the request's If-None-Match or If-Modified-Since headers show
+// the client's copy is current, it responds 304 Not Modified and returns true,
+// and the handler must not write a body. A zero lastModified is not sent
+func (app *application) checkNotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", etag)
+
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if !isNotModified(r, etag, lastModified) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// isNotModified evaluates the conditional headers of a GET request. As in RFC
+// 9110, If-Modified-Since is ignored when If-None-Match is present
+func isNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	// HTTP dates only have second precision
+	return !lastModified.Truncate(time.Second).After(t)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using the weak
+// comparison, which ignores the W/ prefix
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -5,13 +5,30 @@ import (
 	"net/http"
 
 	"github.com/xtommas/food-backend/internal/data"
+	"github.com/xtommas/food-backend/internal/jsonlog"
 )
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey    = contextKey("user")
+	requestContextKey = contextKey("request")
+)
+
+// requestInfo is set up by the logRequest middleware for every request. It's
+// shared by pointer, so what later middleware learn about the request, such as
+// the user, also reaches the access log
+type requestInfo struct {
+	id     string
+	logger *jsonlog.Logger
+	userID int64
+}
 
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
+	if info := app.contextGetRequestInfo(r); info != nil && !user.IsAnonymous() {
+		info.userID = user.Id
+	}
+
 	ctx := context.WithValue(r.Context(), userContextKey, user)
 	return r.WithContext(ctx)
 }
@@ -24,3 +41,24 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 
 	return user
 }
+
+func (app *application) contextSetRequestInfo(r *http.Request, info *requestInfo) *http.Request {
+	ctx := context.WithValue(r.Context(), requestContextKey, info)
+	return r.WithContext(ctx)
+}
+
+// contextGetRequestInfo returns nil for requests that didn't go through logRequest
+func (app *application) contextGetRequestInfo(r *http.Request) *requestInfo {
+	info, _ := r.Context().Value(requestContextKey).(*requestInfo)
+	return info
+}
+
+// requestLogger returns the logger of the request, whose entries carry its
+// request ID, falling back to the application logger
+func (app *application) requestLogger(r *http.Request) *jsonlog.Logger {
+	if info := app.contextGetRequestInfo(r); info != nil {
+		return info.logger
+	}
+
+	return app.logger
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/xtommas/food-backend/internal/data"
+	"github.com/xtommas/food-backend/internal/geo"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+func (app *application) listDeliveryZonesHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := app.readIdParam(r, "restaurant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Restaurants.Get(r.Context(), restaurantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	zones, err := app.models.DeliveryZones.GetAllForRestaurant(r.Context(), restaurantID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"delivery_zones": zones}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createDeliveryZoneHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := app.readIdParam(r, "restaurant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Restaurants.Get(r.Context(), restaurantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name            string      `json:"name"`
+		Kind            string      `json:"kind"`
+		Polygon         []geo.Point `json:"polygon"`
+		CenterLatitude  float64     `json:"center_latitude"`
+		CenterLongitude float64     `json:"center_longitude"`
+		RadiusMeters    int         `json:"radius_meters"`
+		Fee             int64       `json:"fee"`
+		MinimumOrder    int64       `json:"minimum_order"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	zone := &data.DeliveryZone{
+		RestaurantID:    restaurantID,
+		Name:            input.Name,
+		Kind:            input.Kind,
+		Polygon:         input.Polygon,
+		CenterLatitude:  input.CenterLatitude,
+		CenterLongitude: input.CenterLongitude,
+		RadiusMeters:    input.RadiusMeters,
+		Fee:             input.Fee,
+		MinimumOrder:    input.MinimumOrder,
+	}
+
+	v := validator.New()
+
+	if data.ValidateDeliveryZone(v, zone); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.DeliveryZones.Insert(r.Context(), zone)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, data.AuditDeliveryZoneCreate, "delivery_zone", zone.ID, nil, zone)
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/restaurants/%d/delivery-zones/%d", restaurantID, zone.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"delivery_zone": zone}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showDeliveryZoneHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := app.readIdParam(r, "restaurant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	zoneID, err := app.readIdParam(r, "zone_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	zone, err := app.models.DeliveryZones.Get(r.Context(), zoneID, restaurantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"delivery_zone": zone}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateDeliveryZoneHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := app.readIdParam(r, "restaurant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	zoneID, err := app.readIdParam(r, "zone_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	zone, err := app.models.DeliveryZones.Get(r.Context(), zoneID, restaurantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	before := *zone
+
+	var input struct {
+		Name            *string     `json:"name"`
+		Kind            *string     `json:"kind"`
+		Polygon         []geo.Point `json:"polygon"`
+		CenterLatitude  *float64    `json:"center_latitude"`
+		CenterLongitude *float64    `json:"center_longitude"`
+		RadiusMeters    *int        `json:"radius_meters"`
+		Fee             *int64      `json:"fee"`
+		MinimumOrder    *int64      `json:"minimum_order"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// switching shapes discards the old one, so the new shape has to be sent in full
+	if input.Kind != nil && *input.Kind != zone.Kind {
+		zone.Kind = *input.Kind
+		zone.Polygon = nil
+		zone.CenterLatitude = 0
+		zone.CenterLongitude = 0
+		zone.RadiusMeters = 0
+	}
+
+	if input.Name != nil {
+		zone.Name = *input.Name
+	}
+	if input.Polygon != nil {
+		zone.Polygon = input.Polygon
+	}
+	if input.CenterLatitude != nil {
+		zone.CenterLatitude = *input.CenterLatitude
+	}
+	if input.CenterLongitude != nil {
+		zone.CenterLongitude = *input.CenterLongitude
+	}
+	if input.RadiusMeters != nil {
+		zone.RadiusMeters = *input.RadiusMeters
+	}
+	if input.Fee != nil {
+		zone.Fee = *input.Fee
+	}
+	if input.MinimumOrder != nil {
+		zone.MinimumOrder = *input.MinimumOrder
+	}
+
+	v := validator.New()
+
+	if data.ValidateDeliveryZone(v, zone); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.DeliveryZones.Update(r.Context(), zone)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditDeliveryZoneUpdate, "delivery_zone", zone.ID, &before, zone)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"delivery_zone": zone}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteDeliveryZoneHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := app.readIdParam(r, "restaurant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	zoneID, err := app.readIdParam(r, "zone_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	zone, err := app.models.DeliveryZones.Get(r.Context(), zoneID, restaurantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.DeliveryZones.Delete(r.Context(), zoneID, restaurantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditDeliveryZoneDelete, "delivery_zone", zone.ID, zone, nil)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "delivery zone successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -4,12 +4,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"io"
-	"mime"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/xtommas/food-backend/internal/data"
 	"github.com/xtommas/food-backend/internal/validator"
@@ -22,7 +19,7 @@ func (app *application) createDishHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	_, err = app.models.Restaurants.Get(restaurantID)
+	_, err = app.models.Restaurants.Get(r.Context(), restaurantID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -61,12 +58,14 @@ func (app *application) createDishHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Dishes.Insert(dish)
+	err = app.models.Dishes.Insert(r.Context(), dish)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.audit(r, data.AuditDishCreate, "dish", dish.ID, nil, dish)
+
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/restaurants/%d/dishes/%d", dish.RestaurantID, dish.ID))
 
@@ -89,7 +88,7 @@ func (app *application) showDishHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = app.models.Restaurants.Get(restaurantID)
+	_, err = app.models.Restaurants.Get(r.Context(), restaurantID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -100,7 +99,7 @@ func (app *application) showDishHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	dish, err := app.models.Dishes.Get(id)
+	dish, err := app.models.Dishes.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -116,6 +115,20 @@ func (app *application) showDishHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	user := app.contextGetUser(r)
+
+	dish.IsFavorite, err = app.models.Favorites.IsFavoriteDish(r.Context(), user.Id, dish.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Last-Modified would miss rating and favourite changes, so like restaurants
+	// dishes are only revalidated by their ETag
+	if app.checkNotModified(w, r, dishETag(dish), time.Time{}) {
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"dish": dish}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -135,7 +148,7 @@ func (app *application) updateDishHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	dish, err := app.models.Dishes.Get(id)
+	dish, err := app.models.Dishes.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -166,6 +179,8 @@ func (app *application) updateDishHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	before := *dish
+
 	if input.Name != nil {
 		dish.Name = *input.Name
 	}
@@ -192,7 +207,7 @@ func (app *application) updateDishHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Dishes.Update(dish)
+	err = app.models.Dishes.Update(r.Context(), dish)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -203,6 +218,8 @@ func (app *application) updateDishHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.audit(r, data.AuditDishUpdate, "dish", dish.ID, &before, dish)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"dish": dish}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -222,7 +239,7 @@ func (app *application) deleteDishHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	dish, err := app.models.Dishes.Get(id)
+	dish, err := app.models.Dishes.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -238,7 +255,14 @@ func (app *application) deleteDishHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Dishes.Delete(id)
+	// the gallery rows are deleted along with the dish, but not their files
+	galleryImages, err := app.models.DishImages.GetAll(r.Context(), dish.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Dishes.Delete(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -249,13 +273,17 @@ func (app *application) deleteDishHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if dish.Photo != "" {
-		if _, err := os.Stat(dish.Photo); err == nil {
-			if err := os.Remove(dish.Photo); err != nil {
-				app.serverErrorResponse(w, r, err)
-				return
-			}
-		}
+	app.audit(r, data.AuditDishDelete, "dish", dish.ID, dish, nil)
+
+	// the dish is already gone, so files that can't be removed are only logged
+	err = app.deletePhoto(r.Context(), dish.Photo)
+	if err != nil {
+		app.logError(r, err)
+	}
+
+	err = app.deleteGalleryPhotos(r.Context(), galleryImages)
+	if err != nil {
+		app.logError(r, err)
 	}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "dish successfully deleted"}, nil)
@@ -271,7 +299,7 @@ func (app *application) listDishesHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	_, err = app.models.Restaurants.Get(restaurantID)
+	_, err = app.models.Restaurants.Get(r.Context(), restaurantID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -307,7 +335,9 @@ func (app *application) listDishesHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	dishes, metadata, err := app.models.Dishes.GetAllForRestaurant(restaurantID, input.Name, input.Categories, input.Available, input.Filters)
+	user := app.contextGetUser(r)
+
+	dishes, metadata, err := app.models.Dishes.GetAllForRestaurant(r.Context(), restaurantID, user.Id, input.Name, input.Categories, input.Available, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -332,7 +362,7 @@ func (app *application) uploadPhotoHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	dish, err := app.models.Dishes.Get(id)
+	dish, err := app.models.Dishes.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -354,17 +384,30 @@ func (app *application) uploadPhotoHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	fileName := strconv.FormatInt(dish.ID, 10) + ".jpg"
-	folder := "images/dishes/"
+	v := validator.New()
+	previous := dish.Photo
 
-	dish.Photo, err = app.storeImage(w, r, folder, fileName)
+	dish.Photo, err = app.storePhoto(r, "dishes/"+strconv.FormatInt(dish.ID, 10), v)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Dishes.Update(dish)
+	err = app.models.Dishes.Update(r.Context(), dish)
 	if err != nil {
+		// the row still points to the previous photo. An upload in the same
+		// format has already replaced its files, so only another key is removed
+		if dish.Photo != previous {
+			if deleteErr := app.deletePhoto(r.Context(), dish.Photo); deleteErr != nil {
+				app.logError(r, deleteErr)
+			}
+		}
+
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
@@ -374,6 +417,17 @@ func (app *application) uploadPhotoHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	app.audit(r, data.AuditDishUpdate, "dish", dish.ID, envelope{"photo": previous}, envelope{"photo": dish.Photo})
+
+	// a new upload in another format leaves the old sizes behind. The dish
+	// already points to the new photo, so failing to remove them is only logged
+	if previous != dish.Photo {
+		err = app.deletePhoto(r.Context(), previous)
+		if err != nil {
+			app.logError(r, err)
+		}
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"dish": dish}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -393,7 +447,7 @@ func (app *application) servePhotoHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	dish, err := app.models.Dishes.Get(id)
+	dish, err := app.models.Dishes.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -409,18 +463,5 @@ func (app *application) servePhotoHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	imageFile, err := os.Open(dish.Photo)
-	if err != nil {
-		app.notFoundResponse(w, r)
-		return
-	}
-	defer imageFile.Close()
-
-	contentType := mime.TypeByExtension(filepath.Ext(dish.Photo))
-	w.Header().Set("Content-Type", contentType)
-
-	_, err = io.Copy(w, imageFile)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-	}
+	app.servePhoto(w, r, dish.Photo)
 }
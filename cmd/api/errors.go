@@ -3,13 +3,18 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"time"
+
+	"github.com/xtommas/food-backend/internal/data"
 )
 
 func (app *application) logError(r *http.Request, err error) {
-	app.logger.PrintError(err, map[string]string{
+	properties := map[string]string{
 		"request_method": r.Method,
 		"request_url":    r.URL.String(),
-	})
+	}
+
+	app.requestLogger(r).PrintError(err, properties)
 }
 
 // sends JSON formatted error messages and a given status code
@@ -54,11 +59,36 @@ func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.
 	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
 }
 
+// failedRowValidationResponse reports validation errors for the rows of an uploaded file
+func (app *application) failedRowValidationResponse(w http.ResponseWriter, r *http.Request, rows []rowError) {
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, envelope{"rows": rows})
+}
+
 func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
 	message := "unable to update the record due to an edit conflict, please try again"
 	app.errorResponse(w, r, http.StatusConflict, message)
 }
 
+func (app *application) orderAlreadyClaimedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this order has already been claimed by another courier"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) duplicateReviewResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this order has already been reviewed"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) reviewAlreadyRepliedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this review already has a reply"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) ordersInProgressResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your account can't be deleted while you have orders in progress"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
 	app.errorResponse(w, r, http.StatusTooManyRequests, message)
@@ -86,6 +116,36 @@ func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.R
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
 
+func (app *application) accountSuspendedResponse(w http.ResponseWriter, r *http.Request, user *data.User) {
+	message := "your user account has been suspended"
+	if user.SuspendedUntil != nil {
+		message += " until " + user.SuspendedUntil.UTC().Format(time.RFC3339)
+	}
+	message += ": " + user.StatusReason
+
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) accountBannedResponse(w http.ResponseWriter, r *http.Request, user *data.User) {
+	message := "your user account has been banned: " + user.StatusReason
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+// accountStatusResponse writes the error response for suspended and banned
+// users, returning false for active ones
+func (app *application) accountStatusResponse(w http.ResponseWriter, r *http.Request, user *data.User) bool {
+	switch user.Status {
+	case data.UserStatusSuspended:
+		app.accountSuspendedResponse(w, r, user)
+	case data.UserStatusBanned:
+		app.accountBannedResponse(w, r, user)
+	default:
+		return false
+	}
+
+	return true
+}
+
 func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
 	message := "your user account doesn't have the necessary permissions to access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
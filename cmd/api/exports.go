@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xtommas/food-backend/internal/data"
+	"github.com/xtommas/food-backend/internal/validator"
+	"github.com/xtommas/food-backend/internal/xlsx"
+)
+
+// tableWriter is the row-by-row output shared by the CSV and XLSX exports.
+// Money cells are given as xlsx.Money and nil cells are left empty
+type tableWriter interface {
+	Write(cells []any) error
+	Close() error
+}
+
+type csvTableWriter struct {
+	w *csv.Writer
+}
+
+func (c csvTableWriter) Write(cells []any) error {
+	record := make([]string, len(cells))
+
+	for i, cell := range cells {
+		switch value := cell.(type) {
+		case nil:
+		case xlsx.Money:
+			record[i] = xlsx.FormatCents(int64(value))
+		case string:
+			record[i] = escapeCSVCell(value)
+		default:
+			record[i] = fmt.Sprint(value)
+		}
+	}
+
+	return c.w.Write(record)
+}
+
+// escapeCSVCell stops spreadsheets from evaluating user-supplied text as a formula
+func escapeCSVCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// unescapeCSVCell reverses escapeCSVCell, so exported files can be imported again
+func unescapeCSVCell(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune("=+-@", rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+func (c csvTableWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+var exportFormats = map[string]struct {
+	contentType string
+	open        func(w io.Writer, sheetName string) (tableWriter, error)
+}{
+	"csv": {
+		contentType: "text/csv; charset=utf-8",
+		open: func(w io.Writer, _ string) (tableWriter, error) {
+			return csvTableWriter{w: csv.NewWriter(w)}, nil
+		},
+	},
+	"xlsx": {
+		contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		open: func(w io.Writer, sheetName string) (tableWriter, error) {
+			return xlsx.NewWriter(w, sheetName)
+		},
+	},
+}
+
+// startExport sets the download headers and opens a table writer on the response
+func startExport(w http.ResponseWriter, format, fileName, sheetName string) (tableWriter, error) {
+	w.Header().Set("Content-Type", exportFormats[format].contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, fileName, format))
+
+	tw, err := exportFormats[format].open(w, sheetName)
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		return nil, err
+	}
+
+	return tw, nil
+}
+
+var orderExportHeader = []any{
+	"order_id", "created_at", "status", "fulfilment_type", "customer_id", "address", "table_number",
+	"delivery_fee", "order_total", "item_id", "dish_id", "dish_name", "unit_price", "quantity", "subtotal",
+}
+
+func orderExportRecord(row *data.OrderExportRow) []any {
+	order := row.Order
+
+	record := []any{
+		order.ID,
+		order.CreatedAt.UTC().Format(time.RFC3339),
+		order.Status,
+		order.FulfilmentType,
+		order.UserID,
+		order.Address,
+		nil,
+		xlsx.Money(order.DeliveryFee),
+		xlsx.Money(order.Total),
+		nil, nil, nil, nil, nil, nil,
+	}
+
+	if order.TableNumber != 0 {
+		record[6] = order.TableNumber
+	}
+
+	if item := row.Item; item != nil {
+		record[9] = item.ID
+		if item.DishID != 0 {
+			record[10] = item.DishID
+		}
+		record[11] = item.DishName
+		record[12] = xlsx.Money(item.UnitPrice)
+		record[13] = item.Quantity
+		record[14] = xlsx.Money(item.Subtotal)
+	}
+
+	return record
+}
+
+func (app *application) exportOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := app.readIdParam(r, "restaurant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Restaurants.Get(r.Context(), restaurantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	format := app.readString(qs, "format", "csv")
+	status := app.readString(qs, "status", "")
+	fulfilmentType := app.readString(qs, "fulfilment_type", "")
+
+	// both dates are inclusive and default to the last 30 days
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to := app.readDate(qs, "to", today, v)
+	from := app.readDate(qs, "from", to.AddDate(0, 0, -29), v)
+
+	_, ok := exportFormats[format]
+	v.Check(ok, "format", "must be csv or xlsx")
+	v.Check(!from.After(to), "to", "must not be before from")
+
+	if status != "" {
+		data.ValidateStatus(v, status)
+	}
+	if fulfilmentType != "" {
+		data.ValidateFulfilmentType(v, fulfilmentType)
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// the export can run for longer than the server's WriteTimeout allows
+	app.extendWriteDeadline(w, r, data.ExportTimeout)
+
+	fileName := fmt.Sprintf("restaurant-%d-orders-%s-%s", restaurantID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+
+	// the response is only started once the first row arrives, so a query
+	// that fails up front can still be reported as a JSON error
+	var out tableWriter
+
+	start := func() error {
+		tw, err := startExport(w, format, fileName, "Orders")
+		if err != nil {
+			return err
+		}
+		out = tw
+		return out.Write(orderExportHeader)
+	}
+
+	err = app.models.Orders.Export(r.Context(), restaurantID, status, fulfilmentType, from, to.AddDate(0, 0, 1), func(row *data.OrderExportRow) error {
+		if out == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return out.Write(orderExportRecord(row))
+	})
+	if err == nil && out == nil {
+		err = start()
+	}
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		if out == nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		// part of the file has already been sent and the status can't change, so
+		// abort the connection to keep the client from taking a truncated file as
+		// complete
+		app.logError(r, err)
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/xtommas/food-backend/internal/data"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+// readFavoriteFilters reads the pagination and sorting shared by both favourites listings.
+// Sorting by created_at orders by when the favourite was saved
+func (app *application) readFavoriteFilters(r *http.Request, v *validator.Validator, sortSafelist []string) data.Filters {
+	qs := r.URL.Query()
+
+	var filters data.Filters
+
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	filters.Sort = app.readString(qs, "sort", "-created_at")
+	filters.SortSafelist = sortSafelist
+
+	return filters
+}
+
+func (app *application) listFavoriteRestaurantsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	filters := app.readFavoriteFilters(r, v, []string{"id", "name", "created_at", "-id", "-name", "-created_at"})
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	restaurants, metadata, err := app.models.Favorites.GetRestaurantsForUser(r.Context(), user.Id, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"restaurants": restaurants, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) addFavoriteRestaurantHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := app.readIdParam(r, "restaurant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Favorites.AddRestaurant(r.Context(), user.Id, restaurantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "restaurant added to favorites"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) removeFavoriteRestaurantHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := app.readIdParam(r, "restaurant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Favorites.RemoveRestaurant(r.Context(), user.Id, restaurantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "restaurant removed from favorites"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listFavoriteDishesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	filters := app.readFavoriteFilters(r, v, []string{"id", "name", "price", "created_at", "-id", "-name", "-price", "-created_at"})
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	dishes, metadata, err := app.models.Favorites.GetDishesForUser(r.Context(), user.Id, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"dishes": dishes, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) addFavoriteDishHandler(w http.ResponseWriter, r *http.Request) {
+	dishID, err := app.readIdParam(r, "dish_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Favorites.AddDish(r.Context(), user.Id, dishID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "dish added to favorites"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) removeFavoriteDishHandler(w http.ResponseWriter, r *http.Request) {
+	dishID, err := app.readIdParam(r, "dish_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Favorites.RemoveDish(r.Context(), user.Id, dishID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "dish removed from favorites"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
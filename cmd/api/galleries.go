@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xtommas/food-backend/internal/data"
+	"github.com/xtommas/food-backend/internal/images"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+// how long the image URLs in gallery listings stay valid
+const galleryURLExpiry = time.Hour
+
+// gallery is the dish or restaurant gallery a request refers to. Routes with
+// an {id} are dish galleries, and the rest are restaurant galleries
+type gallery struct {
+	images  data.GalleryModelInterface
+	ownerID int64
+	// the audit log target type of the owner, "dish" or "restaurant". Its
+	// images are recorded as "dish_image" or "restaurant_image"
+	ownerType string
+	// images are stored under this prefix, e.g. "dishes/5/gallery/"
+	keyPrefix string
+	location  string
+}
+
+// readGallery resolves the gallery of a request, responding not found when the
+// restaurant or dish doesn't exist or the dish belongs to another restaurant
+func (app *application) readGallery(w http.ResponseWriter, r *http.Request) (*gallery, bool) {
+	restaurantID, err := app.readIdParam(r, "restaurant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	_, err = app.models.Restaurants.Get(r.Context(), restaurantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if r.PathValue("id") == "" {
+		return &gallery{
+			images:    app.models.RestaurantImages,
+			ownerID:   restaurantID,
+			ownerType: "restaurant",
+			keyPrefix: fmt.Sprintf("restaurants/%d/gallery/", restaurantID),
+			location:  fmt.Sprintf("/restaurants/%d/images", restaurantID),
+		}, true
+	}
+
+	dishID, err := app.readIdParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	dish, err := app.models.Dishes.Get(r.Context(), dishID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if dish.RestaurantID != restaurantID {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	return &gallery{
+		images:    app.models.DishImages,
+		ownerID:   dish.ID,
+		ownerType: "dish",
+		keyPrefix: fmt.Sprintf("dishes/%d/gallery/", dish.ID),
+		location:  fmt.Sprintf("/restaurants/%d/dishes/%d/images", restaurantID, dish.ID),
+	}, true
+}
+
+// setImageURLs fills in a presigned URL for each size of the images
+func (app *application) setImageURLs(ctx context.Context, galleryImages ...*data.GalleryImage) error {
+	for _, image := range galleryImages {
+		image.URLs = make(map[string]string, len(images.Sizes))
+
+		for _, size := range images.Sizes {
+			url, err := app.storage.PresignedURL(ctx, images.VariantKey(image.Key, size), galleryURLExpiry)
+			if err != nil {
+				return err
+			}
+
+			image.URLs[size] = url
+		}
+	}
+
+	return nil
+}
+
+// deleteGalleryPhotos removes the stored files of a gallery whose rows are
+// being deleted along with their dish or restaurant
+func (app *application) deleteGalleryPhotos(ctx context.Context, galleryImages []*data.GalleryImage) error {
+	for _, image := range galleryImages {
+		if err := app.deletePhoto(ctx, image.Key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (app *application) listGalleryImagesHandler(w http.ResponseWriter, r *http.Request) {
+	g, ok := app.readGallery(w, r)
+	if !ok {
+		return
+	}
+
+	galleryImages, err := g.images.GetAll(r.Context(), g.ownerID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.setImageURLs(r.Context(), galleryImages...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"images": galleryImages}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) uploadGalleryImageHandler(w http.ResponseWriter, r *http.Request) {
+	g, ok := app.readGallery(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseMultipartForm(maxPhotoBytes)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	image := &data.GalleryImage{
+		OwnerID: g.ownerID,
+		Caption: strings.TrimSpace(r.FormValue("caption")),
+	}
+
+	if primary := r.FormValue("primary"); primary != "" {
+		image.IsPrimary, err = strconv.ParseBool(primary)
+		if err != nil {
+			v.AddError("primary", "must be a boolean value")
+		}
+	}
+
+	// checked before the photo is stored, so invalid requests leave no files behind
+	if data.ValidateGalleryImage(v, image); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	image.Key, err = app.storePhoto(r, g.keyPrefix+strings.ToLower(rand.Text()), v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = g.images.Insert(r.Context(), image)
+	if err != nil {
+		if deleteErr := app.deletePhoto(r.Context(), image.Key); deleteErr != nil {
+			app.logError(r, deleteErr)
+		}
+
+		switch {
+		case errors.Is(err, data.ErrGalleryFull):
+			v.AddError("photo", fmt.Sprintf("gallery already has the maximum of %d images", data.MaxGalleryImages))
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditGalleryImageAdd, g.ownerType+"_image", image.ID, nil, image)
+
+	err = app.setImageURLs(r.Context(), image)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("%s/%d", g.location, image.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"image": image}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateGalleryImageHandler(w http.ResponseWriter, r *http.Request) {
+	g, ok := app.readGallery(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := app.readIdParam(r, "image_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	image, err := g.images.Get(r.Context(), id, g.ownerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Caption   *string `json:"caption"`
+		IsPrimary *bool   `json:"is_primary"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	before := *image
+
+	if input.Caption != nil {
+		image.Caption = strings.TrimSpace(*input.Caption)
+	}
+
+	if input.IsPrimary != nil {
+		v.Check(*input.IsPrimary || !image.IsPrimary, "is_primary", "can't be unset, make another image primary instead")
+		image.IsPrimary = *input.IsPrimary
+	}
+
+	if data.ValidateGalleryImage(v, image); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = g.images.Update(r.Context(), image)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditGalleryImageUpdate, g.ownerType+"_image", image.ID, &before, image)
+
+	err = app.setImageURLs(r.Context(), image)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"image": image}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) reorderGalleryImagesHandler(w http.ResponseWriter, r *http.Request) {
+	g, ok := app.readGallery(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ImageIDs []int64 `json:"image_ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(len(input.ImageIDs) > 0, "image_ids", "must contain at least 1 image")
+	v.Check(validator.Unique(input.ImageIDs), "image_ids", "must not contain duplicate values")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = g.images.Reorder(r.Context(), g.ownerID, input.ImageIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGalleryMismatch):
+			v.AddError("image_ids", "must list every image of the gallery exactly once")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditGalleryReorder, g.ownerType, g.ownerID, nil, envelope{"image_ids": input.ImageIDs})
+
+	galleryImages, err := g.images.GetAll(r.Context(), g.ownerID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.setImageURLs(r.Context(), galleryImages...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"images": galleryImages}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteGalleryImageHandler(w http.ResponseWriter, r *http.Request) {
+	g, ok := app.readGallery(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := app.readIdParam(r, "image_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	image, err := g.images.Get(r.Context(), id, g.ownerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = g.images.Delete(r.Context(), image.ID, g.ownerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditGalleryImageDelete, g.ownerType+"_image", image.ID, image, nil)
+
+	err = app.deletePhoto(r.Context(), image.Key)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "image successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
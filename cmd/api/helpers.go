@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -9,15 +11,19 @@ import (
 	"maps"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/xtommas/food-backend/internal/images"
 	"github.com/xtommas/food-backend/internal/validator"
 )
 
 type envelope map[string]any
 
+// the largest photo upload accepted, before it is resized
+const maxPhotoBytes = 10 << 20
+
 func (app *application) readIdParam(r *http.Request, param string) (int64, error) {
 	id, err := strconv.ParseInt(r.PathValue(param), 10, 64)
 	if err != nil || id < 1 {
@@ -139,6 +145,23 @@ func (app *application) readInt(queryString url.Values, key string, defaultValue
 	return i
 }
 
+// reads a calendar date in YYYY-MM-DD format, interpreted as midnight UTC
+func (app *application) readDate(queryString url.Values, key string, defaultValue time.Time, v *validator.Validator) time.Time {
+	s := queryString.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		v.AddError(key, "must be a date in YYYY-MM-DD format")
+		return defaultValue
+	}
+
+	return t
+}
+
 func (app *application) readBool(queryString url.Values, key string, v *validator.Validator) sql.NullBool {
 	s := queryString.Get(key)
 
@@ -162,33 +185,82 @@ func (app *application) readBool(queryString url.Values, key string, v *validato
 	return availableBool
 }
 
-func (app *application) storeImage(w http.ResponseWriter, r *http.Request, folder string, fileName string) (string, error) {
-	image, _, err := r.FormFile("photo")
+// storePhoto processes the "photo" file of a multipart form and stores each of
+// its sizes under baseKey, e.g. "dishes/1" is stored as "dishes/1_large.jpg" and
+// so on. It returns the photo's key, "dishes/1.jpg", from which images.VariantKey
+// derives each size. Problems with the upload itself are added to v
+func (app *application) storePhoto(r *http.Request, baseKey string, v *validator.Validator) (string, error) {
+	file, header, err := r.FormFile("photo")
 	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			v.AddError("photo", "must be provided")
+			return "", nil
+		}
 		return "", err
 	}
-	defer image.Close()
+	defer file.Close()
 
-	_, err = os.Stat(folder + fileName)
-	if err == nil {
-		err := os.Remove(folder + fileName)
-		if err != nil {
-			return "", err
-		}
-	} else if !os.IsNotExist(err) {
-		return "", err
+	if header.Size > maxPhotoBytes {
+		v.AddError("photo", "must not be larger than 10MB")
+		return "", nil
 	}
 
-	newImage, err := os.Create(folder + fileName)
+	contents, err := io.ReadAll(file)
 	if err != nil {
 		return "", err
 	}
-	defer newImage.Close()
 
-	_, err = io.Copy(newImage, image)
+	photo, err := images.Process(contents)
 	if err != nil {
-		return "", err
+		switch {
+		case errors.Is(err, images.ErrUnsupportedFormat), errors.Is(err, images.ErrTooSmall), errors.Is(err, images.ErrTooLarge):
+			v.AddError("photo", err.Error())
+			return "", nil
+		default:
+			return "", err
+		}
+	}
+
+	key := baseKey + photo.Ext
+
+	for _, variant := range photo.Variants {
+		err = app.storage.Put(r.Context(), images.VariantKey(key, variant.Size), bytes.NewReader(variant.Data), int64(len(variant.Data)), photo.ContentType)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return key, nil
+}
+
+// deletePhoto removes every size of a stored photo. Photos uploaded before
+// sizes were introduced are stored under the key itself
+func (app *application) deletePhoto(ctx context.Context, key string) error {
+	if key == "" {
+		return nil
 	}
 
-	return folder + fileName, nil
+	keys := []string{key}
+	for _, size := range images.Sizes {
+		keys = append(keys, images.VariantKey(key, size))
+	}
+
+	for _, k := range keys {
+		if err := app.storage.Delete(ctx, k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extendWriteDeadline lets a streamed download keep writing past the server's
+// WriteTimeout, for d plus the usual writeTimeout to finish the response. It
+// has to be called before anything is written, and a failure is only logged
+// since the download can still finish within the normal timeout
+func (app *application) extendWriteDeadline(w http.ResponseWriter, r *http.Request, d time.Duration) {
+	err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(d + writeTimeout))
+	if err != nil {
+		app.logError(r, fmt.Errorf("extending write deadline: %w", err))
+	}
 }
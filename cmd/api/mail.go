@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xtommas/food-backend/internal/jsonlog"
+	"github.com/xtommas/food-backend/internal/mailer"
+)
+
+// openMailSender sends through the configured SMTP server, or writes emails to
+// the log when there is none. Logged emails include their tokens, so production
+// must have a server
+func openMailSender(cfg config, logger *jsonlog.Logger) (mailer.Sender, error) {
+	if cfg.smtp.host == "" {
+		if cfg.env == "production" {
+			return nil, errors.New("SMTP_HOST must be set in production")
+		}
+
+		logger.PrintInfo("no SMTP_HOST set, emails will be written to the log", nil)
+		return mailer.NewLog(logger), nil
+	}
+
+	return mailer.NewSMTP(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender)
+}
+
+// background runs fn in a goroutine that the server waits for when shutting
+// down. Panics are logged rather than crashing the application
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.PrintError(fmt.Errorf("%v", err), nil)
+			}
+		}()
+
+		fn()
+	}()
+}
+
+// sendEmail sends an email in the background, so the response doesn't wait on
+// the mail server. Failures are logged
+func (app *application) sendEmail(recipient, templateFile string, data any) {
+	app.background(func() {
+		err := app.mailer.Send(recipient, templateFile, data)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"template": templateFile})
+		}
+	})
+}
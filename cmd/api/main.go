@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
 	"strings"
@@ -13,6 +15,8 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/xtommas/food-backend/internal/data"
 	"github.com/xtommas/food-backend/internal/jsonlog"
+	"github.com/xtommas/food-backend/internal/mailer"
+	"github.com/xtommas/food-backend/internal/storage"
 )
 
 var (
@@ -23,11 +27,23 @@ var (
 type config struct {
 	port int
 	env  string
-	db   struct {
+	log  struct {
+		level string
+		file  struct {
+			path       string
+			level      string
+			maxSizeMB  int
+			maxAge     time.Duration
+			maxBackups int
+		}
+		errorSampling jsonlog.Sampling
+	}
+	db struct {
 		dsn          string
 		maxOpenConns int
 		maxIdleConns int
 		maxIdleTime  string
+		queryTimeout time.Duration
 	}
 	limiter struct {
 		rps     float64
@@ -40,20 +56,72 @@ type config struct {
 	jwt struct {
 		secret string
 	}
+	tracking struct {
+		pingInterval    time.Duration
+		retention       time.Duration
+		pruneInterval   time.Duration
+		courierSpeedKmh float64
+	}
+	storage struct {
+		backend   string
+		localRoot string
+		publicURL string
+		secret    string
+		s3        storage.S3Config
+	}
+	tracing struct {
+		endpoint    string
+		serviceName string
+		sampleRatio float64
+	}
+	smtp struct {
+		host     string
+		port     int
+		username string
+		password string
+		sender   string
+	}
 }
 
 type application struct {
-	config config
-	logger *jsonlog.Logger
-	models data.Models
-	wg     sync.WaitGroup
+	config     config
+	logger     *jsonlog.Logger
+	models     data.Models
+	storage    storage.Storage
+	mailer     *mailer.Mailer
+	prometheus *prometheusMetrics
+	wg         sync.WaitGroup
 }
 
 func main() {
+	// reports configuration problems until the configured logger is open
 	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
 
 	var cfg config
 
+	// logging
+	cfg.log.level = getEnv("LOG_LEVEL", "info")
+	cfg.log.file.path = getEnv("LOG_FILE", "")
+	cfg.log.file.level = getEnv("LOG_FILE_LEVEL", "debug")
+	cfg.log.file.maxSizeMB = getEnvInt("LOG_FILE_MAX_SIZE_MB", 100, logger)
+	cfg.log.file.maxAge = getEnvDuration("LOG_FILE_MAX_AGE", 24*time.Hour, logger)
+	cfg.log.file.maxBackups = getEnvInt("LOG_FILE_MAX_BACKUPS", 7, logger)
+	cfg.log.errorSampling.Window = getEnvDuration("LOG_ERROR_SAMPLE_WINDOW", time.Minute, logger)
+	cfg.log.errorSampling.Burst = getEnvInt("LOG_ERROR_SAMPLE_BURST", 10, logger)
+
+	configuredLogger, logFile, err := openLogger(cfg)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+	logger = configuredLogger
+
+	if logFile != nil {
+		defer logFile.Close()
+	}
+
+	// libraries that log through log/slog write to the same sinks
+	slog.SetDefault(slog.New(jsonlog.NewHandler(logger)))
+
 	// server
 	cfg.port = getEnvInt("PORT", 4000, logger)
 	cfg.env = getEnv("ENV", "development")
@@ -63,6 +131,7 @@ func main() {
 	cfg.db.maxOpenConns = getEnvInt("DB_MAX_OPEN_CONNS", 25, logger)
 	cfg.db.maxIdleConns = getEnvInt("DB_MAX_IDLE_CONNS", 25, logger)
 	cfg.db.maxIdleTime = getEnv("DB_MAX_IDLE_TIME", "15m")
+	cfg.db.queryTimeout = getEnvDuration("DB_QUERY_TIMEOUT", data.DefaultQueryTimeout, logger)
 
 	// rate limiter
 	cfg.limiter.rps = getEnvFloat("LIMITER_RPS", 2, logger)
@@ -77,6 +146,41 @@ func main() {
 	// JWT
 	cfg.jwt.secret = requireEnv("JWT_SECRET", logger)
 
+	// courier tracking
+	cfg.tracking.pingInterval = getEnvDuration("TRACKING_PING_INTERVAL", 5*time.Second, logger)
+	cfg.tracking.retention = getEnvDuration("TRACKING_RETENTION", 24*time.Hour, logger)
+	cfg.tracking.pruneInterval = getEnvDuration("TRACKING_PRUNE_INTERVAL", time.Hour, logger)
+	cfg.tracking.courierSpeedKmh = getEnvFloat("TRACKING_COURIER_SPEED_KMH", 20, logger)
+
+	if cfg.tracking.pruneInterval <= 0 {
+		logger.PrintFatal(errors.New("TRACKING_PRUNE_INTERVAL must be greater than zero"), nil)
+	}
+
+	// file storage
+	cfg.storage.backend = getEnv("STORAGE_BACKEND", "local")
+	cfg.storage.localRoot = getEnv("STORAGE_LOCAL_ROOT", "images")
+	cfg.storage.publicURL = getEnv("STORAGE_PUBLIC_URL", fmt.Sprintf("http://localhost:%d", cfg.port))
+	cfg.storage.secret = getEnv("STORAGE_SIGNING_SECRET", cfg.jwt.secret)
+	cfg.storage.s3.Endpoint = getEnv("S3_ENDPOINT", "")
+	cfg.storage.s3.PublicEndpoint = getEnv("S3_PUBLIC_ENDPOINT", "")
+	cfg.storage.s3.AccessKey = getEnv("S3_ACCESS_KEY", "")
+	cfg.storage.s3.SecretKey = getEnv("S3_SECRET_KEY", "")
+	cfg.storage.s3.Bucket = getEnv("S3_BUCKET", "food-backend")
+	cfg.storage.s3.Region = getEnv("S3_REGION", "us-east-1")
+	cfg.storage.s3.UseSSL = getEnvBool("S3_USE_SSL", true, logger)
+
+	// tracing
+	cfg.tracing.endpoint = getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	cfg.tracing.serviceName = getEnv("OTEL_SERVICE_NAME", "food-backend")
+	cfg.tracing.sampleRatio = getEnvFloat("TRACING_SAMPLE_RATIO", 1, logger)
+
+	// email. Without an SMTP host, emails are written to the log instead
+	cfg.smtp.host = getEnv("SMTP_HOST", "")
+	cfg.smtp.port = getEnvInt("SMTP_PORT", 587, logger)
+	cfg.smtp.username = getEnv("SMTP_USERNAME", "")
+	cfg.smtp.password = getEnv("SMTP_PASSWORD", "")
+	cfg.smtp.sender = getEnv("SMTP_SENDER", "Food Backend <no-reply@food-backend.local>")
+
 	// version
 	if os.Getenv("VERSION") == "true" {
 		fmt.Printf("Version:\t%s\n", version)
@@ -91,16 +195,77 @@ func main() {
 	defer db.Close()
 	logger.PrintInfo("database connection pool established", nil)
 
+	store, err := openStorage(cfg)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+	logger.PrintInfo("file storage ready", map[string]string{"backend": cfg.storage.backend})
+
+	sender, err := openMailSender(cfg, logger)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	tracerProvider, err := openTracing(cfg)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
+		config:     cfg,
+		logger:     logger,
+		models:     data.NewModels(db, cfg.db.queryTimeout),
+		storage:    store,
+		mailer:     mailer.New(sender),
+		prometheus: newPrometheusMetrics(db),
 	}
 
 	err = app.serve()
 	if err != nil {
 		logger.PrintFatal(err, nil)
 	}
+
+	// flush the spans still waiting to be exported
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = tracerProvider.Shutdown(ctx)
+	if err != nil {
+		logger.PrintError(err, nil)
+	}
+}
+
+// openLogger writes to stdout and, when LOG_FILE is set, to a rotating log
+// file. The file is returned so it can be closed on exit
+func openLogger(cfg config) (*jsonlog.Logger, *jsonlog.RotatingFile, error) {
+	level, err := jsonlog.ParseLevel(cfg.log.level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sinks := []jsonlog.Sink{{Out: os.Stdout, MinLevel: level}}
+
+	if cfg.log.file.path == "" {
+		return jsonlog.NewWithSinks(cfg.log.errorSampling, sinks...), nil, nil
+	}
+
+	fileLevel, err := jsonlog.ParseLevel(cfg.log.file.level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := jsonlog.OpenRotatingFile(cfg.log.file.path, jsonlog.RotateOptions{
+		MaxSize:    int64(cfg.log.file.maxSizeMB) << 20,
+		MaxAge:     cfg.log.file.maxAge,
+		MaxBackups: cfg.log.file.maxBackups,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sinks = append(sinks, jsonlog.Sink{Out: file, MinLevel: fileLevel})
+
+	return jsonlog.NewWithSinks(cfg.log.errorSampling, sinks...), file, nil
 }
 
 func openDB(cfg config) (*sql.DB, error) {
@@ -176,3 +341,14 @@ func getEnvBool(key string, defaultVal bool, logger *jsonlog.Logger) bool {
 	}
 	return defaultVal
 }
+
+func getEnvDuration(key string, defaultVal time.Duration, logger *jsonlog.Logger) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			logger.PrintFatal(fmt.Errorf("invalid value for %q: %w", key, err), nil)
+		}
+		return d
+	}
+	return defaultVal
+}
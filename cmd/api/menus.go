@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/xtommas/food-backend/internal/data"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+// columns of the menu CSV format. Categories share one cell, separated by |
+var menuCSVHeader = []string{"name", "price", "description", "categories", "available"}
+
+const menuCategorySeparator = "|"
+
+// rowError holds the validation errors of one row of an uploaded file.
+// CSV rows are numbered by line, with the header on line 1, and JSON rows by position from 1
+type rowError struct {
+	Row    int               `json:"row"`
+	Errors map[string]string `json:"errors"`
+}
+
+// menuRow is a parsed import row, along with any errors found while parsing its cells
+type menuRow struct {
+	row    int
+	item   data.MenuItem
+	errors map[string]string
+}
+
+func readMenuCSV(body io.Reader) ([]menuRow, error) {
+	reader := csv.NewReader(body)
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("body must not be empty")
+		}
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// spreadsheet programs often save CSV files with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+
+		if !validator.PermittedValue(name, menuCSVHeader...) {
+			return nil, fmt.Errorf("header contains unknown column %q", name)
+		}
+		columns[name] = i
+	}
+
+	// only the availability column is optional
+	for _, name := range []string{"name", "price", "description", "categories"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("header is missing the %q column", name)
+		}
+	}
+
+	var rows []menuRow
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rows) == data.MaxMenuImportRows {
+			return nil, fmt.Errorf("body must not contain more than %d dishes", data.MaxMenuImportRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := menuRow{row: line, errors: map[string]string{}}
+
+		cell := func(name string) string {
+			return strings.TrimSpace(unescapeCSVCell(record[columns[name]]))
+		}
+
+		row.item.Name = cell("name")
+		row.item.Description = cell("description")
+
+		if price := cell("price"); price != "" {
+			row.item.Price, err = strconv.ParseInt(price, 10, 64)
+			if err != nil {
+				row.errors["price"] = "must be a whole number of cents"
+			}
+		}
+
+		row.item.Categories = []string{}
+		for _, category := range strings.Split(cell("categories"), menuCategorySeparator) {
+			if category = strings.TrimSpace(category); category != "" {
+				row.item.Categories = append(row.item.Categories, category)
+			}
+		}
+
+		if _, ok := columns["available"]; ok && cell("available") != "" {
+			available, err := strconv.ParseBool(cell("available"))
+			if err != nil {
+				row.errors["available"] = "must be true or false"
+			} else {
+				row.item.Available = &available
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// readMenuFormat picks the import format from ?format=, falling back to the
+// request's Content-Type
+func (app *application) readMenuFormat(r *http.Request) string {
+	format := app.readString(r.URL.Query(), "format", "")
+	if format != "" {
+		return format
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		return "csv"
+	}
+
+	return "json"
+}
+
+func (app *application) importMenuHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := app.readIdParam(r, "restaurant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Restaurants.Get(r.Context(), restaurantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+
+	format := app.readMenuFormat(r)
+	dryRun := app.readBool(r.URL.Query(), "dry_run", v)
+
+	v.Check(validator.PermittedValue(format, "csv", "json"), "format", "must be csv or json")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var rows []menuRow
+
+	switch format {
+	case "csv":
+		// limit the size of the request body to 2MB
+		r.Body = http.MaxBytesReader(w, r.Body, 2_097_152)
+
+		rows, err = readMenuCSV(r.Body)
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				err = fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+			}
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	case "json":
+		var input struct {
+			Dishes []data.MenuItem `json:"dishes"`
+		}
+
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if len(input.Dishes) > data.MaxMenuImportRows {
+			app.badRequestResponse(w, r, fmt.Errorf("body must not contain more than %d dishes", data.MaxMenuImportRows))
+			return
+		}
+
+		for i, item := range input.Dishes {
+			rows = append(rows, menuRow{row: i + 1, item: item, errors: map[string]string{}})
+		}
+	}
+
+	if len(rows) == 0 {
+		v.AddError("dishes", "must contain at least one dish")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	dishes := make([]*data.Dish, 0, len(rows))
+	seen := make(map[string]int, len(rows))
+	var rowErrors []rowError
+
+	for _, row := range rows {
+		v := validator.New()
+
+		for key, message := range row.errors {
+			v.AddError(key, message)
+		}
+
+		dish := row.item.Dish(restaurantID)
+		data.ValidateDish(v, dish)
+
+		// dishes are matched by name, so a name can only appear once
+		if first, ok := seen[dish.Name]; ok {
+			v.AddError("name", fmt.Sprintf("duplicates the dish on row %d", first))
+		} else {
+			seen[dish.Name] = row.row
+		}
+
+		if !v.Valid() {
+			rowErrors = append(rowErrors, rowError{Row: row.row, Errors: v.Errors})
+			continue
+		}
+
+		dishes = append(dishes, dish)
+	}
+
+	if len(rowErrors) > 0 {
+		app.failedRowValidationResponse(w, r, rowErrors)
+		return
+	}
+
+	result, err := app.models.Dishes.ImportMenu(r.Context(), restaurantID, dishes, dryRun.Valid && dryRun.Bool)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !result.DryRun {
+		app.audit(r, data.AuditMenuImport, "restaurant", restaurantID, nil, result)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"import": result}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) exportMenuHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID, err := app.readIdParam(r, "restaurant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Restaurants.Get(r.Context(), restaurantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+
+	format := app.readString(r.URL.Query(), "format", "csv")
+
+	if v.Check(validator.PermittedValue(format, "csv", "json"), "format", "must be csv or json"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	dishes, err := app.models.Dishes.GetMenu(r.Context(), restaurantID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	fileName := fmt.Sprintf("restaurant-%d-menu", restaurantID)
+
+	if format == "json" {
+		items := make([]data.MenuItem, len(dishes))
+		for i, dish := range dishes {
+			items[i] = data.NewMenuItem(dish)
+		}
+
+		headers := make(http.Header)
+		headers.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, fileName))
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"dishes": items}, headers)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	out, err := startExport(w, format, fileName, "Menu")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	header := make([]any, len(menuCSVHeader))
+	for i, name := range menuCSVHeader {
+		header[i] = name
+	}
+
+	err = out.Write(header)
+	for _, dish := range dishes {
+		if err != nil {
+			break
+		}
+		err = out.Write([]any{dish.Name, dish.Price, dish.Description, strings.Join(dish.Categories, menuCategorySeparator), dish.Available})
+	}
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		app.logError(r, err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// prometheusMetrics holds the collectors exposed at GET /metrics
+type prometheusMetrics struct {
+	registry *prometheus.Registry
+
+	requestDuration  *prometheus.HistogramVec
+	requestsInFlight prometheus.Gauge
+	rateLimited      prometheus.Counter
+
+	ordersCreated          *prometheus.CounterVec
+	orderStatusTransitions *prometheus.CounterVec
+}
+
+// newPrometheusMetrics registers the API's collectors, along with the Go
+// runtime, process and database pool collectors
+func newPrometheusMetrics(db *sql.DB) *prometheusMetrics {
+	m := &prometheusMetrics{
+		registry: prometheus.NewRegistry(),
+
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "food_backend",
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests, by route pattern, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+
+		requestsInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "food_backend",
+			Name:      "http_requests_in_flight",
+			Help:      "Number of HTTP requests being served.",
+		}),
+
+		rateLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "food_backend",
+			Name:      "rate_limiter_rejections_total",
+			Help:      "Requests rejected with 429 Too Many Requests by the rate limiter.",
+		}),
+
+		ordersCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "food_backend",
+			Name:      "orders_created_total",
+			Help:      "Orders created, by fulfilment type.",
+		}, []string{"fulfilment_type"}),
+
+		orderStatusTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "food_backend",
+			Name:      "order_status_transitions_total",
+			Help:      "Order status changes, by previous and new status.",
+		}, []string{"from", "to"}),
+	}
+
+	m.registry.MustRegister(
+		m.requestDuration,
+		m.requestsInFlight,
+		m.rateLimited,
+		m.ordersCreated,
+		m.orderStatusTransitions,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "postgres"),
+	)
+
+	return m
+}
+
+// handler serves the registered metrics in the Prometheus text format
+func (m *prometheusMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// orderStatusChanged counts a status transition, unless the status is unchanged
+func (m *prometheusMetrics) orderStatusChanged(from, to string) {
+	if from == to {
+		return
+	}
+
+	m.orderStatusTransitions.WithLabelValues(from, to).Inc()
+}
+
+// routeLabel is the path of the ServeMux pattern that serves a request, e.g.
+// "/restaurants/{restaurant_id}", so requests to different IDs share a label.
+// Unmatched requests are labelled "unmatched"
+func routeLabel(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+
+	// the method is a label of its own
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		pattern = path
+	}
+
+	if pattern == "" || pattern == "/" {
+		return "unmatched"
+	}
+
+	return pattern
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/rand"
 	"errors"
 	"expvar"
 	"fmt"
@@ -14,6 +15,12 @@ import (
 	"github.com/pascaldekloe/jwt"
 	"github.com/tomasen/realip"
 	"github.com/xtommas/food-backend/internal/data"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 )
 
@@ -21,6 +28,12 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				// handlers abort a response they can no longer complete, and the
+				// server closes the connection without logging a stack trace
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
 				w.Header().Set("Connection", "close")
 
 				app.serverErrorResponse(w, r, fmt.Errorf("%s", err))
@@ -31,6 +44,113 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
+// trace starts a server span for each request, continuing the trace of the
+// caller when it sends a traceparent header. The trace ID is returned in the
+// X-Trace-ID header
+func (app *application) trace(mux *http.ServeMux, next http.Handler) http.Handler {
+	tracer := otel.Tracer("github.com/xtommas/food-backend/cmd/api")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := routeLabel(mux, r)
+
+		ctx, span := tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(realip.FromRequest(r)),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		if id := traceID(ctx); id != "" {
+			w.Header().Set("X-Trace-ID", id)
+		}
+
+		metrics := httpsnoop.CaptureMetrics(next, w, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(metrics.Code))
+		if metrics.Code >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(metrics.Code))
+		}
+	})
+}
+
+// maxRequestIDLength bounds the X-Request-ID values accepted from clients
+const maxRequestIDLength = 128
+
+// logRequest assigns each request an ID and writes one access log entry per
+// request once the response is sent. The ID is taken from the X-Request-ID
+// header when the client (or a proxy) sends a valid one, and returned in the
+// same header. Errors logged while serving the request carry the ID, see
+// requestLogger
+func (app *application) logRequest(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = rand.Text()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("http.request.id", id))
+
+		properties := map[string]string{"request_id": id}
+		if traceID := traceID(r.Context()); traceID != "" {
+			properties["trace_id"] = traceID
+		}
+
+		info := &requestInfo{id: id, logger: app.logger.With(properties)}
+
+		metrics := httpsnoop.CaptureMetrics(next, w, app.contextSetRequestInfo(r, info))
+
+		route := routeLabel(mux, r)
+
+		entry := map[string]string{
+			"method":      r.Method,
+			"route":       route,
+			"status":      strconv.Itoa(metrics.Code),
+			"duration_ms": strconv.FormatFloat(float64(metrics.Duration.Microseconds())/1000, 'f', 3, 64),
+			"bytes":       strconv.FormatInt(metrics.Written, 10),
+			"client_ip":   realip.FromRequest(r),
+		}
+
+		if info.userID != 0 {
+			entry["user_id"] = strconv.FormatInt(info.userID, 10)
+		}
+
+		// health checks and metric scrapes would drown out the rest of the log
+		if route == "/healthcheck" || route == "/metrics" {
+			info.logger.PrintDebug("request completed", entry)
+			return
+		}
+
+		info.logger.PrintInfo("request completed", entry)
+	})
+}
+
+// validRequestID accepts IDs made of letters, digits and the separators
+// commonly used by proxies and UUIDs, so they can be logged as they are
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
 func (app *application) rateLimit(next http.Handler) http.Handler {
 	type client struct {
 		limiter  *rate.Limiter
@@ -77,6 +197,7 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 			// see if the request is permitted or not
 			if !clients[ip].limiter.Allow() {
 				mu.Unlock()
+				app.prometheus.rateLimited.Inc()
 				// return 429 Too Many Requests
 				app.rateLimitExceededResponse(w, r)
 				return
@@ -143,7 +264,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		user, err := app.models.Users.Get(userId)
+		user, err := app.models.Users.Get(r.Context(), userId)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
@@ -154,6 +275,14 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
+		// the user is loaded on every request, so suspensions and bans take
+		// effect on tokens that were issued before them
+		if app.accountStatusResponse(w, r, user) {
+			return
+		}
+
+		trace.SpanFromContext(r.Context()).SetAttributes(semconv.UserID(claims.Subject))
+
 		r = app.contextSetUser(r, user)
 
 		next.ServeHTTP(w, r)
@@ -194,7 +323,7 @@ func (app *application) requirePermission(code string, next http.HandlerFunc) ht
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
 
-		permissions, err := app.models.Permissions.GetAllForUser(user.Id)
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.Id)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
@@ -226,6 +355,21 @@ func (app *application) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
 	return app.requireActivatedUser(fn)
 }
 
+func (app *application) requireCourier(next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		if !user.IsCourier() {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return app.requireActivatedUser(fn)
+}
+
 func (app *application) requireRestaurantStaff(next http.HandlerFunc) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
@@ -241,7 +385,7 @@ func (app *application) requireRestaurantStaff(next http.HandlerFunc) http.Handl
 			return
 		}
 
-		isStaff, err := app.models.Restaurants.IsStaff(restaurantID, user.Id)
+		isStaff, err := app.models.Restaurants.IsStaff(r.Context(), restaurantID, user.Id)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
@@ -273,7 +417,7 @@ func (app *application) requireRestaurantOwner(next http.HandlerFunc) http.Handl
 			return
 		}
 
-		staffRole, err := app.models.Restaurants.GetStaffRole(restaurantID, user.Id)
+		staffRole, err := app.models.Restaurants.GetStaffRole(r.Context(), restaurantID, user.Id)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
@@ -325,7 +469,9 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 	})
 }
 
-func (app *application) metrics(next http.Handler) http.Handler {
+// metrics updates the expvar counters served at /debug/vars and the Prometheus
+// request metrics. mux is the router behind next, used to label requests by route
+func (app *application) metrics(mux *http.ServeMux, next http.Handler) http.Handler {
 	totalRequestsReceived := expvar.NewInt("total_requests_received")
 	totalResponsesSent := expvar.NewInt("total_responses_sent")
 	totalProcessingTimeMicroseconds := expvar.NewInt("total_processing_time_μs")
@@ -333,15 +479,21 @@ func (app *application) metrics(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		totalRequestsReceived.Add(1)
+		app.prometheus.requestsInFlight.Inc()
 
 		// call the next handler in the chain
 		metrics := httpsnoop.CaptureMetrics(next, w, r)
 
 		// on the way back up the middleware chain, increment the number of responses
 		totalResponsesSent.Add(1)
+		app.prometheus.requestsInFlight.Dec()
 
 		totalProcessingTimeMicroseconds.Add(metrics.Duration.Microseconds())
 
 		totalResponsesSentByStatus.Add(strconv.Itoa(metrics.Code), 1)
+
+		app.prometheus.requestDuration.
+			WithLabelValues(routeLabel(mux, r), r.Method, strconv.Itoa(metrics.Code)).
+			Observe(metrics.Duration.Seconds())
 	})
 }
@@ -33,7 +33,7 @@ func (app *application) createOrderItemHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	dish, err := app.models.Dishes.Get(input.Dish_id)
+	dish, err := app.models.Dishes.Get(r.Context(), input.Dish_id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -64,7 +64,7 @@ func (app *application) createOrderItemHandler(w http.ResponseWriter, r *http.Re
 
 	user := app.contextGetUser(r)
 
-	order, err := app.models.Orders.GetForUser(order_id, user.Id)
+	order, err := app.models.Orders.GetForUser(r.Context(), order_id, user.Id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -75,7 +75,7 @@ func (app *application) createOrderItemHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if order.Status == "delivered" {
+	if order.IsClosed() {
 		app.editConflictResponse(w, r)
 		return
 	}
@@ -85,7 +85,7 @@ func (app *application) createOrderItemHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	insertedItem, err := app.models.OrderItems.InsertFromDish(order_id, dish, input.Quantity)
+	insertedItem, err := app.models.OrderItems.InsertFromDish(r.Context(), order_id, dish, input.Quantity)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -94,7 +94,7 @@ func (app *application) createOrderItemHandler(w http.ResponseWriter, r *http.Re
 
 	order.Total += order_item.Subtotal
 
-	err = app.models.Orders.Update(order)
+	err = app.models.Orders.Update(r.Context(), order)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -127,7 +127,7 @@ func (app *application) getOrderItemsHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	order, err := app.models.Orders.GetForRestaurant(order_id, restaurantID)
+	order, err := app.models.Orders.GetForRestaurant(r.Context(), order_id, restaurantID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -138,36 +138,13 @@ func (app *application) getOrderItemsHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	items, err := app.models.OrderItems.GetForOrder(order.ID)
+	items, err := app.models.OrderItems.GetForOrder(r.Context(), order.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	type order_item struct {
-		Name     string `json:"dish"`
-		Quantity int    `json:"quantity"`
-		Subtotal int64  `json:"subtotal"`
-	}
-
-	order_items := []order_item{}
-
-	for _, item := range items {
-		dish, err := app.models.Dishes.Get(item.DishID)
-		if err != nil {
-			switch {
-			case errors.Is(err, data.ErrRecordNotFound):
-				app.notFoundResponse(w, r)
-			default:
-				app.serverErrorResponse(w, r, err)
-			}
-			return
-		}
-
-		order_items = append(order_items, order_item{Name: dish.Name, Quantity: item.Quantity, Subtotal: item.Subtotal})
-	}
-
-	err = app.writeJSON(w, http.StatusCreated, envelope{"items": order_items}, nil)
+	err = app.writeJSON(w, http.StatusCreated, envelope{"items": summarizeItems(items)}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -182,7 +159,7 @@ func (app *application) getUserOrderItemsHandler(w http.ResponseWriter, r *http.
 
 	user := app.contextGetUser(r)
 
-	order, err := app.models.Orders.GetForUser(order_id, user.Id)
+	order, err := app.models.Orders.GetForUser(r.Context(), order_id, user.Id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -193,36 +170,13 @@ func (app *application) getUserOrderItemsHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	items, err := app.models.OrderItems.GetForOrder(order.ID)
+	items, err := app.models.OrderItems.GetForOrder(r.Context(), order.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	type order_item struct {
-		Name     string `json:"dish"`
-		Quantity int    `json:"quantity"`
-		Subtotal int64  `json:"subtotal"`
-	}
-
-	order_items := []order_item{}
-
-	for _, item := range items {
-		dish, err := app.models.Dishes.Get(item.DishID)
-		if err != nil {
-			switch {
-			case errors.Is(err, data.ErrRecordNotFound):
-				app.notFoundResponse(w, r)
-			default:
-				app.serverErrorResponse(w, r, err)
-			}
-			return
-		}
-
-		order_items = append(order_items, order_item{Name: dish.Name, Quantity: item.Quantity, Subtotal: item.Subtotal})
-	}
-
-	err = app.writeJSON(w, http.StatusCreated, envelope{"items": order_items}, nil)
+	err = app.writeJSON(w, http.StatusCreated, envelope{"items": summarizeItems(items)}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
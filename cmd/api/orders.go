@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -9,6 +10,51 @@ import (
 	"github.com/xtommas/food-backend/internal/validator"
 )
 
+// orderItemSummary is how items are shown inside order responses
+type orderItemSummary struct {
+	Name     string `json:"dish"`
+	Quantity int    `json:"quantity"`
+	Subtotal int64  `json:"subtotal"`
+}
+
+type fullOrder struct {
+	Order data.Order         `json:"order"`
+	Items []orderItemSummary `json:"items"`
+}
+
+// summarizeItems uses the dish name snapshotted into each item, so items of
+// dishes that were renamed or deleted since keep showing what was ordered
+func summarizeItems(items []*data.OrderItem) []orderItemSummary {
+	summaries := make([]orderItemSummary, 0, len(items))
+
+	for _, item := range items {
+		summaries = append(summaries, orderItemSummary{Name: item.DishName, Quantity: item.Quantity, Subtotal: item.Subtotal})
+	}
+
+	return summaries
+}
+
+// withItems pairs each order with its items, loading the items of the whole page in a single query
+func (app *application) withItems(ctx context.Context, orders []*data.Order) ([]fullOrder, error) {
+	orderIDs := make([]int64, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = order.ID
+	}
+
+	itemsForOrders, err := app.models.OrderItems.GetForOrders(ctx, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	fullOrders := make([]fullOrder, 0, len(orders))
+
+	for _, order := range orders {
+		fullOrders = append(fullOrders, fullOrder{Order: *order, Items: summarizeItems(itemsForOrders[order.ID])})
+	}
+
+	return fullOrders, nil
+}
+
 func (app *application) createOrderHandler(w http.ResponseWriter, r *http.Request) {
 	restaurantID, err := app.readIdParam(r, "restaurant_id")
 	if err != nil {
@@ -16,7 +62,7 @@ func (app *application) createOrderHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	_, err = app.models.Restaurants.Get(restaurantID)
+	_, err = app.models.Restaurants.Get(r.Context(), restaurantID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -30,7 +76,13 @@ func (app *application) createOrderHandler(w http.ResponseWriter, r *http.Reques
 	user := app.contextGetUser(r)
 
 	var input struct {
-		Address string `json:"address"`
+		FulfilmentType       string   `json:"fulfilment_type"`
+		AddressID            *int64   `json:"address_id"`
+		Address              string   `json:"address"`
+		DeliveryInstructions string   `json:"delivery_instructions"`
+		TableNumber          int      `json:"table_number"`
+		Latitude             *float64 `json:"latitude"`
+		Longitude            *float64 `json:"longitude"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -39,27 +91,111 @@ func (app *application) createOrderHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// orders without an explicit fulfilment type are delivered, as they were before pickup and dine-in existed
+	if input.FulfilmentType == "" {
+		input.FulfilmentType = data.FulfilmentDelivery
+	}
+
 	order := &data.Order{
-		UserID:       user.Id,
-		RestaurantID: restaurantID,
-		Total:        0,
-		Address:      input.Address,
-		Status:       "pending",
+		UserID:               user.Id,
+		RestaurantID:         restaurantID,
+		Total:                0,
+		FulfilmentType:       input.FulfilmentType,
+		Address:              input.Address,
+		DeliveryInstructions: input.DeliveryInstructions,
+		TableNumber:          input.TableNumber,
+		Status:               "pending",
 	}
 
 	v := validator.New()
 
+	v.Check((input.Latitude == nil) == (input.Longitude == nil), "latitude", "must be provided together with longitude")
+	if input.Latitude != nil && input.Longitude != nil {
+		order.Latitude = *input.Latitude
+		order.Longitude = *input.Longitude
+		order.HasDestination = true
+	}
+
+	var address *data.Address
+
+	switch {
+	case input.AddressID != nil:
+		v.Check(order.FulfilmentType == data.FulfilmentDelivery, "address_id", "must only be provided for delivery orders")
+		v.Check(input.Address == "", "address", "must not be provided together with address_id")
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		address, err = app.models.Addresses.Get(r.Context(), *input.AddressID, user.Id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("address_id", "no saved address found with this id")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+	case order.FulfilmentType == data.FulfilmentDelivery && input.Address == "":
+		// fall back to the user's default address, if they have one
+		address, err = app.models.Addresses.GetDefaultForUser(r.Context(), user.Id)
+		if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	// snapshot the saved address, so later edits to the address book don't change the order
+	if address != nil {
+		order.Address = address.Format()
+		order.Latitude = address.Latitude
+		order.Longitude = address.Longitude
+		order.HasDestination = address.HasCoordinates
+		if order.DeliveryInstructions == "" {
+			order.DeliveryInstructions = address.Instructions
+		}
+	}
+
+	if order.FulfilmentType == data.FulfilmentDelivery {
+		zones, err := app.models.DeliveryZones.GetAllForRestaurant(r.Context(), restaurantID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		// restaurants that haven't defined any zones deliver anywhere. Otherwise the
+		// destination must come from a saved address, so the zone check doesn't rest
+		// on coordinates the client sent alongside a free-text address
+		if len(zones) > 0 {
+			if address == nil {
+				v.AddError("address_id", "must be provided to check the restaurant's delivery zones")
+			} else if !address.HasCoordinates {
+				v.AddError("address_id", "must refer to an address with coordinates to check the restaurant's delivery zones")
+			} else if zone := data.MatchDeliveryZone(zones, order.Latitude, order.Longitude); zone == nil {
+				v.AddError("address", "is outside the restaurant's delivery zones")
+			} else {
+				order.DeliveryFee = zone.Fee
+				order.MinimumOrder = zone.MinimumOrder
+			}
+		}
+	}
+
 	if data.ValidateOrder(v, order); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Orders.Insert(order)
+	err = app.models.Orders.Insert(r.Context(), order)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.prometheus.ordersCreated.WithLabelValues(order.FulfilmentType).Inc()
+
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/restaurant/%d/orders/orders/%d", order.RestaurantID, order.ID))
 
@@ -77,7 +213,8 @@ func (app *application) getOrdersForRestaurantHandler(w http.ResponseWriter, r *
 	}
 
 	var input struct {
-		Status string
+		Status         string
+		FulfilmentType string
 		data.Filters
 	}
 
@@ -86,6 +223,7 @@ func (app *application) getOrdersForRestaurantHandler(w http.ResponseWriter, r *
 	qs := r.URL.Query()
 
 	input.Status = app.readString(qs, "status", "")
+	input.FulfilmentType = app.readString(qs, "fulfilment_type", "")
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 50, v)
@@ -94,55 +232,25 @@ func (app *application) getOrdersForRestaurantHandler(w http.ResponseWriter, r *
 
 	input.Filters.SortSafelist = []string{"id", "total", "status", "-id", "-total", "-status"}
 
+	if input.FulfilmentType != "" {
+		data.ValidateFulfilmentType(v, input.FulfilmentType)
+	}
+
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	orders, metadata, err := app.models.Orders.GetAllForRestaurant(restaurantID, input.Status, input.Filters)
+	orders, metadata, err := app.models.Orders.GetAllForRestaurant(r.Context(), restaurantID, input.Status, input.FulfilmentType, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	type order_item struct {
-		Name     string `json:"dish"`
-		Quantity int    `json:"quantity"`
-		Subtotal int64  `json:"subtotal"`
-	}
-
-	type fullOrder struct {
-		Order data.Order   `json:"order"`
-		Items []order_item `json:"items"`
-	}
-
-	fullOrders := []fullOrder{}
-
-	for _, order := range orders {
-		items, err := app.models.OrderItems.GetForOrder(order.ID)
-		if err != nil {
-			app.serverErrorResponse(w, r, err)
-			return
-		}
-
-		orderItems := []order_item{}
-
-		for _, item := range items {
-			dish, err := app.models.Dishes.Get(item.DishID)
-			if err != nil {
-				switch {
-				case errors.Is(err, data.ErrRecordNotFound):
-					app.notFoundResponse(w, r)
-				default:
-					app.serverErrorResponse(w, r, err)
-				}
-				return
-			}
-
-			orderItems = append(orderItems, order_item{Name: dish.Name, Quantity: item.Quantity, Subtotal: item.Subtotal})
-		}
-
-		fullOrders = append(fullOrders, fullOrder{Order: *order, Items: orderItems})
+	fullOrders, err := app.withItems(r.Context(), orders)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"orders": fullOrders, "metadata": metadata}, nil)
@@ -177,50 +285,16 @@ func (app *application) getOrdersForUserHandler(w http.ResponseWriter, r *http.R
 
 	user := app.contextGetUser(r)
 
-	orders, metadata, err := app.models.Orders.GetAllForUser(user.Id, input.Status, input.Filters)
+	orders, metadata, err := app.models.Orders.GetAllForUser(r.Context(), user.Id, input.Status, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	type order_item struct {
-		Name     string `json:"dish"`
-		Quantity int    `json:"quantity"`
-		Subtotal int64  `json:"subtotal"`
-	}
-
-	type fullOrder struct {
-		Order data.Order   `json:"order"`
-		Items []order_item `json:"items"`
-	}
-
-	fullOrders := []fullOrder{}
-
-	for _, order := range orders {
-		items, err := app.models.OrderItems.GetForOrder(order.ID)
-		if err != nil {
-			app.serverErrorResponse(w, r, err)
-			return
-		}
-
-		orderItems := []order_item{}
-
-		for _, item := range items {
-			dish, err := app.models.Dishes.Get(item.DishID)
-			if err != nil {
-				switch {
-				case errors.Is(err, data.ErrRecordNotFound):
-					app.notFoundResponse(w, r)
-				default:
-					app.serverErrorResponse(w, r, err)
-				}
-				return
-			}
-
-			orderItems = append(orderItems, order_item{Name: dish.Name, Quantity: item.Quantity, Subtotal: item.Subtotal})
-		}
-
-		fullOrders = append(fullOrders, fullOrder{Order: *order, Items: orderItems})
+	fullOrders, err := app.withItems(r.Context(), orders)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"orders": fullOrders, "metadata": metadata}, nil)
@@ -242,7 +316,7 @@ func (app *application) getSingleOrderForRestaurantHandler(w http.ResponseWriter
 		return
 	}
 
-	order, err := app.models.Orders.GetForRestaurant(orderID, restaurantID)
+	order, err := app.models.Orders.GetForRestaurant(r.Context(), orderID, restaurantID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -253,41 +327,13 @@ func (app *application) getSingleOrderForRestaurantHandler(w http.ResponseWriter
 		return
 	}
 
-	type order_item struct {
-		Name     string `json:"dish"`
-		Quantity int    `json:"quantity"`
-		Subtotal int64  `json:"subtotal"`
-	}
-
-	type fullOrder struct {
-		Order data.Order   `json:"order"`
-		Items []order_item `json:"items"`
-	}
-
-	orderItems := []order_item{}
-
-	items, err := app.models.OrderItems.GetForOrder(order.ID)
+	items, err := app.models.OrderItems.GetForOrder(r.Context(), order.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	for _, item := range items {
-		dish, err := app.models.Dishes.Get(item.DishID)
-		if err != nil {
-			switch {
-			case errors.Is(err, data.ErrRecordNotFound):
-				app.notFoundResponse(w, r)
-			default:
-				app.serverErrorResponse(w, r, err)
-			}
-			return
-		}
-
-		orderItems = append(orderItems, order_item{Name: dish.Name, Quantity: item.Quantity, Subtotal: item.Subtotal})
-	}
-
-	detailedOrder := fullOrder{Order: *order, Items: orderItems}
+	detailedOrder := fullOrder{Order: *order, Items: summarizeItems(items)}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"order": detailedOrder}, nil)
 	if err != nil {
@@ -303,7 +349,7 @@ func (app *application) getSingleOrderForUserHandler(w http.ResponseWriter, r *h
 	}
 	user := app.contextGetUser(r)
 
-	order, err := app.models.Orders.GetForUser(order_id, user.Id)
+	order, err := app.models.Orders.GetForUser(r.Context(), order_id, user.Id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -314,41 +360,13 @@ func (app *application) getSingleOrderForUserHandler(w http.ResponseWriter, r *h
 		return
 	}
 
-	type order_item struct {
-		Name     string `json:"dish"`
-		Quantity int    `json:"quantity"`
-		Subtotal int64  `json:"subtotal"`
-	}
-
-	type fullOrder struct {
-		Order data.Order   `json:"order"`
-		Items []order_item `json:"items"`
-	}
-
-	orderItems := []order_item{}
-
-	items, err := app.models.OrderItems.GetForOrder(order.ID)
+	items, err := app.models.OrderItems.GetForOrder(r.Context(), order.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	for _, item := range items {
-		dish, err := app.models.Dishes.Get(item.DishID)
-		if err != nil {
-			switch {
-			case errors.Is(err, data.ErrRecordNotFound):
-				app.notFoundResponse(w, r)
-			default:
-				app.serverErrorResponse(w, r, err)
-			}
-			return
-		}
-
-		orderItems = append(orderItems, order_item{Name: dish.Name, Quantity: item.Quantity, Subtotal: item.Subtotal})
-	}
-
-	detailedOrder := fullOrder{Order: *order, Items: orderItems}
+	detailedOrder := fullOrder{Order: *order, Items: summarizeItems(items)}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"order": detailedOrder}, nil)
 	if err != nil {
@@ -369,7 +387,7 @@ func (app *application) updateOrderHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	order, err := app.models.Orders.GetForRestaurant(orderID, restaurantID)
+	order, err := app.models.Orders.GetForRestaurant(r.Context(), orderID, restaurantID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -390,9 +408,14 @@ func (app *application) updateOrderHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	previousStatus := order.Status
+
 	if input.Status != nil {
 		v := validator.New()
-		data.ValidateStatusTransition(v, order.Status, *input.Status)
+		data.ValidateStatusTransition(v, order.FulfilmentType, data.ActorStaff, order.Status, *input.Status)
+		if *input.Status == "confirmed" {
+			data.ValidateMinimumOrder(v, order)
+		}
 		if !v.Valid() {
 			app.failedValidationResponse(w, r, v.Errors)
 			return
@@ -408,7 +431,7 @@ func (app *application) updateOrderHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.models.Orders.Update(order)
+	err = app.models.Orders.Update(r.Context(), order)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -419,6 +442,12 @@ func (app *application) updateOrderHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	app.prometheus.orderStatusChanged(previousStatus, order.Status)
+
+	if order.Status != previousStatus {
+		app.audit(r, data.AuditOrderUpdateStatus, "order", order.ID, envelope{"status": previousStatus}, envelope{"status": order.Status})
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"order": order}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
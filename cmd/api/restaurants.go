@@ -4,13 +4,16 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/xtommas/food-backend/internal/data"
 	"github.com/xtommas/food-backend/internal/validator"
 )
 
 func (app *application) listRestaurantsHandler(w http.ResponseWriter, r *http.Request) {
-	restaurants, err := app.models.Restaurants.GetAll()
+	user := app.contextGetUser(r)
+
+	restaurants, err := app.models.Restaurants.GetAll(r.Context(), user.Id)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -29,7 +32,7 @@ func (app *application) showRestaurantHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	restaurant, err := app.models.Restaurants.Get(id)
+	restaurant, err := app.models.Restaurants.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -40,6 +43,18 @@ func (app *application) showRestaurantHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	user := app.contextGetUser(r)
+
+	restaurant.IsFavorite, err = app.models.Favorites.IsFavoriteRestaurant(r.Context(), user.Id, restaurant.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if app.checkNotModified(w, r, restaurantETag(restaurant), time.Time{}) {
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"restaurant": restaurant}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -83,12 +98,14 @@ func (app *application) createRestaurantHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.models.Restaurants.Insert(restaurant)
+	err = app.models.Restaurants.Insert(r.Context(), restaurant)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.audit(r, data.AuditRestaurantCreate, "restaurant", restaurant.ID, nil, restaurant)
+
 	err = app.writeJSON(w, http.StatusCreated, envelope{"restaurant": restaurant}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -102,7 +119,7 @@ func (app *application) updateRestaurantHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	restaurant, err := app.models.Restaurants.Get(id)
+	restaurant, err := app.models.Restaurants.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -113,6 +130,8 @@ func (app *application) updateRestaurantHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	before := *restaurant
+
 	var input struct {
 		Name      *string  `json:"name"`
 		Photo     *string  `json:"photo"`
@@ -165,7 +184,7 @@ func (app *application) updateRestaurantHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.models.Restaurants.Update(restaurant)
+	err = app.models.Restaurants.Update(r.Context(), restaurant)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -176,6 +195,8 @@ func (app *application) updateRestaurantHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	app.audit(r, data.AuditRestaurantUpdate, "restaurant", restaurant.ID, &before, restaurant)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"restaurant": restaurant}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -189,7 +210,32 @@ func (app *application) deleteRestaurantHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.models.Restaurants.Delete(id)
+	restaurant, err := app.models.Restaurants.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// the gallery rows and dishes are deleted along with the restaurant, but not
+	// their files
+	galleryImages, err := app.models.RestaurantImages.GetAll(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	dishPhotos, err := app.models.Dishes.GetPhotoKeysForRestaurant(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Restaurants.Delete(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -200,6 +246,21 @@ func (app *application) deleteRestaurantHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	app.audit(r, data.AuditRestaurantDelete, "restaurant", id, restaurant, nil)
+
+	// the restaurant is already gone, so files that can't be removed are only logged
+	err = app.deleteGalleryPhotos(r.Context(), galleryImages)
+	if err != nil {
+		app.logError(r, err)
+	}
+
+	for _, key := range dishPhotos {
+		err = app.deletePhoto(r.Context(), key)
+		if err != nil {
+			app.logError(r, err)
+		}
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "restaurant successfully deleted"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -215,7 +276,7 @@ func (app *application) listRestaurantStaffHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
-	_, err = app.models.Restaurants.Get(restaurantID)
+	_, err = app.models.Restaurants.Get(r.Context(), restaurantID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -226,7 +287,7 @@ func (app *application) listRestaurantStaffHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
-	staff, err := app.models.Restaurants.GetStaff(restaurantID)
+	staff, err := app.models.Restaurants.GetStaff(r.Context(), restaurantID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -267,7 +328,7 @@ func (app *application) addRestaurantStaffHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	_, err = app.models.Restaurants.Get(restaurantID)
+	_, err = app.models.Restaurants.Get(r.Context(), restaurantID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -278,7 +339,7 @@ func (app *application) addRestaurantStaffHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	user, err := app.models.Users.GetByEmail(input.Email)
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -290,18 +351,20 @@ func (app *application) addRestaurantStaffHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = app.models.Restaurants.AddStaff(restaurantID, user.Id, input.Role)
+	err = app.models.Restaurants.AddStaff(r.Context(), restaurantID, user.Id, input.Role)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.models.Permissions.AddForUser(user.Id, "dishes:write", "orders:read", "orders:write")
+	err = app.models.Permissions.AddForUser(r.Context(), user.Id, "dishes:write", "orders:read", "orders:write")
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.audit(r, data.AuditStaffAdd, "user", user.Id, nil, envelope{"restaurant_id": restaurantID, "role": input.Role})
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "staff member successfully added"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -321,7 +384,7 @@ func (app *application) removeRestaurantStaffHandler(w http.ResponseWriter, r *h
 		return
 	}
 
-	err = app.models.Restaurants.RemoveStaff(restaurantID, userID)
+	role, err := app.models.Restaurants.GetStaffRole(r.Context(), restaurantID, userID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -332,6 +395,19 @@ func (app *application) removeRestaurantStaffHandler(w http.ResponseWriter, r *h
 		return
 	}
 
+	err = app.models.Restaurants.RemoveStaff(r.Context(), restaurantID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditStaffRemove, "user", userID, envelope{"restaurant_id": restaurantID, "role": role}, nil)
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "staff member successfully removed"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
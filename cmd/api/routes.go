@@ -10,9 +10,14 @@ func (app *application) routes() http.Handler {
 
 	mux.HandleFunc("GET /healthcheck", app.healthcheckHandler)
 
+	// presigned downloads from local file storage
+	mux.HandleFunc("GET /storage/{key...}", app.serveStoredFileHandler)
+
 	// dishes endpoints
 	mux.HandleFunc("GET /restaurants/{restaurant_id}/dishes", app.requirePermission("dishes:read", app.listDishesHandler))
 	mux.HandleFunc("POST /restaurants/{restaurant_id}/dishes", app.requireRestaurantStaff(app.createDishHandler))
+	mux.HandleFunc("POST /restaurants/{restaurant_id}/dishes/import", app.requireRestaurantStaff(app.importMenuHandler))
+	mux.HandleFunc("GET /restaurants/{restaurant_id}/dishes/export", app.requireRestaurantStaff(app.exportMenuHandler))
 	mux.HandleFunc("GET /restaurants/{restaurant_id}/dishes/{id}", app.requirePermission("dishes:read", app.showDishHandler))
 	mux.HandleFunc("PATCH /restaurants/{restaurant_id}/dishes/{id}", app.requireRestaurantStaff(app.updateDishHandler))
 	mux.HandleFunc("DELETE /restaurants/{restaurant_id}/dishes/{id}", app.requireRestaurantStaff(app.deleteDishHandler))
@@ -31,28 +36,95 @@ func (app *application) routes() http.Handler {
 	mux.HandleFunc("POST /restaurants/{restaurant_id}/staff", app.requireRestaurantOwner(app.addRestaurantStaffHandler))
 	mux.HandleFunc("DELETE /restaurants/{restaurant_id}/staff/{user_id}", app.requireRestaurantOwner(app.removeRestaurantStaffHandler))
 
+	// gallery endpoints
+	mux.HandleFunc("GET /restaurants/{restaurant_id}/images", app.requirePermission("restaurants:read", app.listGalleryImagesHandler))
+	mux.HandleFunc("POST /restaurants/{restaurant_id}/images", app.requireRestaurantStaff(app.uploadGalleryImageHandler))
+	mux.HandleFunc("PUT /restaurants/{restaurant_id}/images/order", app.requireRestaurantStaff(app.reorderGalleryImagesHandler))
+	mux.HandleFunc("PATCH /restaurants/{restaurant_id}/images/{image_id}", app.requireRestaurantStaff(app.updateGalleryImageHandler))
+	mux.HandleFunc("DELETE /restaurants/{restaurant_id}/images/{image_id}", app.requireRestaurantStaff(app.deleteGalleryImageHandler))
+	mux.HandleFunc("GET /restaurants/{restaurant_id}/dishes/{id}/images", app.requirePermission("dishes:read", app.listGalleryImagesHandler))
+	mux.HandleFunc("POST /restaurants/{restaurant_id}/dishes/{id}/images", app.requireRestaurantStaff(app.uploadGalleryImageHandler))
+	mux.HandleFunc("PUT /restaurants/{restaurant_id}/dishes/{id}/images/order", app.requireRestaurantStaff(app.reorderGalleryImagesHandler))
+	mux.HandleFunc("PATCH /restaurants/{restaurant_id}/dishes/{id}/images/{image_id}", app.requireRestaurantStaff(app.updateGalleryImageHandler))
+	mux.HandleFunc("DELETE /restaurants/{restaurant_id}/dishes/{id}/images/{image_id}", app.requireRestaurantStaff(app.deleteGalleryImageHandler))
+
+	// delivery zones endpoints
+	mux.HandleFunc("GET /restaurants/{restaurant_id}/delivery-zones", app.requirePermission("restaurants:read", app.listDeliveryZonesHandler))
+	mux.HandleFunc("POST /restaurants/{restaurant_id}/delivery-zones", app.requireRestaurantOwner(app.createDeliveryZoneHandler))
+	mux.HandleFunc("GET /restaurants/{restaurant_id}/delivery-zones/{zone_id}", app.requirePermission("restaurants:read", app.showDeliveryZoneHandler))
+	mux.HandleFunc("PATCH /restaurants/{restaurant_id}/delivery-zones/{zone_id}", app.requireRestaurantOwner(app.updateDeliveryZoneHandler))
+	mux.HandleFunc("DELETE /restaurants/{restaurant_id}/delivery-zones/{zone_id}", app.requireRestaurantOwner(app.deleteDeliveryZoneHandler))
+
+	// reviews endpoints
+	mux.HandleFunc("GET /restaurants/{restaurant_id}/reviews", app.requirePermission("restaurants:read", app.listRestaurantReviewsHandler))
+	mux.HandleFunc("POST /restaurants/{restaurant_id}/reviews/{review_id}/reply", app.requireRestaurantOwner(app.replyToReviewHandler))
+
+	// analytics endpoints
+	mux.HandleFunc("GET /restaurants/{restaurant_id}/analytics", app.requireRestaurantOwner(app.restaurantAnalyticsHandler))
+
 	// users endpoints
 	mux.HandleFunc("POST /users", app.registerUserHandler)
 	mux.HandleFunc("PUT /users/activate", app.activateUserHandler)
 	mux.HandleFunc("PUT /users/password", app.updateUserPasswordHandler)
+	mux.HandleFunc("PUT /users/email", app.confirmEmailChangeHandler)
 	mux.HandleFunc("GET /users/me", app.requireActivatedUser(app.getUserDataHandler))
 	mux.HandleFunc("POST /users/me/photo", app.requireActivatedUser(app.uploadUserPhotoHandler))
 	mux.HandleFunc("GET /users/me/photo", app.requireActivatedUser(app.serveUserPhotoHandler))
 	mux.HandleFunc("PATCH /users/me", app.requireActivatedUser(app.updateUserHandler))
+	mux.HandleFunc("DELETE /users/me", app.requireAuthenticatedUser(app.deleteUserHandler))
+	mux.HandleFunc("GET /users/me/export", app.requireAuthenticatedUser(app.exportUserDataHandler))
+
+	// address book endpoints
+	mux.HandleFunc("GET /users/me/addresses", app.requireActivatedUser(app.listAddressesHandler))
+	mux.HandleFunc("POST /users/me/addresses", app.requireActivatedUser(app.createAddressHandler))
+	mux.HandleFunc("GET /users/me/addresses/{address_id}", app.requireActivatedUser(app.showAddressHandler))
+	mux.HandleFunc("PATCH /users/me/addresses/{address_id}", app.requireActivatedUser(app.updateAddressHandler))
+	mux.HandleFunc("DELETE /users/me/addresses/{address_id}", app.requireActivatedUser(app.deleteAddressHandler))
+
+	// favorites endpoints
+	mux.HandleFunc("GET /users/me/favorites/restaurants", app.requireActivatedUser(app.listFavoriteRestaurantsHandler))
+	mux.HandleFunc("PUT /users/me/favorites/restaurants/{restaurant_id}", app.requireActivatedUser(app.addFavoriteRestaurantHandler))
+	mux.HandleFunc("DELETE /users/me/favorites/restaurants/{restaurant_id}", app.requireActivatedUser(app.removeFavoriteRestaurantHandler))
+	mux.HandleFunc("GET /users/me/favorites/dishes", app.requireActivatedUser(app.listFavoriteDishesHandler))
+	mux.HandleFunc("PUT /users/me/favorites/dishes/{dish_id}", app.requireActivatedUser(app.addFavoriteDishHandler))
+	mux.HandleFunc("DELETE /users/me/favorites/dishes/{dish_id}", app.requireActivatedUser(app.removeFavoriteDishHandler))
 
 	// admin endpoints
 	mux.HandleFunc("POST /admin/promote", app.requireAdmin(app.promoteUserHandler))
+	mux.HandleFunc("POST /admin/couriers", app.requireAdmin(app.promoteCourierHandler))
+	mux.HandleFunc("GET /admin/users", app.requireAdmin(app.listUsersHandler))
+	mux.HandleFunc("GET /admin/users/{id}", app.requireAdmin(app.showUserHandler))
+	mux.HandleFunc("PATCH /admin/users/{id}", app.requireAdmin(app.adminUpdateUserHandler))
+	mux.HandleFunc("POST /admin/users/{id}/suspend", app.requireAdmin(app.suspendUserHandler))
+	mux.HandleFunc("POST /admin/users/{id}/ban", app.requireAdmin(app.banUserHandler))
+	mux.HandleFunc("POST /admin/users/{id}/reinstate", app.requireAdmin(app.reinstateUserHandler))
+	mux.HandleFunc("POST /admin/users/{id}/permissions", app.requireAdmin(app.addUserPermissionsHandler))
+	mux.HandleFunc("DELETE /admin/users/{id}/permissions/{code}", app.requireAdmin(app.deleteUserPermissionHandler))
+	mux.HandleFunc("GET /admin/reviews", app.requireAdmin(app.listReviewsForModerationHandler))
+	mux.HandleFunc("PATCH /admin/reviews/{review_id}", app.requireAdmin(app.moderateReviewHandler))
+	mux.HandleFunc("GET /admin/audit-log", app.requireAdmin(app.listAuditLogHandler))
 
 	// orders endpoints
 	mux.HandleFunc("POST /restaurants/{restaurant_id}/orders", app.requireActivatedUser(app.createOrderHandler))
 	mux.HandleFunc("GET /restaurants/{restaurant_id}/orders", app.requireRestaurantStaff(app.getOrdersForRestaurantHandler))
 	mux.HandleFunc("GET /users/me/orders", app.requireActivatedUser(app.getOrdersForUserHandler))
+	mux.HandleFunc("GET /restaurants/{restaurant_id}/orders/export", app.requireRestaurantStaff(app.exportOrdersHandler))
 	mux.HandleFunc("GET /restaurants/{restaurant_id}/orders/{order_id}", app.requireRestaurantStaff(app.getSingleOrderForRestaurantHandler))
 	mux.HandleFunc("GET /users/me/orders/{order_id}", app.requireActivatedUser(app.getSingleOrderForUserHandler))
 	mux.HandleFunc("PATCH /restaurants/{restaurant_id}/orders/{order_id}", app.requireRestaurantStaff(app.updateOrderHandler))
 	mux.HandleFunc("POST /restaurants/{restaurant_id}/orders/{order_id}/items", app.requireActivatedUser(app.createOrderItemHandler))
 	mux.HandleFunc("GET /restaurants/{restaurant_id}/orders/{order_id}/items", app.requireRestaurantStaff(app.getOrderItemsHandler))
 	mux.HandleFunc("GET /users/me/orders/{order_id}/items", app.requireActivatedUser(app.getUserOrderItemsHandler))
+	mux.HandleFunc("GET /users/me/orders/{order_id}/location", app.requireActivatedUser(app.getOrderLocationHandler))
+	mux.HandleFunc("POST /users/me/orders/{order_id}/review", app.requireActivatedUser(app.createReviewHandler))
+
+	// courier endpoints
+	mux.HandleFunc("GET /courier/orders/available", app.requireCourier(app.listUnclaimedOrdersHandler))
+	mux.HandleFunc("GET /courier/orders", app.requireCourier(app.getOrdersForCourierHandler))
+	mux.HandleFunc("POST /courier/orders/{order_id}/claim", app.requireCourier(app.claimOrderHandler))
+	mux.HandleFunc("POST /courier/orders/{order_id}/pickup", app.requireCourier(app.pickUpOrderHandler))
+	mux.HandleFunc("POST /courier/orders/{order_id}/deliver", app.requireCourier(app.deliverOrderHandler))
+	mux.HandleFunc("POST /courier/orders/{order_id}/location", app.requireCourier(app.createCourierLocationHandler))
 
 	// tokens endpoints
 	mux.HandleFunc("POST /tokens/authentication", app.createAuthenticationTokenHandler)
@@ -61,11 +133,12 @@ func (app *application) routes() http.Handler {
 
 	// debug
 	mux.Handle("GET /debug/vars", expvar.Handler())
+	mux.Handle("GET /metrics", app.prometheus.handler())
 
 	// Custom not-found and method-not-allowed responses
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		app.notFoundResponse(w, r)
 	})
 
-	return app.metrics(app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(mux)))))
+	return app.metrics(mux, app.trace(mux, app.logRequest(mux, app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(mux)))))))
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,14 +13,33 @@ import (
 	"time"
 )
 
+// writeTimeout is how long a handler has to write its response. Streamed
+// downloads extend it with extendWriteDeadline
+const writeTimeout = 30 * time.Second
+
 func (app *application) serve() error {
+	// every request context derives from this one, so cancelling it stops the
+	// queries of requests still running when the shutdown grace period ends
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
+	// background jobs run until the server starts shutting down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	app.pruneCourierLocations(jobsCtx)
+
 	// declare HTTP server
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", app.config.port),
 		Handler:      app.routes(),
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		WriteTimeout: writeTimeout,
+		ErrorLog:     log.New(app.logger, "", 0),
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
 	}
 
 	// shutdown channel to receive erros returned by Shutdown()
@@ -44,6 +65,7 @@ func (app *application) serve() error {
 
 		err := srv.Shutdown(ctx)
 		if err != nil {
+			cancelRequests()
 			shutdownError <- err
 		}
 
@@ -51,6 +73,8 @@ func (app *application) serve() error {
 			"addr": srv.Addr,
 		})
 
+		stopJobs()
+
 		// block until the WaitGroup counter is zero -- essentially, until the background goroutines have finished
 		app.wg.Wait()
 		shutdownError <- nil
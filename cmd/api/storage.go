@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xtommas/food-backend/internal/images"
+	"github.com/xtommas/food-backend/internal/storage"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+// openStorage builds the file storage backend selected by STORAGE_BACKEND
+func openStorage(cfg config) (storage.Storage, error) {
+	switch cfg.storage.backend {
+	case "local":
+		return storage.NewLocal(cfg.storage.localRoot, cfg.storage.publicURL, []byte(cfg.storage.secret)), nil
+	case "s3":
+		if cfg.storage.s3.Endpoint == "" {
+			return nil, errors.New("S3_ENDPOINT must be set when STORAGE_BACKEND is s3")
+		}
+
+		s3, err := storage.NewS3(cfg.storage.s3)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		err = s3.EnsureBucket(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return s3, nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q, must be local or s3", cfg.storage.backend)
+	}
+}
+
+// servePhoto streams the size of a photo picked with ?size=, large by default
+func (app *application) servePhoto(w http.ResponseWriter, r *http.Request, key string) {
+	v := validator.New()
+
+	size := app.readString(r.URL.Query(), "size", images.SizeLarge)
+
+	if v.Check(validator.PermittedValue(size, images.Sizes...), "size", "must be one of large, medium or thumbnail"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if key == "" {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	object, err := app.storage.Get(r.Context(), images.VariantKey(key, size))
+	if errors.Is(err, storage.ErrNotFound) {
+		// photos uploaded before sizes were introduced only have the original
+		object, err = app.storage.Get(r.Context(), key)
+	}
+
+	app.writeStoredObject(w, r, object, err)
+}
+
+// serveStoredFile streams a stored file, or responds not found when there is none
+func (app *application) serveStoredFile(w http.ResponseWriter, r *http.Request, key string) {
+	object, err := app.storage.Get(r.Context(), key)
+
+	app.writeStoredObject(w, r, object, err)
+}
+
+// writeStoredObject writes an object opened with Storage.Get, or the error from opening it
+func (app *application) writeStoredObject(w http.ResponseWriter, r *http.Request, object *storage.Object, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrInvalidKey):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer object.Body.Close()
+
+	// ServeContent answers conditional and range requests from these headers
+	w.Header().Set("Content-Type", object.ContentType)
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", objectETag(object))
+
+	http.ServeContent(w, r, "", object.ModTime, object.Body)
+}
+
+// serveStoredFileHandler serves files from local storage through presigned URLs.
+// With the S3 backend presigned URLs point at the bucket, so this endpoint is not found
+func (app *application) serveStoredFileHandler(w http.ResponseWriter, r *http.Request) {
+	local, ok := app.storage.(*storage.Local)
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	key := r.PathValue("key")
+	qs := r.URL.Query()
+
+	if !local.Verify(key, qs.Get("expires"), qs.Get("signature")) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	app.serveStoredFile(w, r, key)
+}
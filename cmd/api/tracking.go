@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/xtommas/food-backend/internal/data"
+	"github.com/xtommas/food-backend/internal/geo"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+func (app *application) createCourierLocationHandler(w http.ResponseWriter, r *http.Request) {
+	orderID, err := app.readIdParam(r, "order_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	order, err := app.models.Orders.GetForCourier(r.Context(), orderID, user.Id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.Latitude != nil, "latitude", "must be provided")
+	v.Check(input.Longitude != nil, "longitude", "must be provided")
+	v.Check(order.Status == "out_for_delivery", "status", "location can only be shared while the order is out for delivery")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	location := &data.CourierLocation{
+		OrderID:   order.ID,
+		CourierID: user.Id,
+		Latitude:  *input.Latitude,
+		Longitude: *input.Longitude,
+	}
+
+	if data.ValidateCourierLocation(v, location); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.CourierLocations.Insert(r.Context(), location, app.config.tracking.pingInterval)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPingTooSoon):
+			app.rateLimitExceededResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"location": location}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getOrderLocationHandler(w http.ResponseWriter, r *http.Request) {
+	orderID, err := app.readIdParam(r, "order_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	order, err := app.models.Orders.GetForUser(r.Context(), orderID, user.Id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if order.Status != "out_for_delivery" {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	location, err := app.models.CourierLocations.GetLatestForOrder(r.Context(), order.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	env := envelope{"location": location}
+
+	// the ETA can only be estimated when the order has coordinates for its destination
+	if order.HasDestination {
+		distance := geo.Distance(location.Latitude, location.Longitude, order.Latitude, order.Longitude)
+		eta := estimateTravelTime(distance, app.config.tracking.courierSpeedKmh)
+
+		env["distance_meters"] = math.Round(distance)
+		env["eta_seconds"] = int64(eta.Seconds())
+		env["estimated_arrival"] = location.RecordedAt.Add(eta).UTC()
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// estimates how long it takes to cover a distance in metres at an average speed in km/h
+func estimateTravelTime(meters, speedKmh float64) time.Duration {
+	if speedKmh <= 0 {
+		return 0
+	}
+
+	hours := (meters / 1000) / speedKmh
+	return time.Duration(hours * float64(time.Hour)).Round(time.Second)
+}
+
+// pruneCourierLocations starts a background goroutine that deletes pings older
+// than the configured retention period every prune interval, until ctx is
+// cancelled. The server waits for it through app.wg when shutting down
+func (app *application) pruneCourierLocations(ctx context.Context) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		ticker := time.NewTicker(app.config.tracking.pruneInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			cutoff := time.Now().Add(-app.config.tracking.retention)
+
+			deleted, err := app.models.CourierLocations.DeleteOlderThan(ctx, cutoff)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				app.logger.PrintError(err, map[string]string{"job": "prune_courier_locations"})
+				continue
+			}
+
+			if deleted > 0 {
+				app.logger.PrintInfo("pruned courier locations", map[string]string{
+					"deleted": fmt.Sprint(deleted),
+				})
+			}
+		}
+	}()
+}
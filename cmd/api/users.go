@@ -2,12 +2,9 @@ package main
 
 import (
 	"errors"
-	"io"
-	"mime"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pascaldekloe/jwt"
@@ -50,7 +47,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Users.Insert(user)
+	err = app.models.Users.Insert(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
@@ -62,7 +59,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Permissions.AddForUser(user.Id, "dishes:read", "restaurants:read")
+	err = app.models.Permissions.AddForUser(r.Context(), user.Id, rolePermissions["customer"]...)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -133,7 +130,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	user, err := app.models.Users.Get(userId)
+	user, err := app.models.Users.Get(r.Context(), userId)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -146,7 +143,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 
 	user.Activated = true
 
-	err = app.models.Users.Update(user)
+	err = app.models.Users.Update(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -218,7 +215,7 @@ func (app *application) updateUserPasswordHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	user, err := app.models.Users.Get(userId)
+	user, err := app.models.Users.Get(r.Context(), userId)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -235,7 +232,10 @@ func (app *application) updateUserPasswordHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = app.models.Users.Update(user)
+	// an email change started by whoever knew the old password is cancelled
+	user.PendingEmail = ""
+
+	err = app.models.Users.Update(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -246,6 +246,12 @@ func (app *application) updateUserPasswordHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	err = app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeEmailChange, user.Id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	env := envelope{"message": "your password was successfully reset"}
 
 	err = app.writeJSON(w, http.StatusOK, env, nil)
@@ -272,7 +278,7 @@ func (app *application) promoteUserHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	user, err := app.models.Users.GetByEmail(input.Email)
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -284,9 +290,10 @@ func (app *application) promoteUserHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	previousRole := user.Role
 	user.Role = "admin"
 
-	err = app.models.Users.Update(user)
+	err = app.models.Users.UpdateRole(r.Context(), user, rolePermissions["admin"]...)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -297,13 +304,67 @@ func (app *application) promoteUserHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.models.Permissions.AddForUser(user.Id, "dishes:read", "dishes:write", "restaurants:read", "orders:read", "orders:write")
+	app.audit(r, data.AuditUserPromoteAdmin, "user", user.Id, envelope{"role": previousRole}, envelope{"role": user.Role})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "user successfully promoted to admin"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) promoteCourierHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "user successfully promoted to admin"}, nil)
+	v := validator.New()
+	data.ValidateEmail(v, input.Email)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("email", "no user found with this email address")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if user.IsAdmin() {
+		v.AddError("email", "admins cannot be made couriers")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	previousRole := user.Role
+	user.Role = "courier"
+
+	err = app.models.Users.UpdateRole(r.Context(), user, rolePermissions["courier"]...)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.audit(r, data.AuditUserPromoteCourier, "user", user.Id, envelope{"role": previousRole}, envelope{"role": user.Role})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "user successfully promoted to courier"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -327,17 +388,30 @@ func (app *application) uploadUserPhotoHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	fileName := strconv.FormatInt(int64(user.Id), 10) + ".jpg"
-	folder := "images/users/"
+	v := validator.New()
+	previous := user.Photo
 
-	user.Photo, err = app.storeImage(w, r, folder, fileName)
+	user.Photo, err = app.storePhoto(r, "users/"+strconv.FormatInt(int64(user.Id), 10), v)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.models.Users.Update(user)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Users.Update(r.Context(), user)
 	if err != nil {
+		// the row still points to the previous photo. An upload in the same
+		// format has already replaced its files, so only another key is removed
+		if user.Photo != previous {
+			if deleteErr := app.deletePhoto(r.Context(), user.Photo); deleteErr != nil {
+				app.logError(r, deleteErr)
+			}
+		}
+
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
@@ -347,6 +421,15 @@ func (app *application) uploadUserPhotoHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// a new upload in another format leaves the old sizes behind. The user
+	// already points to the new photo, so failing to remove them is only logged
+	if previous != user.Photo {
+		err = app.deletePhoto(r.Context(), previous)
+		if err != nil {
+			app.logError(r, err)
+		}
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -356,35 +439,129 @@ func (app *application) uploadUserPhotoHandler(w http.ResponseWriter, r *http.Re
 func (app *application) serveUserPhotoHandler(w http.ResponseWriter, r *http.Request) {
 	user := app.contextGetUser(r)
 
-	imagePath := user.Photo
+	app.servePhoto(w, r, user.Photo)
+}
 
-	// Open the image file
-	imageFile, err := os.Open(imagePath)
+// emailChangeTokenTTL is how long the new address has to confirm an email change
+const emailChangeTokenTTL = 24 * time.Hour
+
+func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Name     *string `json:"name"`
+		Email    *string `json:"email"`
+		Password string  `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.notFoundResponse(w, r)
+		app.badRequestResponse(w, r, err)
 		return
 	}
-	defer imageFile.Close()
 
-	// Get the image's content type
-	contentType := mime.TypeByExtension(filepath.Ext(imagePath))
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+
+	v := validator.New()
+
+	// a new email only replaces the current one once it's confirmed, so a typo
+	// can't lock the owner out. Starting a change needs the current password,
+	// since a stolen token alone would otherwise be enough to move the account
+	// to another address. Sending the current email cancels a pending change
+	newEmail := ""
+	if input.Email != nil {
+		if strings.EqualFold(*input.Email, user.Email) {
+			user.PendingEmail = ""
+		} else {
+			newEmail = *input.Email
+			data.ValidateEmail(v, newEmail)
+			v.Check(input.Password != "", "password", "must be provided to change the email")
+		}
+	}
+
+	if data.ValidateUser(v, user); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if newEmail != "" {
+		match, err := user.Password.Matches(input.Password)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
 
-	// Set the content type header
-	w.Header().Set("Content-Type", contentType)
+		if !match {
+			app.invalidCredentialsResponse(w, r)
+			return
+		}
 
-	// Copy the image contents to the response
-	_, err = io.Copy(w, imageFile)
+		_, err = app.models.Users.GetByEmail(r.Context(), newEmail)
+		switch {
+		case err == nil:
+			v.AddError("email", "a user with this email address already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		case !errors.Is(err, data.ErrRecordNotFound):
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		user.PendingEmail = newEmail
+	}
+
+	err = app.models.Users.Update(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if input.Email != nil {
+		// only the latest change can be confirmed
+		err = app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeEmailChange, user.Id)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if newEmail != "" {
+		token, err := app.models.Tokens.New(r.Context(), user.Id, emailChangeTokenTTL, data.ScopeEmailChange)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.sendEmail(newEmail, "email_change_confirm.tmpl", map[string]any{
+			"Name":   user.Name,
+			"Token":  token.Plaintext,
+			"Expiry": token.Expiry.UTC().Format(time.RFC3339),
+		})
+
+		app.sendEmail(user.Email, "email_change_notice.tmpl", map[string]any{
+			"Name":     user.Name,
+			"NewEmail": newEmail,
+		})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
-func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request) {
-	user := app.contextGetUser(r)
-
+// confirmEmailChangeHandler completes an email change with the token sent to
+// the new address. It needs no authentication, like account activation
+func (app *application) confirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Name  *string `json:"name"`
-		Email *string `json:"email"`
+		TokenPlaintext string `json:"token"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -393,24 +570,41 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if input.Name != nil {
-		user.Name = *input.Name
-	}
+	v := validator.New()
 
-	if input.Email != nil {
-		user.Email = *input.Email
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
 	}
 
-	v := validator.New()
+	user, err := app.models.Users.GetForToken(r.Context(), data.ScopeEmailChange, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired email change token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
 
-	if data.ValidateUser(v, user); !v.Valid() {
+	if user.PendingEmail == "" {
+		v.AddError("token", "invalid or expired email change token")
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Users.Update(user)
+	user.Email = user.PendingEmail
+	user.PendingEmail = ""
+
+	err = app.models.Users.Update(r.Context(), user)
 	if err != nil {
 		switch {
+		case errors.Is(err, data.ErrDuplicateEmail):
+			// taken by another account since the change was requested
+			v.AddError("email", "a user with this email address already exists")
+			app.failedValidationResponse(w, r, v.Errors)
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
 		default:
@@ -419,6 +613,12 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	err = app.models.Tokens.DeleteAllForUser(r.Context(), data.ScopeEmailChange, user.Id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -0,0 +1,359 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+type Address struct {
+	ID             int64     `json:"id"`
+	UserID         int64     `json:"user_id"`
+	Label          string    `json:"label"`
+	Street         string    `json:"street"`
+	City           string    `json:"city"`
+	PostalCode     string    `json:"postal_code,omitempty"`
+	Latitude       float64   `json:"latitude,omitempty"`
+	Longitude      float64   `json:"longitude,omitempty"`
+	HasCoordinates bool      `json:"-"`
+	Instructions   string    `json:"instructions,omitempty"`
+	IsDefault      bool      `json:"is_default"`
+	CreatedAt      time.Time `json:"created_at"`
+	Version        int       `json:"-"`
+}
+
+// Format renders the address as the single line that gets snapshotted into orders
+func (a *Address) Format() string {
+	city := a.City
+	if a.PostalCode != "" {
+		city = a.PostalCode + " " + a.City
+	}
+
+	return strings.Join([]string{a.Street, city}, ", ")
+}
+
+func ValidateUserAddress(v *validator.Validator, address *Address) {
+	v.Check(address.Label != "", "label", "must be provided")
+	v.Check(utf8.RuneCountInString(address.Label) <= 50, "label", "must be no more than 50 characters long")
+
+	v.Check(address.Street != "", "street", "must be provided")
+	v.Check(utf8.RuneCountInString(address.Street) <= 200, "street", "must be no more than 200 characters long")
+
+	v.Check(address.City != "", "city", "must be provided")
+	v.Check(utf8.RuneCountInString(address.City) <= 100, "city", "must be no more than 100 characters long")
+
+	v.Check(utf8.RuneCountInString(address.PostalCode) <= 20, "postal_code", "must be no more than 20 characters long")
+	v.Check(utf8.RuneCountInString(address.Instructions) <= 280, "instructions", "must be no more than 280 characters long")
+
+	if address.HasCoordinates {
+		v.Check(address.Latitude >= -90 && address.Latitude <= 90, "latitude", "must be between -90 and 90")
+		v.Check(address.Longitude >= -180 && address.Longitude <= 180, "longitude", "must be between -180 and 180")
+	}
+}
+
+type AddressModel struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+// Insert adds an address to the user's address book. When the new address is
+// the default, the previous default is cleared in the same transaction
+func (m AddressModel) Insert(ctx context.Context, address *Address) error {
+	ctx, span := startSpan(ctx, "AddressModel.Insert")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if address.IsDefault {
+		err = clearDefaultAddress(ctx, tx, address.UserID, address.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO user_addresses (user_id, label, street, city, postal_code, latitude, longitude, has_coordinates, instructions, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, version`
+
+	args := []any{
+		address.UserID,
+		address.Label,
+		address.Street,
+		address.City,
+		address.PostalCode,
+		address.Latitude,
+		address.Longitude,
+		address.HasCoordinates,
+		address.Instructions,
+		address.IsDefault,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&address.ID, &address.CreatedAt, &address.Version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m AddressModel) Get(ctx context.Context, id int64, userID int64) (*Address, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, user_id, label, street, city, postal_code, latitude, longitude, has_coordinates, instructions, is_default, created_at, version
+		FROM user_addresses
+		WHERE id = $1 AND user_id = $2`
+
+	var address Address
+
+	ctx, span := startSpan(ctx, "AddressModel.Get")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&address.ID,
+		&address.UserID,
+		&address.Label,
+		&address.Street,
+		&address.City,
+		&address.PostalCode,
+		&address.Latitude,
+		&address.Longitude,
+		&address.HasCoordinates,
+		&address.Instructions,
+		&address.IsDefault,
+		&address.CreatedAt,
+		&address.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &address, nil
+}
+
+func (m AddressModel) GetDefaultForUser(ctx context.Context, userID int64) (*Address, error) {
+	query := `
+		SELECT id, user_id, label, street, city, postal_code, latitude, longitude, has_coordinates, instructions, is_default, created_at, version
+		FROM user_addresses
+		WHERE user_id = $1 AND is_default`
+
+	var address Address
+
+	ctx, span := startSpan(ctx, "AddressModel.GetDefaultForUser")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		&address.ID,
+		&address.UserID,
+		&address.Label,
+		&address.Street,
+		&address.City,
+		&address.PostalCode,
+		&address.Latitude,
+		&address.Longitude,
+		&address.HasCoordinates,
+		&address.Instructions,
+		&address.IsDefault,
+		&address.CreatedAt,
+		&address.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &address, nil
+}
+
+func (m AddressModel) GetAllForUser(ctx context.Context, userID int64) ([]*Address, error) {
+	query := `
+		SELECT id, user_id, label, street, city, postal_code, latitude, longitude, has_coordinates, instructions, is_default, created_at, version
+		FROM user_addresses
+		WHERE user_id = $1
+		ORDER BY is_default DESC, label ASC, id ASC`
+
+	ctx, span := startSpan(ctx, "AddressModel.GetAllForUser")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := []*Address{}
+
+	for rows.Next() {
+		var address Address
+
+		err := rows.Scan(
+			&address.ID,
+			&address.UserID,
+			&address.Label,
+			&address.Street,
+			&address.City,
+			&address.PostalCode,
+			&address.Latitude,
+			&address.Longitude,
+			&address.HasCoordinates,
+			&address.Instructions,
+			&address.IsDefault,
+			&address.CreatedAt,
+			&address.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		addresses = append(addresses, &address)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+func (m AddressModel) Update(ctx context.Context, address *Address) error {
+	ctx, span := startSpan(ctx, "AddressModel.Update")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if address.IsDefault {
+		err = clearDefaultAddress(ctx, tx, address.UserID, address.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE user_addresses
+		SET label = $1, street = $2, city = $3, postal_code = $4, latitude = $5, longitude = $6,
+		    has_coordinates = $7, instructions = $8, is_default = $9, version = version + 1
+		WHERE id = $10 AND user_id = $11 AND version = $12
+		RETURNING version`
+
+	args := []any{
+		address.Label,
+		address.Street,
+		address.City,
+		address.PostalCode,
+		address.Latitude,
+		address.Longitude,
+		address.HasCoordinates,
+		address.Instructions,
+		address.IsDefault,
+		address.ID,
+		address.UserID,
+		address.Version,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&address.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m AddressModel) Delete(ctx context.Context, id int64, userID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM user_addresses
+		WHERE id = $1 AND user_id = $2`
+
+	ctx, span := startSpan(ctx, "AddressModel.Delete")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// unsets the user's current default address, other than the one being saved.
+// The user row is locked first, so concurrent requests making an address the
+// default are applied one at a time instead of both keeping a default
+func clearDefaultAddress(ctx context.Context, tx *sql.Tx, userID int64, keepID int64) error {
+	err := tx.QueryRowContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	query := `
+		UPDATE user_addresses
+		SET is_default = FALSE, version = version + 1
+		WHERE user_id = $1 AND is_default AND id <> $2`
+
+	_, err = tx.ExecContext(ctx, query, userID, keepID)
+	return err
+}
@@ -0,0 +1,221 @@
+package data
+
+import "testing"
+
+func insertTestAddress(t *testing.T, model AddressModel, userID int64, isDefault bool) *Address {
+	t.Helper()
+
+	address := &Address{
+		UserID:         userID,
+		Label:          "Home",
+		Street:         "123 Market Street",
+		City:           "Springfield",
+		PostalCode:     "12345",
+		Latitude:       40.7128,
+		Longitude:      -74.006,
+		HasCoordinates: true,
+		Instructions:   "Ring twice",
+		IsDefault:      isDefault,
+	}
+
+	if err := model.Insert(t.Context(), address); err != nil {
+		t.Fatalf("failed to insert test address: %v", err)
+	}
+
+	return address
+}
+
+func TestAddress_Format(t *testing.T) {
+	address := &Address{Street: "123 Market Street", City: "Springfield", PostalCode: "12345"}
+
+	if got := address.Format(); got != "123 Market Street, 12345 Springfield" {
+		t.Errorf("Format() = %q, want %q", got, "123 Market Street, 12345 Springfield")
+	}
+
+	address.PostalCode = ""
+	if got := address.Format(); got != "123 Market Street, Springfield" {
+		t.Errorf("Format() without postal code = %q, want %q", got, "123 Market Street, Springfield")
+	}
+}
+
+func TestAddressModel_InsertAndGet(t *testing.T) {
+	model := AddressModel{DB: testDB}
+	user := insertTestUser(t, UserModel{DB: testDB})
+	address := insertTestAddress(t, model, user.Id, false)
+
+	if address.ID == 0 {
+		t.Error("Insert() did not set address.ID")
+	}
+
+	fetched, err := model.Get(t.Context(), address.ID, user.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if fetched.Street != address.Street {
+		t.Errorf("Get() Street = %q, want %q", fetched.Street, address.Street)
+	}
+	if fetched.Latitude != address.Latitude {
+		t.Errorf("Get() Latitude = %v, want %v", fetched.Latitude, address.Latitude)
+	}
+	if !fetched.HasCoordinates {
+		t.Error("Get() HasCoordinates = false, want true")
+	}
+}
+
+func TestAddressModel_Get_OtherUser(t *testing.T) {
+	userModel := UserModel{DB: testDB}
+	model := AddressModel{DB: testDB}
+	owner := insertTestUser(t, userModel)
+	other := insertTestUser(t, userModel)
+	address := insertTestAddress(t, model, owner.Id, false)
+
+	_, err := model.Get(t.Context(), address.ID, other.Id)
+	if err != ErrRecordNotFound {
+		t.Errorf("Get() error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestAddressModel_DefaultSwitching(t *testing.T) {
+	model := AddressModel{DB: testDB}
+	user := insertTestUser(t, UserModel{DB: testDB})
+	first := insertTestAddress(t, model, user.Id, true)
+	second := insertTestAddress(t, model, user.Id, true)
+
+	fetched, err := model.GetDefaultForUser(t.Context(), user.Id)
+	if err != nil {
+		t.Fatalf("GetDefaultForUser() error = %v", err)
+	}
+	if fetched.ID != second.ID {
+		t.Errorf("GetDefaultForUser() ID = %d, want %d", fetched.ID, second.ID)
+	}
+
+	first, err = model.Get(t.Context(), first.ID, user.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if first.IsDefault {
+		t.Error("previous default address should no longer be the default")
+	}
+
+	first.IsDefault = true
+	if err := model.Update(t.Context(), first); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	fetched, err = model.GetDefaultForUser(t.Context(), user.Id)
+	if err != nil {
+		t.Fatalf("GetDefaultForUser() after Update() error = %v", err)
+	}
+	if fetched.ID != first.ID {
+		t.Errorf("GetDefaultForUser() after Update() ID = %d, want %d", fetched.ID, first.ID)
+	}
+}
+
+func TestAddressModel_Insert_ConcurrentDefaults(t *testing.T) {
+	model := AddressModel{DB: testDB}
+	user := insertTestUser(t, UserModel{DB: testDB})
+
+	const inserts = 5
+
+	errs := make(chan error, inserts)
+	for range inserts {
+		go func() {
+			errs <- model.Insert(t.Context(), &Address{UserID: user.Id, Label: "Home", Street: "123 Market Street", City: "Springfield", IsDefault: true})
+		}()
+	}
+
+	for range inserts {
+		if err := <-errs; err != nil {
+			t.Errorf("Insert() concurrent default error = %v", err)
+		}
+	}
+
+	addresses, err := model.GetAllForUser(t.Context(), user.Id)
+	if err != nil {
+		t.Fatalf("GetAllForUser() error = %v", err)
+	}
+
+	defaults := 0
+	for _, address := range addresses {
+		if address.IsDefault {
+			defaults++
+		}
+	}
+	if len(addresses) != inserts || defaults != 1 {
+		t.Errorf("GetAllForUser() = %d addresses with %d defaults, want %d with 1", len(addresses), defaults, inserts)
+	}
+}
+
+func TestAddressModel_GetDefaultForUser_NotFound(t *testing.T) {
+	model := AddressModel{DB: testDB}
+	user := insertTestUser(t, UserModel{DB: testDB})
+	insertTestAddress(t, model, user.Id, false)
+
+	_, err := model.GetDefaultForUser(t.Context(), user.Id)
+	if err != ErrRecordNotFound {
+		t.Errorf("GetDefaultForUser() error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestAddressModel_GetAllForUser(t *testing.T) {
+	model := AddressModel{DB: testDB}
+	user := insertTestUser(t, UserModel{DB: testDB})
+
+	addresses, err := model.GetAllForUser(t.Context(), user.Id)
+	if err != nil {
+		t.Fatalf("GetAllForUser() error = %v", err)
+	}
+	if addresses == nil || len(addresses) != 0 {
+		t.Errorf("GetAllForUser() = %v, want empty slice", addresses)
+	}
+
+	insertTestAddress(t, model, user.Id, false)
+	insertTestAddress(t, model, user.Id, true)
+
+	addresses, err = model.GetAllForUser(t.Context(), user.Id)
+	if err != nil {
+		t.Fatalf("GetAllForUser() error = %v", err)
+	}
+	if len(addresses) != 2 {
+		t.Errorf("GetAllForUser() returned %d addresses, want 2", len(addresses))
+	}
+}
+
+func TestAddressModel_Update_EditConflict(t *testing.T) {
+	model := AddressModel{DB: testDB}
+	user := insertTestUser(t, UserModel{DB: testDB})
+	address := insertTestAddress(t, model, user.Id, false)
+
+	stale := *address
+
+	address.Label = "Work"
+	if err := model.Update(t.Context(), address); err != nil {
+		t.Fatalf("Update() current address error = %v", err)
+	}
+
+	stale.Label = "Stale"
+	err := model.Update(t.Context(), &stale)
+	if err != ErrEditConflict {
+		t.Errorf("Update() stale address error = %v, want ErrEditConflict", err)
+	}
+}
+
+func TestAddressModel_Delete(t *testing.T) {
+	model := AddressModel{DB: testDB}
+	user := insertTestUser(t, UserModel{DB: testDB})
+	address := insertTestAddress(t, model, user.Id, false)
+
+	if err := model.Delete(t.Context(), address.ID, user.Id); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	_, err := model.Get(t.Context(), address.ID, user.Id)
+	if err != ErrRecordNotFound {
+		t.Errorf("Get() after Delete() error = %v, want ErrRecordNotFound", err)
+	}
+
+	err = model.Delete(t.Context(), address.ID, user.Id)
+	if err != ErrRecordNotFound {
+		t.Errorf("Delete() twice error = %v, want ErrRecordNotFound", err)
+	}
+}
@@ -0,0 +1,299 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+var validIntervals = []string{IntervalDay, IntervalWeek, IntervalMonth}
+
+// the longest date range a single analytics request may cover
+const maxAnalyticsRange = 366 * 24 * time.Hour
+
+type AnalyticsFilters struct {
+	From      time.Time
+	To        time.Time
+	Interval  string
+	TopDishes int
+}
+
+func ValidateAnalyticsFilters(v *validator.Validator, f AnalyticsFilters) {
+	v.Check(validator.PermittedValue(f.Interval, validIntervals...), "interval", "must be one of day, week or month")
+	v.Check(f.To.After(f.From), "to", "must be after from")
+	v.Check(f.To.Sub(f.From) <= maxAnalyticsRange, "to", "range must not exceed 366 days")
+	v.Check(f.TopDishes > 0, "top_dishes", "must be greater than zero")
+	v.Check(f.TopDishes <= 50, "top_dishes", "must be a maximum of 50")
+}
+
+// SalesBucket holds the orders placed during one interval. Revenue only counts
+// completed orders
+type SalesBucket struct {
+	PeriodStart     time.Time `json:"period_start"`
+	Orders          int       `json:"orders"`
+	CompletedOrders int       `json:"completed_orders"`
+	Revenue         int64     `json:"revenue"`
+}
+
+// DishSales aggregates the order item snapshots of a dish. DishID is zero for
+// dishes that have since been deleted
+type DishSales struct {
+	DishID   int64  `json:"dish_id,omitempty"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+	Revenue  int64  `json:"revenue"`
+}
+
+type RestaurantAnalytics struct {
+	From                 time.Time          `json:"from"`
+	To                   time.Time          `json:"to"`
+	Interval             string             `json:"interval"`
+	Orders               int                `json:"orders"`
+	CompletedOrders      int                `json:"completed_orders"`
+	CancelledOrders      int                `json:"cancelled_orders"`
+	Revenue              int64              `json:"revenue"`
+	AverageOrderValue    int64              `json:"average_order_value"`
+	CancellationRate     float64            `json:"cancellation_rate"`
+	AverageStatusSeconds map[string]float64 `json:"average_status_seconds"`
+	Sales                []SalesBucket      `json:"sales"`
+	TopDishesByQuantity  []DishSales        `json:"top_dishes_by_quantity"`
+	TopDishesByRevenue   []DishSales        `json:"top_dishes_by_revenue"`
+}
+
+// completedStatuses lists the final statuses of every fulfilment type other than cancelled
+func completedStatuses() []string {
+	var statuses []string
+
+	for _, status := range validStatuses {
+		for _, transitions := range validTransitions {
+			next, ok := transitions[status]
+			if ok && len(next) == 0 && status != "cancelled" {
+				statuses = append(statuses, status)
+				break
+			}
+		}
+	}
+
+	return statuses
+}
+
+// analyticsTimeout bounds the aggregate queries of a report, which scan every
+// order in the requested range, unless QueryTimeout is longer
+const analyticsTimeout = 10 * time.Second
+
+type AnalyticsModel struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+// GetForRestaurant aggregates the orders a restaurant received in [From, To).
+// All queries run in one read-only snapshot so the figures agree with each other
+func (m AnalyticsModel) GetForRestaurant(ctx context.Context, restaurantID int64, f AnalyticsFilters) (*RestaurantAnalytics, error) {
+	ctx, span := startSpan(ctx, "AnalyticsModel.GetForRestaurant")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, max(m.QueryTimeout, analyticsTimeout))
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	analytics := &RestaurantAnalytics{
+		From:     f.From,
+		To:       f.To,
+		Interval: f.Interval,
+	}
+
+	completed := pq.Array(completedStatuses())
+
+	query := `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE status = ANY($4)),
+		       COUNT(*) FILTER (WHERE status = 'cancelled'),
+		       COALESCE(SUM(total) FILTER (WHERE status = ANY($4)), 0)
+		FROM orders
+		WHERE restaurant_id = $1 AND created_at >= $2 AND created_at < $3`
+
+	err = tx.QueryRowContext(ctx, query, restaurantID, f.From, f.To, completed).Scan(
+		&analytics.Orders,
+		&analytics.CompletedOrders,
+		&analytics.CancelledOrders,
+		&analytics.Revenue,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if analytics.CompletedOrders > 0 {
+		analytics.AverageOrderValue = analytics.Revenue / int64(analytics.CompletedOrders)
+	}
+	if analytics.Orders > 0 {
+		analytics.CancellationRate = float64(analytics.CancelledOrders) / float64(analytics.Orders)
+	}
+
+	analytics.Sales, err = m.sales(ctx, tx, restaurantID, f, completed)
+	if err != nil {
+		return nil, err
+	}
+
+	analytics.TopDishesByQuantity, err = m.topDishes(ctx, tx, restaurantID, f, completed, "quantity")
+	if err != nil {
+		return nil, err
+	}
+
+	analytics.TopDishesByRevenue, err = m.topDishes(ctx, tx, restaurantID, f, completed, "revenue")
+	if err != nil {
+		return nil, err
+	}
+
+	analytics.AverageStatusSeconds, err = m.statusDurations(ctx, tx, restaurantID, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return analytics, tx.Commit()
+}
+
+// sales buckets orders by interval in UTC. Intervals without orders are
+// included with zero values so the series has no gaps
+func (m AnalyticsModel) sales(ctx context.Context, tx *sql.Tx, restaurantID int64, f AnalyticsFilters, completed any) ([]SalesBucket, error) {
+	query := `
+		SELECT b.period_start,
+		       COUNT(o.id),
+		       COUNT(o.id) FILTER (WHERE o.status = ANY($5)),
+		       COALESCE(SUM(o.total) FILTER (WHERE o.status = ANY($5)), 0)
+		FROM generate_series(
+		         date_trunc($4, $2::timestamptz, 'UTC'),
+		         $3::timestamptz - INTERVAL '1 microsecond',
+		         ('1 ' || $4)::interval
+		     ) AS b(period_start)
+		LEFT JOIN orders o
+		       ON o.restaurant_id = $1
+		      AND o.created_at >= $2 AND o.created_at < $3
+		      AND date_trunc($4, o.created_at, 'UTC') = b.period_start
+		GROUP BY b.period_start
+		ORDER BY b.period_start`
+
+	rows, err := tx.QueryContext(ctx, query, restaurantID, f.From, f.To, f.Interval, completed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []SalesBucket{}
+
+	for rows.Next() {
+		var bucket SalesBucket
+
+		err := rows.Scan(&bucket.PeriodStart, &bucket.Orders, &bucket.CompletedOrders, &bucket.Revenue)
+		if err != nil {
+			return nil, err
+		}
+
+		bucket.PeriodStart = bucket.PeriodStart.UTC()
+		buckets = append(buckets, bucket)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buckets, nil
+}
+
+// topDishes ranks the dishes of completed orders by quantity sold or by revenue,
+// using the name and price snapshotted into each order item
+func (m AnalyticsModel) topDishes(ctx context.Context, tx *sql.Tx, restaurantID int64, f AnalyticsFilters, completed any, by string) ([]DishSales, error) {
+	order := "quantity DESC, revenue DESC"
+	if by == "revenue" {
+		order = "revenue DESC, quantity DESC"
+	}
+
+	query := `
+		SELECT COALESCE(oi.dish_id, 0), oi.dish_name, SUM(oi.quantity) AS quantity, SUM(oi.subtotal) AS revenue
+		FROM order_items oi
+		INNER JOIN orders o ON o.id = oi.order_id
+		WHERE o.restaurant_id = $1 AND o.created_at >= $2 AND o.created_at < $3 AND o.status = ANY($4)
+		GROUP BY oi.dish_id, oi.dish_name
+		ORDER BY ` + order + `, oi.dish_name ASC
+		LIMIT $5`
+
+	rows, err := tx.QueryContext(ctx, query, restaurantID, f.From, f.To, completed, f.TopDishes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dishes := []DishSales{}
+
+	for rows.Next() {
+		var dish DishSales
+
+		err := rows.Scan(&dish.DishID, &dish.Name, &dish.Quantity, &dish.Revenue)
+		if err != nil {
+			return nil, err
+		}
+
+		dishes = append(dishes, dish)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dishes, nil
+}
+
+// statusDurations averages how long orders stayed in each status before moving
+// on. The status an order is currently in has no end yet and is left out
+func (m AnalyticsModel) statusDurations(ctx context.Context, tx *sql.Tx, restaurantID int64, f AnalyticsFilters) (map[string]float64, error) {
+	query := `
+		SELECT status, AVG(EXTRACT(EPOCH FROM next_changed_at - changed_at))::float8
+		FROM (
+			SELECT h.status, h.changed_at,
+			       LEAD(h.changed_at) OVER (PARTITION BY h.order_id ORDER BY h.changed_at, h.id) AS next_changed_at
+			FROM order_status_history h
+			INNER JOIN orders o ON o.id = h.order_id
+			WHERE o.restaurant_id = $1 AND o.created_at >= $2 AND o.created_at < $3
+		) t
+		WHERE next_changed_at IS NOT NULL
+		GROUP BY status`
+
+	rows, err := tx.QueryContext(ctx, query, restaurantID, f.From, f.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	durations := map[string]float64{}
+
+	for rows.Next() {
+		var status string
+		var seconds float64
+
+		err := rows.Scan(&status, &seconds)
+		if err != nil {
+			return nil, err
+		}
+
+		durations[status] = seconds
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return durations, nil
+}
@@ -0,0 +1,202 @@
+package data
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+// ---- helpers ----
+
+func newTestAnalyticsFilters() AnalyticsFilters {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	return AnalyticsFilters{
+		From:      today.AddDate(0, 0, -6),
+		To:        today.AddDate(0, 0, 1),
+		Interval:  IntervalDay,
+		TopDishes: 10,
+	}
+}
+
+// inserts an order with one item line and moves it to the given status
+func insertAnalyticsOrder(t *testing.T, restaurantID int64, dish *Dish, quantity int, status string) *Order {
+	t.Helper()
+
+	user := insertTestUser(t, UserModel{DB: testDB})
+	order := insertTestOrder(t, OrderModel{DB: testDB}, user.Id, restaurantID)
+
+	item, err := (OrderItemModel{DB: testDB}).InsertFromDish(t.Context(), order.ID, dish, quantity)
+	if err != nil {
+		t.Fatalf("failed to insert test order item: %v", err)
+	}
+
+	order.Total = item.Subtotal
+	order.Status = status
+	if err := (OrderModel{DB: testDB}).Update(t.Context(), order); err != nil {
+		t.Fatalf("failed to update test order: %v", err)
+	}
+
+	return order
+}
+
+func TestCompletedStatuses(t *testing.T) {
+	got := completedStatuses()
+
+	for _, want := range []string{"delivered", "picked_up", "served"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("completedStatuses() = %v, missing %q", got, want)
+		}
+	}
+	if slices.Contains(got, "cancelled") {
+		t.Errorf("completedStatuses() = %v, should not contain cancelled", got)
+	}
+}
+
+func TestValidateAnalyticsFilters(t *testing.T) {
+	valid := newTestAnalyticsFilters()
+
+	tests := []struct {
+		name      string
+		modify    func(f *AnalyticsFilters)
+		wantValid bool
+		wantKey   string
+	}{
+		{name: "valid", modify: func(f *AnalyticsFilters) {}, wantValid: true},
+		{name: "unknown interval", modify: func(f *AnalyticsFilters) { f.Interval = "year" }, wantKey: "interval"},
+		{name: "to before from", modify: func(f *AnalyticsFilters) { f.To = f.From.AddDate(0, 0, -1) }, wantKey: "to"},
+		{name: "range too long", modify: func(f *AnalyticsFilters) { f.From = f.To.AddDate(-2, 0, 0) }, wantKey: "to"},
+		{name: "zero top dishes", modify: func(f *AnalyticsFilters) { f.TopDishes = 0 }, wantKey: "top_dishes"},
+		{name: "too many top dishes", modify: func(f *AnalyticsFilters) { f.TopDishes = 51 }, wantKey: "top_dishes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := valid
+			tt.modify(&f)
+
+			v := validator.New()
+			ValidateAnalyticsFilters(v, f)
+
+			if v.Valid() != tt.wantValid {
+				t.Errorf("Valid() = %v, want %v (errors: %v)", v.Valid(), tt.wantValid, v.Errors)
+			}
+			if tt.wantKey != "" {
+				if _, ok := v.Errors[tt.wantKey]; !ok {
+					t.Errorf("expected error for key %q, got %v", tt.wantKey, v.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyticsModel_GetForRestaurant(t *testing.T) {
+	model := AnalyticsModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	dishModel := DishModel{DB: testDB}
+	burger := insertTestDish(t, dishModel, restaurantID)
+	fries := insertTestDish(t, dishModel, restaurantID)
+
+	insertAnalyticsOrder(t, restaurantID, burger, 1, "delivered")
+	insertAnalyticsOrder(t, restaurantID, fries, 5, "delivered")
+	insertAnalyticsOrder(t, restaurantID, burger, 2, "cancelled")
+	insertAnalyticsOrder(t, restaurantID, burger, 1, "confirmed")
+
+	filters := newTestAnalyticsFilters()
+
+	analytics, err := model.GetForRestaurant(t.Context(), restaurantID, filters)
+	if err != nil {
+		t.Fatalf("GetForRestaurant() error = %v", err)
+	}
+
+	if analytics.Orders != 4 {
+		t.Errorf("Orders = %d, want 4", analytics.Orders)
+	}
+	if analytics.CompletedOrders != 2 {
+		t.Errorf("CompletedOrders = %d, want 2", analytics.CompletedOrders)
+	}
+	if analytics.CancelledOrders != 1 {
+		t.Errorf("CancelledOrders = %d, want 1", analytics.CancelledOrders)
+	}
+	if analytics.CancellationRate != 0.25 {
+		t.Errorf("CancellationRate = %v, want 0.25", analytics.CancellationRate)
+	}
+
+	wantRevenue := burger.Price + fries.Price*5
+	if analytics.Revenue != wantRevenue {
+		t.Errorf("Revenue = %d, want %d", analytics.Revenue, wantRevenue)
+	}
+	if analytics.AverageOrderValue != wantRevenue/2 {
+		t.Errorf("AverageOrderValue = %d, want %d", analytics.AverageOrderValue, wantRevenue/2)
+	}
+
+	if len(analytics.Sales) != 7 {
+		t.Fatalf("Sales has %d buckets, want 7", len(analytics.Sales))
+	}
+	last := analytics.Sales[len(analytics.Sales)-1]
+	if last.Orders != 4 || last.Revenue != wantRevenue {
+		t.Errorf("today's bucket = %+v, want 4 orders and revenue %d", last, wantRevenue)
+	}
+	if analytics.Sales[0].Orders != 0 {
+		t.Errorf("first bucket Orders = %d, want 0", analytics.Sales[0].Orders)
+	}
+
+	if len(analytics.TopDishesByQuantity) != 2 {
+		t.Fatalf("TopDishesByQuantity has %d dishes, want 2", len(analytics.TopDishesByQuantity))
+	}
+	if top := analytics.TopDishesByQuantity[0]; top.DishID != fries.ID || top.Quantity != 5 {
+		t.Errorf("TopDishesByQuantity[0] = %+v, want fries with quantity 5", top)
+	}
+
+	// confirmed, and then delivered or cancelled, all spent time as pending
+	if _, ok := analytics.AverageStatusSeconds["pending"]; !ok {
+		t.Errorf("AverageStatusSeconds = %v, missing pending", analytics.AverageStatusSeconds)
+	}
+}
+
+func TestAnalyticsModel_GetForRestaurant_OtherRestaurant(t *testing.T) {
+	model := AnalyticsModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	otherID := seedRestaurant(t)
+	dish := insertTestDish(t, DishModel{DB: testDB}, otherID)
+
+	insertAnalyticsOrder(t, otherID, dish, 1, "delivered")
+
+	analytics, err := model.GetForRestaurant(t.Context(), restaurantID, newTestAnalyticsFilters())
+	if err != nil {
+		t.Fatalf("GetForRestaurant() error = %v", err)
+	}
+
+	if analytics.Orders != 0 || analytics.Revenue != 0 {
+		t.Errorf("GetForRestaurant() = %d orders, revenue %d, want none", analytics.Orders, analytics.Revenue)
+	}
+	if len(analytics.TopDishesByRevenue) != 0 {
+		t.Errorf("TopDishesByRevenue = %v, want empty", analytics.TopDishesByRevenue)
+	}
+}
+
+func TestAnalyticsModel_GetForRestaurant_WeeklyBuckets(t *testing.T) {
+	model := AnalyticsModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+
+	filters := newTestAnalyticsFilters()
+	filters.From = filters.To.AddDate(0, 0, -28)
+	filters.Interval = IntervalWeek
+
+	analytics, err := model.GetForRestaurant(t.Context(), restaurantID, filters)
+	if err != nil {
+		t.Fatalf("GetForRestaurant() error = %v", err)
+	}
+
+	// 28 days starting mid-week touch four or five weeks
+	if n := len(analytics.Sales); n < 4 || n > 5 {
+		t.Errorf("Sales has %d weekly buckets, want 4 or 5", n)
+	}
+	for _, bucket := range analytics.Sales {
+		if bucket.PeriodStart.Weekday() != time.Monday {
+			t.Errorf("bucket starts on %s, want Monday", bucket.PeriodStart.Weekday())
+		}
+	}
+}
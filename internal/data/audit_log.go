@@ -0,0 +1,276 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// actions recorded in the audit log, named after their target
+const (
+	AuditRestaurantCreate   = "restaurant.create"
+	AuditRestaurantUpdate   = "restaurant.update"
+	AuditRestaurantDelete   = "restaurant.delete"
+	AuditDishCreate         = "dish.create"
+	AuditDishUpdate         = "dish.update"
+	AuditDishDelete         = "dish.delete"
+	AuditMenuImport         = "menu.import"
+	AuditGalleryImageAdd    = "gallery_image.add"
+	AuditGalleryImageUpdate = "gallery_image.update"
+	AuditGalleryImageDelete = "gallery_image.delete"
+	AuditGalleryReorder     = "gallery.reorder"
+	AuditOrderUpdateStatus  = "order.update_status"
+	AuditStaffAdd           = "staff.add"
+	AuditStaffRemove        = "staff.remove"
+	AuditDeliveryZoneCreate = "delivery_zone.create"
+	AuditDeliveryZoneUpdate = "delivery_zone.update"
+	AuditDeliveryZoneDelete = "delivery_zone.delete"
+	AuditReviewReply        = "review.reply"
+	AuditReviewModerate     = "review.moderate"
+	AuditUserPromoteAdmin   = "user.promote_admin"
+	AuditUserPromoteCourier = "user.promote_courier"
+	AuditUserUpdate         = "user.update"
+	AuditUserSuspend        = "user.suspend"
+	AuditUserBan            = "user.ban"
+	AuditUserReinstate      = "user.reinstate"
+	AuditPermissionsGrant   = "permissions.grant"
+	AuditPermissionsRevoke  = "permissions.revoke"
+)
+
+// AuditActions lists every action, for validating filters
+var AuditActions = []string{
+	AuditRestaurantCreate,
+	AuditRestaurantUpdate,
+	AuditRestaurantDelete,
+	AuditDishCreate,
+	AuditDishUpdate,
+	AuditDishDelete,
+	AuditMenuImport,
+	AuditGalleryImageAdd,
+	AuditGalleryImageUpdate,
+	AuditGalleryImageDelete,
+	AuditGalleryReorder,
+	AuditOrderUpdateStatus,
+	AuditStaffAdd,
+	AuditStaffRemove,
+	AuditDeliveryZoneCreate,
+	AuditDeliveryZoneUpdate,
+	AuditDeliveryZoneDelete,
+	AuditReviewReply,
+	AuditReviewModerate,
+	AuditUserPromoteAdmin,
+	AuditUserPromoteCourier,
+	AuditUserUpdate,
+	AuditUserSuspend,
+	AuditUserBan,
+	AuditUserReinstate,
+	AuditPermissionsGrant,
+	AuditPermissionsRevoke,
+}
+
+// AuditEntry records a privileged action. Before and After hold only the fields
+// of the target that the action changed: Before is empty for creations and
+// After for deletions. ActorID is zero once the actor's account is deleted
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	ActorID    int64           `json:"actor_id,omitempty"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   int64           `json:"target_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	IP         string          `json:"ip"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// SetChanges stores the fields that differ between before and after, which
+// are compared by their JSON encoding. Either can be nil, and then every field
+// of the other one is stored
+func (e *AuditEntry) SetChanges(before, after any) error {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return err
+	}
+
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return err
+	}
+
+	changedBefore := map[string]json.RawMessage{}
+	changedAfter := map[string]json.RawMessage{}
+
+	for key, value := range beforeFields {
+		if !bytes.Equal(value, afterFields[key]) {
+			changedBefore[key] = value
+		}
+	}
+
+	for key, value := range afterFields {
+		if !bytes.Equal(value, beforeFields[key]) {
+			changedAfter[key] = value
+		}
+	}
+
+	e.Before, e.After = nil, nil
+
+	if before != nil {
+		e.Before, err = json.Marshal(changedBefore)
+		if err != nil {
+			return err
+		}
+	}
+
+	if after != nil {
+		e.After, err = json.Marshal(changedAfter)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// auditFields encodes v, which must encode to a JSON object, into its fields
+func auditFields(v any) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+
+	err = json.Unmarshal(js, &fields)
+	if err != nil {
+		return nil, fmt.Errorf("audit target must encode to a JSON object: %w", err)
+	}
+
+	return fields, nil
+}
+
+// AuditLogFilters restricts the audit log to one actor or action, and to
+// entries created within [From, To). Zero values don't filter
+type AuditLogFilters struct {
+	ActorID int64
+	Action  string
+	From    time.Time
+	To      time.Time
+}
+
+type AuditLogModel struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+func (m AuditLogModel) Insert(ctx context.Context, entry *AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (actor_id, action, target_type, target_id, before, after, ip)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	args := []any{
+		entry.ActorID,
+		entry.Action,
+		entry.TargetType,
+		entry.TargetID,
+		nullJSON(entry.Before),
+		nullJSON(entry.After),
+		entry.IP,
+	}
+
+	ctx, span := startSpan(ctx, "AuditLogModel.Insert")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+func (m AuditLogModel) GetAll(ctx context.Context, auditFilters AuditLogFilters, filters Filters) ([]*AuditEntry, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), id, actor_id, action, target_type, target_id, before, after, ip, created_at
+		FROM audit_log
+		WHERE (actor_id = $1 OR $1 = 0)
+		AND (action = $2 OR $2 = '')
+		AND ($3::timestamptz IS NULL OR created_at >= $3)
+		AND ($4::timestamptz IS NULL OR created_at < $4)
+		ORDER BY %s %s, id DESC
+		LIMIT $5 OFFSET $6`, filters.sortColumn(), filters.sortDirection())
+
+	args := []any{
+		auditFilters.ActorID,
+		auditFilters.Action,
+		sql.NullTime{Time: auditFilters.From, Valid: !auditFilters.From.IsZero()},
+		sql.NullTime{Time: auditFilters.To, Valid: !auditFilters.To.IsZero()},
+		filters.limit(),
+		filters.offset(),
+	}
+
+	ctx, span := startSpan(ctx, "AuditLogModel.GetAll")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*AuditEntry{}
+
+	for rows.Next() {
+		var entry AuditEntry
+		var actorID sql.NullInt64
+		var before, after []byte
+
+		err := rows.Scan(
+			&totalRecords,
+			&entry.ID,
+			&actorID,
+			&entry.Action,
+			&entry.TargetType,
+			&entry.TargetID,
+			&before,
+			&after,
+			&entry.IP,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entry.ActorID = actorID.Int64
+		entry.Before = before
+		entry.After = after
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
+
+// nullJSON stores an empty document as NULL
+func nullJSON(js json.RawMessage) any {
+	if len(js) == 0 {
+		return nil
+	}
+
+	return []byte(js)
+}
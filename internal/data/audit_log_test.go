@@ -0,0 +1,152 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func newTestAuditFilters() Filters {
+	return Filters{
+		Page:         1,
+		PageSize:     10,
+		Sort:         "-created_at",
+		SortSafelist: []string{"id", "-id", "created_at", "-created_at"},
+	}
+}
+
+func TestAuditEntry_SetChanges(t *testing.T) {
+	before := &Restaurant{ID: 1, Name: "Old Name", City: "Rosario", Country: "Argentina"}
+	after := &Restaurant{ID: 1, Name: "New Name", City: "Rosario", Country: "Argentina", Photo: "front.jpg"}
+
+	tests := []struct {
+		name       string
+		before     any
+		after      any
+		wantBefore string
+		wantAfter  string
+	}{
+		{"update", before, after, `{"name":"Old Name"}`, `{"name":"New Name","photo":"front.jpg"}`},
+		{"create", nil, map[string]any{"role": "owner"}, "", `{"role":"owner"}`},
+		{"delete", map[string]any{"role": "staff"}, nil, `{"role":"staff"}`, ""},
+		{"no changes", before, before, `{}`, `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry AuditEntry
+
+			if err := entry.SetChanges(tt.before, tt.after); err != nil {
+				t.Fatalf("SetChanges() error = %v", err)
+			}
+
+			if string(entry.Before) != tt.wantBefore {
+				t.Errorf("Before = %s, want %s", entry.Before, tt.wantBefore)
+			}
+			if string(entry.After) != tt.wantAfter {
+				t.Errorf("After = %s, want %s", entry.After, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestAuditEntry_SetChanges_NotAnObject(t *testing.T) {
+	var entry AuditEntry
+
+	if err := entry.SetChanges(nil, "admin"); err == nil {
+		t.Error("SetChanges() with a string target should fail")
+	}
+}
+
+func TestAuditLogModel_InsertAndGetAll(t *testing.T) {
+	actor := insertTestUser(t, UserModel{DB: testDB})
+	other := insertTestUser(t, UserModel{DB: testDB})
+	model := AuditLogModel{DB: testDB}
+
+	t.Cleanup(func() {
+		testDB.Exec(`DELETE FROM audit_log WHERE actor_id = ANY(ARRAY[$1, $2]::bigint[])`, actor.Id, other.Id)
+	})
+
+	insert := func(actorID int64, action string) *AuditEntry {
+		t.Helper()
+
+		entry := &AuditEntry{ActorID: actorID, Action: action, TargetType: "restaurant", TargetID: 7, IP: "192.0.2.1"}
+		if err := entry.SetChanges(map[string]any{"name": "Old"}, map[string]any{"name": "New"}); err != nil {
+			t.Fatalf("SetChanges() error = %v", err)
+		}
+		if err := model.Insert(t.Context(), entry); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+		return entry
+	}
+
+	update := insert(actor.Id, AuditRestaurantUpdate)
+	insert(actor.Id, AuditRestaurantDelete)
+	insert(other.Id, AuditRestaurantUpdate)
+
+	entries, metadata, err := model.GetAll(t.Context(), AuditLogFilters{ActorID: actor.Id}, newTestAuditFilters())
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if metadata.TotalRecords != 2 {
+		t.Errorf("GetAll() by actor returned %d entries, want 2", metadata.TotalRecords)
+	}
+
+	entries, _, err = model.GetAll(t.Context(), AuditLogFilters{ActorID: actor.Id, Action: AuditRestaurantUpdate}, newTestAuditFilters())
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != update.ID {
+		t.Fatalf("GetAll() by actor and action = %d entries, want only entry %d", len(entries), update.ID)
+	}
+
+	var after map[string]string
+	if err := json.Unmarshal(entries[0].After, &after); err != nil || after["name"] != "New" {
+		t.Errorf("After = %s, want the new name", entries[0].After)
+	}
+	if entries[0].IP != "192.0.2.1" {
+		t.Errorf("IP = %q, want 192.0.2.1", entries[0].IP)
+	}
+
+	tomorrow := time.Now().Add(24 * time.Hour)
+	_, metadata, err = model.GetAll(t.Context(), AuditLogFilters{ActorID: actor.Id, From: tomorrow}, newTestAuditFilters())
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if metadata.TotalRecords != 0 {
+		t.Errorf("GetAll() from tomorrow returned %d entries, want 0", metadata.TotalRecords)
+	}
+}
+
+func TestAuditLogModel_ActorDeleted(t *testing.T) {
+	userModel := UserModel{DB: testDB}
+	actor := insertTestUser(t, userModel)
+	model := AuditLogModel{DB: testDB}
+
+	entry := &AuditEntry{ActorID: actor.Id, Action: AuditStaffAdd, TargetType: "user", TargetID: actor.Id}
+	if err := model.Insert(t.Context(), entry); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	t.Cleanup(func() {
+		testDB.Exec(`DELETE FROM audit_log WHERE id = $1`, entry.ID)
+	})
+
+	if _, err := testDB.Exec(`DELETE FROM users WHERE id = $1`, actor.Id); err != nil {
+		t.Fatalf("failed to delete actor: %v", err)
+	}
+
+	entries, _, err := model.GetAll(t.Context(), AuditLogFilters{Action: AuditStaffAdd, From: entry.CreatedAt}, newTestAuditFilters())
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+
+	for _, e := range entries {
+		if e.ID == entry.ID {
+			if e.ActorID != 0 {
+				t.Errorf("ActorID = %d after the actor was deleted, want 0", e.ActorID)
+			}
+			return
+		}
+	}
+	t.Error("entry was deleted along with its actor")
+}
@@ -0,0 +1,149 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+var ErrPingTooSoon = errors.New("location ping too soon")
+
+type CourierLocation struct {
+	ID         int64     `json:"-"`
+	OrderID    int64     `json:"order_id"`
+	CourierID  int64     `json:"courier_id"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+func ValidateCourierLocation(v *validator.Validator, location *CourierLocation) {
+	v.Check(location.Latitude >= -90 && location.Latitude <= 90, "latitude", "must be between -90 and 90")
+	v.Check(location.Longitude >= -180 && location.Longitude <= 180, "longitude", "must be between -180 and 180")
+}
+
+type CourierLocationModel struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+// Insert records a ping unless another ping was stored for the same order less
+// than minInterval ago, in which case it returns ErrPingTooSoon. The order row
+// is locked while checking, so concurrent pings for the same order, from any
+// API replica, are checked one after the other
+func (m CourierLocationModel) Insert(ctx context.Context, location *CourierLocation, minInterval time.Duration) error {
+	ctx, span := startSpan(ctx, "CourierLocationModel.Insert")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var orderID int64
+
+	err = tx.QueryRowContext(ctx, `SELECT id FROM orders WHERE id = $1 FOR UPDATE`, location.OrderID).Scan(&orderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO courier_locations (order_id, courier_id, latitude, longitude)
+		SELECT $1, $2, $3, $4
+		WHERE NOT EXISTS (
+			SELECT 1 FROM courier_locations
+			WHERE order_id = $1 AND recorded_at > NOW() - make_interval(secs => $5)
+		)
+		RETURNING id, recorded_at`
+
+	args := []any{location.OrderID, location.CourierID, location.Latitude, location.Longitude, minInterval.Seconds()}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&location.ID, &location.RecordedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrPingTooSoon
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m CourierLocationModel) GetLatestForOrder(ctx context.Context, orderID int64) (*CourierLocation, error) {
+	if orderID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, order_id, courier_id, latitude, longitude, recorded_at
+		FROM courier_locations
+		WHERE order_id = $1
+		ORDER BY recorded_at DESC
+		LIMIT 1`
+
+	var location CourierLocation
+
+	ctx, span := startSpan(ctx, "CourierLocationModel.GetLatestForOrder")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, orderID).Scan(
+		&location.ID,
+		&location.OrderID,
+		&location.CourierID,
+		&location.Latitude,
+		&location.Longitude,
+		&location.RecordedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &location, nil
+}
+
+// pruneTimeout bounds DeleteOlderThan. One prune deletes every ping recorded
+// since the previous run, far more rows than QueryTimeout is sized for, so it
+// gets this limit unless the configured QueryTimeout is longer
+const pruneTimeout = 30 * time.Second
+
+// DeleteOlderThan removes pings recorded before the cutoff and returns how many were deleted
+func (m CourierLocationModel) DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `
+		DELETE FROM courier_locations
+		WHERE recorded_at < $1`
+
+	ctx, span := startSpan(ctx, "CourierLocationModel.DeleteOlderThan")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, max(m.QueryTimeout, pruneTimeout))
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
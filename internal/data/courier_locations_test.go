@@ -0,0 +1,136 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func insertOutForDeliveryOrder(t *testing.T, courierID int64) *Order {
+	t.Helper()
+
+	orderModel := OrderModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	user := insertTestUser(t, UserModel{DB: testDB})
+	order := insertReadyDeliveryOrder(t, orderModel, user.Id, restaurantID)
+
+	claimed, err := orderModel.Claim(t.Context(), order.ID, courierID)
+	if err != nil {
+		t.Fatalf("failed to claim test order: %v", err)
+	}
+
+	claimed.Status = "out_for_delivery"
+	if err := orderModel.Update(t.Context(), claimed); err != nil {
+		t.Fatalf("failed to mark test order out for delivery: %v", err)
+	}
+
+	return claimed
+}
+
+func TestCourierLocationModel_InsertAndGetLatest(t *testing.T) {
+	model := CourierLocationModel{DB: testDB}
+	courier := insertTestCourier(t, UserModel{DB: testDB})
+	order := insertOutForDeliveryOrder(t, courier.Id)
+
+	first := &CourierLocation{OrderID: order.ID, CourierID: courier.Id, Latitude: -34.60, Longitude: -58.38}
+	if err := model.Insert(t.Context(), first, 0); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if first.ID == 0 {
+		t.Error("Insert() did not set location.ID")
+	}
+	if first.RecordedAt.IsZero() {
+		t.Error("Insert() did not set RecordedAt")
+	}
+
+	second := &CourierLocation{OrderID: order.ID, CourierID: courier.Id, Latitude: -34.61, Longitude: -58.39}
+	if err := model.Insert(t.Context(), second, 0); err != nil {
+		t.Fatalf("Insert() second ping error = %v", err)
+	}
+
+	latest, err := model.GetLatestForOrder(t.Context(), order.ID)
+	if err != nil {
+		t.Fatalf("GetLatestForOrder() error = %v", err)
+	}
+	if latest.ID != second.ID {
+		t.Errorf("GetLatestForOrder() ID = %d, want %d", latest.ID, second.ID)
+	}
+	if latest.Latitude != second.Latitude {
+		t.Errorf("GetLatestForOrder() Latitude = %f, want %f", latest.Latitude, second.Latitude)
+	}
+}
+
+func TestCourierLocationModel_Insert_TooSoon(t *testing.T) {
+	model := CourierLocationModel{DB: testDB}
+	courier := insertTestCourier(t, UserModel{DB: testDB})
+	order := insertOutForDeliveryOrder(t, courier.Id)
+
+	ping := &CourierLocation{OrderID: order.ID, CourierID: courier.Id, Latitude: -34.60, Longitude: -58.38}
+	if err := model.Insert(t.Context(), ping, time.Minute); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	err := model.Insert(t.Context(), &CourierLocation{OrderID: order.ID, CourierID: courier.Id, Latitude: -34.61, Longitude: -58.39}, time.Minute)
+	if err != ErrPingTooSoon {
+		t.Errorf("Insert() within interval error = %v, want ErrPingTooSoon", err)
+	}
+}
+
+func TestCourierLocationModel_Insert_Concurrent(t *testing.T) {
+	model := CourierLocationModel{DB: testDB}
+	courier := insertTestCourier(t, UserModel{DB: testDB})
+	order := insertOutForDeliveryOrder(t, courier.Id)
+
+	const pings = 5
+
+	errs := make(chan error, pings)
+	for range pings {
+		go func() {
+			errs <- model.Insert(t.Context(), &CourierLocation{OrderID: order.ID, CourierID: courier.Id, Latitude: -34.60, Longitude: -58.38}, time.Minute)
+		}()
+	}
+
+	stored := 0
+	for range pings {
+		err := <-errs
+		switch err {
+		case nil:
+			stored++
+		case ErrPingTooSoon:
+		default:
+			t.Errorf("Insert() unexpected error = %v", err)
+		}
+	}
+
+	if stored != 1 {
+		t.Errorf("Insert() stored %d concurrent pings, want exactly 1", stored)
+	}
+}
+
+func TestCourierLocationModel_GetLatestForOrder_NotFound(t *testing.T) {
+	model := CourierLocationModel{DB: testDB}
+
+	_, err := model.GetLatestForOrder(t.Context(), 999999)
+	if err != ErrRecordNotFound {
+		t.Errorf("GetLatestForOrder() error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestCourierLocationModel_DeleteOlderThan(t *testing.T) {
+	model := CourierLocationModel{DB: testDB}
+	courier := insertTestCourier(t, UserModel{DB: testDB})
+	order := insertOutForDeliveryOrder(t, courier.Id)
+
+	ping := &CourierLocation{OrderID: order.ID, CourierID: courier.Id, Latitude: -34.60, Longitude: -58.38}
+	if err := model.Insert(t.Context(), ping, 0); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	if _, err := model.DeleteOlderThan(t.Context(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("DeleteOlderThan() error = %v", err)
+	}
+
+	_, err := model.GetLatestForOrder(t.Context(), order.ID)
+	if err != ErrRecordNotFound {
+		t.Errorf("GetLatestForOrder() after DeleteOlderThan() error = %v, want ErrRecordNotFound", err)
+	}
+}
@@ -0,0 +1,332 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xtommas/food-backend/internal/geo"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+const (
+	ZonePolygon = "polygon"
+	ZoneCircle  = "circle"
+)
+
+type DeliveryZone struct {
+	ID              int64       `json:"id"`
+	RestaurantID    int64       `json:"restaurant_id"`
+	Name            string      `json:"name"`
+	Kind            string      `json:"kind"`
+	Polygon         []geo.Point `json:"polygon,omitempty"`
+	CenterLatitude  float64     `json:"center_latitude,omitempty"`
+	CenterLongitude float64     `json:"center_longitude,omitempty"`
+	RadiusMeters    int         `json:"radius_meters,omitempty"`
+	Fee             int64       `json:"fee"`
+	MinimumOrder    int64       `json:"minimum_order"`
+	CreatedAt       time.Time   `json:"created_at"`
+	Version         int         `json:"-"`
+}
+
+// Contains reports whether the given coordinates fall inside the zone
+func (z *DeliveryZone) Contains(latitude, longitude float64) bool {
+	point := geo.Point{Latitude: latitude, Longitude: longitude}
+
+	switch z.Kind {
+	case ZonePolygon:
+		return geo.InPolygon(z.Polygon, point)
+	case ZoneCircle:
+		center := geo.Point{Latitude: z.CenterLatitude, Longitude: z.CenterLongitude}
+		return geo.InCircle(center, float64(z.RadiusMeters), point)
+	default:
+		return false
+	}
+}
+
+// MatchDeliveryZone returns the zone covering the given coordinates. When zones
+// overlap the one with the lowest fee wins, so customers are never overcharged.
+// It returns nil if no zone covers the coordinates
+func MatchDeliveryZone(zones []*DeliveryZone, latitude, longitude float64) *DeliveryZone {
+	var match *DeliveryZone
+
+	for _, zone := range zones {
+		if !zone.Contains(latitude, longitude) {
+			continue
+		}
+		if match == nil || zone.Fee < match.Fee {
+			match = zone
+		}
+	}
+
+	return match
+}
+
+func ValidateDeliveryZone(v *validator.Validator, zone *DeliveryZone) {
+	v.Check(zone.Name != "", "name", "must be provided")
+	v.Check(utf8.RuneCountInString(zone.Name) <= 100, "name", "must be no more than 100 characters long")
+
+	v.Check(validator.PermittedValue(zone.Kind, ZonePolygon, ZoneCircle), "kind", "must be either polygon or circle")
+
+	switch zone.Kind {
+	case ZonePolygon:
+		v.Check(len(zone.Polygon) >= 3, "polygon", "must contain at least 3 points")
+		v.Check(len(zone.Polygon) <= 500, "polygon", "must not contain more than 500 points")
+		for _, point := range zone.Polygon {
+			if point.Latitude < -90 || point.Latitude > 90 || point.Longitude < -180 || point.Longitude > 180 {
+				v.AddError("polygon", "points must have a latitude between -90 and 90 and a longitude between -180 and 180")
+				break
+			}
+		}
+		v.Check(zone.RadiusMeters == 0, "radius_meters", "must only be provided for circle zones")
+	case ZoneCircle:
+		v.Check(zone.CenterLatitude >= -90 && zone.CenterLatitude <= 90, "center_latitude", "must be between -90 and 90")
+		v.Check(zone.CenterLongitude >= -180 && zone.CenterLongitude <= 180, "center_longitude", "must be between -180 and 180")
+		v.Check(zone.RadiusMeters > 0, "radius_meters", "must be greater than zero")
+		v.Check(zone.RadiusMeters <= 100_000, "radius_meters", "must not be more than 100000")
+		v.Check(len(zone.Polygon) == 0, "polygon", "must only be provided for polygon zones")
+	}
+
+	v.Check(zone.Fee >= 0, "fee", "must not be negative")
+	v.Check(zone.MinimumOrder >= 0, "minimum_order", "must not be negative")
+}
+
+type DeliveryZoneModel struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+// polygons are stored as a JSONB array of points. Circles store an empty array
+func marshalPolygon(polygon []geo.Point) (string, error) {
+	if polygon == nil {
+		polygon = []geo.Point{}
+	}
+
+	js, err := json.Marshal(polygon)
+	if err != nil {
+		return "", err
+	}
+
+	return string(js), nil
+}
+
+func (m DeliveryZoneModel) Insert(ctx context.Context, zone *DeliveryZone) error {
+	polygon, err := marshalPolygon(zone.Polygon)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO delivery_zones (restaurant_id, name, kind, polygon, center_latitude, center_longitude, radius_meters, fee, minimum_order)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, version`
+
+	args := []any{
+		zone.RestaurantID,
+		zone.Name,
+		zone.Kind,
+		polygon,
+		zone.CenterLatitude,
+		zone.CenterLongitude,
+		zone.RadiusMeters,
+		zone.Fee,
+		zone.MinimumOrder,
+	}
+
+	ctx, span := startSpan(ctx, "DeliveryZoneModel.Insert")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&zone.ID, &zone.CreatedAt, &zone.Version)
+}
+
+func (m DeliveryZoneModel) Get(ctx context.Context, id int64, restaurantID int64) (*DeliveryZone, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, restaurant_id, name, kind, polygon, center_latitude, center_longitude, radius_meters, fee, minimum_order, created_at, version
+		FROM delivery_zones
+		WHERE id = $1 AND restaurant_id = $2`
+
+	var zone DeliveryZone
+	var polygon []byte
+
+	ctx, span := startSpan(ctx, "DeliveryZoneModel.Get")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, restaurantID).Scan(
+		&zone.ID,
+		&zone.RestaurantID,
+		&zone.Name,
+		&zone.Kind,
+		&polygon,
+		&zone.CenterLatitude,
+		&zone.CenterLongitude,
+		&zone.RadiusMeters,
+		&zone.Fee,
+		&zone.MinimumOrder,
+		&zone.CreatedAt,
+		&zone.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = json.Unmarshal(polygon, &zone.Polygon)
+	if err != nil {
+		return nil, err
+	}
+
+	return &zone, nil
+}
+
+func (m DeliveryZoneModel) GetAllForRestaurant(ctx context.Context, restaurantID int64) ([]*DeliveryZone, error) {
+	query := `
+		SELECT id, restaurant_id, name, kind, polygon, center_latitude, center_longitude, radius_meters, fee, minimum_order, created_at, version
+		FROM delivery_zones
+		WHERE restaurant_id = $1
+		ORDER BY fee ASC, id ASC`
+
+	ctx, span := startSpan(ctx, "DeliveryZoneModel.GetAllForRestaurant")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []*DeliveryZone{}
+
+	for rows.Next() {
+		var zone DeliveryZone
+		var polygon []byte
+
+		err := rows.Scan(
+			&zone.ID,
+			&zone.RestaurantID,
+			&zone.Name,
+			&zone.Kind,
+			&polygon,
+			&zone.CenterLatitude,
+			&zone.CenterLongitude,
+			&zone.RadiusMeters,
+			&zone.Fee,
+			&zone.MinimumOrder,
+			&zone.CreatedAt,
+			&zone.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(polygon, &zone.Polygon)
+		if err != nil {
+			return nil, err
+		}
+
+		zones = append(zones, &zone)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return zones, nil
+}
+
+func (m DeliveryZoneModel) Update(ctx context.Context, zone *DeliveryZone) error {
+	polygon, err := marshalPolygon(zone.Polygon)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE delivery_zones
+		SET name = $1, kind = $2, polygon = $3, center_latitude = $4, center_longitude = $5,
+		    radius_meters = $6, fee = $7, minimum_order = $8, version = version + 1
+		WHERE id = $9 AND restaurant_id = $10 AND version = $11
+		RETURNING version`
+
+	args := []any{
+		zone.Name,
+		zone.Kind,
+		polygon,
+		zone.CenterLatitude,
+		zone.CenterLongitude,
+		zone.RadiusMeters,
+		zone.Fee,
+		zone.MinimumOrder,
+		zone.ID,
+		zone.RestaurantID,
+		zone.Version,
+	}
+
+	ctx, span := startSpan(ctx, "DeliveryZoneModel.Update")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(&zone.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m DeliveryZoneModel) Delete(ctx context.Context, id int64, restaurantID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM delivery_zones
+		WHERE id = $1 AND restaurant_id = $2`
+
+	ctx, span := startSpan(ctx, "DeliveryZoneModel.Delete")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
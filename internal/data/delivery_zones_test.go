@@ -0,0 +1,216 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/xtommas/food-backend/internal/geo"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+var testZonePolygon = []geo.Point{
+	{Latitude: -34.5700, Longitude: -58.4400},
+	{Latitude: -34.5700, Longitude: -58.4000},
+	{Latitude: -34.6000, Longitude: -58.4000},
+	{Latitude: -34.6000, Longitude: -58.4400},
+}
+
+func insertTestDeliveryZone(t *testing.T, model DeliveryZoneModel, restaurantID int64) *DeliveryZone {
+	t.Helper()
+
+	zone := &DeliveryZone{
+		RestaurantID: restaurantID,
+		Name:         "Palermo",
+		Kind:         ZonePolygon,
+		Polygon:      testZonePolygon,
+		Fee:          300,
+		MinimumOrder: 1500,
+	}
+
+	if err := model.Insert(t.Context(), zone); err != nil {
+		t.Fatalf("failed to insert test delivery zone: %v", err)
+	}
+
+	return zone
+}
+
+func TestDeliveryZone_Contains(t *testing.T) {
+	polygon := &DeliveryZone{Kind: ZonePolygon, Polygon: testZonePolygon}
+	circle := &DeliveryZone{Kind: ZoneCircle, CenterLatitude: -34.603722, CenterLongitude: -58.381592, RadiusMeters: 1000}
+
+	if !polygon.Contains(-34.5850, -58.4200) {
+		t.Error("polygon Contains() = false for a point inside")
+	}
+	if polygon.Contains(-34.603722, -58.381592) {
+		t.Error("polygon Contains() = true for a point outside")
+	}
+	if !circle.Contains(-34.6050, -58.3820) {
+		t.Error("circle Contains() = false for a point inside")
+	}
+	if circle.Contains(-34.5850, -58.4200) {
+		t.Error("circle Contains() = true for a point outside")
+	}
+}
+
+func TestMatchDeliveryZone(t *testing.T) {
+	wide := &DeliveryZone{ID: 1, Kind: ZoneCircle, CenterLatitude: -34.59, CenterLongitude: -58.42, RadiusMeters: 10_000, Fee: 500}
+	narrow := &DeliveryZone{ID: 2, Kind: ZonePolygon, Polygon: testZonePolygon, Fee: 200}
+	zones := []*DeliveryZone{wide, narrow}
+
+	if got := MatchDeliveryZone(zones, -34.5850, -58.4200); got != narrow {
+		t.Errorf("MatchDeliveryZone() in overlap = %v, want the cheaper zone", got)
+	}
+	if got := MatchDeliveryZone(zones, -34.6200, -58.4200); got != wide {
+		t.Errorf("MatchDeliveryZone() outside the polygon = %v, want the circle", got)
+	}
+	if got := MatchDeliveryZone(zones, -34.901112, -56.164532); got != nil {
+		t.Errorf("MatchDeliveryZone() outside every zone = %v, want nil", got)
+	}
+	if got := MatchDeliveryZone(nil, -34.5850, -58.4200); got != nil {
+		t.Errorf("MatchDeliveryZone() with no zones = %v, want nil", got)
+	}
+}
+
+func TestValidateDeliveryZone(t *testing.T) {
+	tests := []struct {
+		name      string
+		zone      DeliveryZone
+		wantValid bool
+		wantKey   string
+	}{
+		{"valid polygon", DeliveryZone{Name: "Palermo", Kind: ZonePolygon, Polygon: testZonePolygon}, true, ""},
+		{"valid circle", DeliveryZone{Name: "Centre", Kind: ZoneCircle, CenterLatitude: -34.6, CenterLongitude: -58.4, RadiusMeters: 2000}, true, ""},
+		{"unknown kind", DeliveryZone{Name: "Zone", Kind: "square"}, false, "kind"},
+		{"polygon with two points", DeliveryZone{Name: "Zone", Kind: ZonePolygon, Polygon: testZonePolygon[:2]}, false, "polygon"},
+		{"polygon point out of range", DeliveryZone{Name: "Zone", Kind: ZonePolygon, Polygon: []geo.Point{{Latitude: 0, Longitude: 0}, {Latitude: 0, Longitude: 1}, {Latitude: 91, Longitude: 1}}}, false, "polygon"},
+		{"circle without radius", DeliveryZone{Name: "Zone", Kind: ZoneCircle}, false, "radius_meters"},
+		{"circle with polygon", DeliveryZone{Name: "Zone", Kind: ZoneCircle, RadiusMeters: 100, Polygon: testZonePolygon}, false, "polygon"},
+		{"negative fee", DeliveryZone{Name: "Zone", Kind: ZoneCircle, RadiusMeters: 100, Fee: -1}, false, "fee"},
+		{"missing name", DeliveryZone{Kind: ZoneCircle, RadiusMeters: 100}, false, "name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateDeliveryZone(v, &tt.zone)
+
+			if v.Valid() != tt.wantValid {
+				t.Fatalf("ValidateDeliveryZone() valid = %v, want %v (errors: %v)", v.Valid(), tt.wantValid, v.Errors)
+			}
+			if tt.wantKey != "" {
+				if _, ok := v.Errors[tt.wantKey]; !ok {
+					t.Errorf("ValidateDeliveryZone() errors = %v, want key %q", v.Errors, tt.wantKey)
+				}
+			}
+		})
+	}
+}
+
+func TestDeliveryZoneModel_InsertAndGet(t *testing.T) {
+	model := DeliveryZoneModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	zone := insertTestDeliveryZone(t, model, restaurantID)
+
+	if zone.ID == 0 {
+		t.Error("Insert() did not set zone.ID")
+	}
+
+	fetched, err := model.Get(t.Context(), zone.ID, restaurantID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(fetched.Polygon) != len(testZonePolygon) {
+		t.Fatalf("Get() returned %d polygon points, want %d", len(fetched.Polygon), len(testZonePolygon))
+	}
+	if fetched.Polygon[0] != testZonePolygon[0] {
+		t.Errorf("Get() first point = %v, want %v", fetched.Polygon[0], testZonePolygon[0])
+	}
+	if fetched.MinimumOrder != 1500 {
+		t.Errorf("Get() MinimumOrder = %d, want 1500", fetched.MinimumOrder)
+	}
+
+	_, err = model.Get(t.Context(), zone.ID, seedRestaurant(t))
+	if err != ErrRecordNotFound {
+		t.Errorf("Get() for another restaurant error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestDeliveryZoneModel_GetAllForRestaurant(t *testing.T) {
+	model := DeliveryZoneModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+
+	zones, err := model.GetAllForRestaurant(t.Context(), restaurantID)
+	if err != nil {
+		t.Fatalf("GetAllForRestaurant() error = %v", err)
+	}
+	if len(zones) != 0 {
+		t.Errorf("GetAllForRestaurant() returned %d zones, want 0", len(zones))
+	}
+
+	insertTestDeliveryZone(t, model, restaurantID)
+	circle := &DeliveryZone{
+		RestaurantID:    restaurantID,
+		Name:            "Centre",
+		Kind:            ZoneCircle,
+		CenterLatitude:  -34.603722,
+		CenterLongitude: -58.381592,
+		RadiusMeters:    2000,
+		Fee:             100,
+	}
+	if err := model.Insert(t.Context(), circle); err != nil {
+		t.Fatalf("Insert() circle error = %v", err)
+	}
+
+	zones, err = model.GetAllForRestaurant(t.Context(), restaurantID)
+	if err != nil {
+		t.Fatalf("GetAllForRestaurant() error = %v", err)
+	}
+	if len(zones) != 2 {
+		t.Fatalf("GetAllForRestaurant() returned %d zones, want 2", len(zones))
+	}
+	if zones[0].ID != circle.ID {
+		t.Errorf("GetAllForRestaurant() first zone = %d, want the cheapest zone %d", zones[0].ID, circle.ID)
+	}
+	if len(zones[0].Polygon) != 0 {
+		t.Errorf("GetAllForRestaurant() circle has %d polygon points, want 0", len(zones[0].Polygon))
+	}
+}
+
+func TestDeliveryZoneModel_Update(t *testing.T) {
+	model := DeliveryZoneModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	zone := insertTestDeliveryZone(t, model, restaurantID)
+
+	stale := *zone
+
+	zone.Fee = 450
+	if err := model.Update(t.Context(), zone); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	fetched, err := model.Get(t.Context(), zone.ID, restaurantID)
+	if err != nil {
+		t.Fatalf("Get() after Update() error = %v", err)
+	}
+	if fetched.Fee != 450 {
+		t.Errorf("Update() Fee = %d, want 450", fetched.Fee)
+	}
+
+	stale.Fee = 999
+	if err := model.Update(t.Context(), &stale); err != ErrEditConflict {
+		t.Errorf("Update() stale zone error = %v, want ErrEditConflict", err)
+	}
+}
+
+func TestDeliveryZoneModel_Delete(t *testing.T) {
+	model := DeliveryZoneModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	zone := insertTestDeliveryZone(t, model, restaurantID)
+
+	if err := model.Delete(t.Context(), zone.ID, restaurantID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if err := model.Delete(t.Context(), zone.ID, restaurantID); err != ErrRecordNotFound {
+		t.Errorf("Delete() twice error = %v, want ErrRecordNotFound", err)
+	}
+}
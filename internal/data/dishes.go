@@ -21,11 +21,25 @@ type Dish struct {
 	Categories   []string  `json:"categories"`
 	Photo        string    `json:"photo,omitempty"`
 	Available    bool      `json:"available"`
+	Rating       float64   `json:"rating"`
+	RatingCount  int       `json:"rating_count"`
+	IsFavorite   bool      `json:"is_favorite"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// dishRatingsJoin adds the average rating and number of ratings each dish has
+// received in visible reviews, for a query that aliases dishes as d
+const dishRatingsJoin = `
+		LEFT JOIN LATERAL (
+			SELECT COALESCE(ROUND(AVG(dr.rating), 2), 0)::float8 AS rating, COUNT(*) AS rating_count
+			FROM dish_reviews dr
+			INNER JOIN reviews rv ON rv.id = dr.review_id AND NOT rv.hidden
+			WHERE dr.dish_id = d.id
+		) ratings ON TRUE`
+
 type DishModel struct {
-	DB *sql.DB
+	DB           *sql.DB
+	QueryTimeout time.Duration
 }
 
 func ValidateDish(v *validator.Validator, dish *Dish) {
@@ -44,7 +58,7 @@ func ValidateDish(v *validator.Validator, dish *Dish) {
 	v.Check(validator.Unique(dish.Categories), "categories", "must not contain duplicate values")
 }
 
-func (d DishModel) Insert(dish *Dish) error {
+func (d DishModel) Insert(ctx context.Context, dish *Dish) error {
 	query := `
 		INSERT INTO dishes (restaurant_id, name, price, description, categories, photo)
 		VALUES ($1, $2, $3, $4, $5, $6)
@@ -52,25 +66,32 @@ func (d DishModel) Insert(dish *Dish) error {
 
 	args := []any{dish.RestaurantID, dish.Name, dish.Price, dish.Description, pq.Array(dish.Categories), dish.Photo}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "DishModel.Insert")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, d.QueryTimeout)
 	defer cancel()
 
 	return d.DB.QueryRowContext(ctx, query, args...).Scan(&dish.ID, &dish.Available, &dish.UpdatedAt)
 }
 
-func (d DishModel) Get(id int64) (*Dish, error) {
+func (d DishModel) Get(ctx context.Context, id int64) (*Dish, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT id, restaurant_id, name, price, description, categories, photo, available, updated_at
-		FROM dishes
-		WHERE id = $1`
+		SELECT d.id, d.restaurant_id, d.name, d.price, d.description, d.categories, d.photo, d.available,
+		       ratings.rating, ratings.rating_count, d.updated_at
+		FROM dishes d` + dishRatingsJoin + `
+		WHERE d.id = $1`
 
 	var dish Dish
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "DishModel.Get")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, d.QueryTimeout)
 	defer cancel()
 
 	err := d.DB.QueryRowContext(ctx, query, id).Scan(
@@ -82,6 +103,8 @@ func (d DishModel) Get(id int64) (*Dish, error) {
 		pq.Array(&dish.Categories),
 		&dish.Photo,
 		&dish.Available,
+		&dish.Rating,
+		&dish.RatingCount,
 		&dish.UpdatedAt,
 	)
 	if err != nil {
@@ -96,17 +119,21 @@ func (d DishModel) Get(id int64) (*Dish, error) {
 	return &dish, nil
 }
 
-func (d DishModel) GetForRestaurant(id int64, restaurantID int64) ([]*Dish, error) {
+func (d DishModel) GetForRestaurant(ctx context.Context, id int64, restaurantID int64) ([]*Dish, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT id, restaurant_id, name, price, description, categories, photo, available, updated_at
-		FROM dishes
-		WHERE id = $1 AND restaurant_id = $2`
+		SELECT d.id, d.restaurant_id, d.name, d.price, d.description, d.categories, d.photo, d.available,
+		       ratings.rating, ratings.rating_count, d.updated_at
+		FROM dishes d` + dishRatingsJoin + `
+		WHERE d.id = $1 AND d.restaurant_id = $2`
+
+	ctx, span := startSpan(ctx, "DishModel.GetForRestaurant")
+	defer span.End()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withTimeout(ctx, d.QueryTimeout)
 	defer cancel()
 
 	rows, err := d.DB.QueryContext(ctx, query, id, restaurantID)
@@ -129,6 +156,8 @@ func (d DishModel) GetForRestaurant(id int64, restaurantID int64) ([]*Dish, erro
 			pq.Array(&dish.Categories),
 			&dish.Photo,
 			&dish.Available,
+			&dish.Rating,
+			&dish.RatingCount,
 			&dish.UpdatedAt,
 		)
 		if err != nil {
@@ -149,7 +178,7 @@ func (d DishModel) GetForRestaurant(id int64, restaurantID int64) ([]*Dish, erro
 	return dishes, nil
 }
 
-func (d DishModel) Update(dish *Dish) error {
+func (d DishModel) Update(ctx context.Context, dish *Dish) error {
 	query := `
 		UPDATE dishes
 		SET name = $1, price = $2, description = $3, categories = $4, photo = $5, available = $6
@@ -166,7 +195,10 @@ func (d DishModel) Update(dish *Dish) error {
 		dish.ID,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "DishModel.Update")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, d.QueryTimeout)
 	defer cancel()
 
 	err := d.DB.QueryRowContext(ctx, query, args...).Scan(&dish.UpdatedAt)
@@ -182,7 +214,7 @@ func (d DishModel) Update(dish *Dish) error {
 	return nil
 }
 
-func (d DishModel) Delete(id int64) error {
+func (d DishModel) Delete(ctx context.Context, id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
@@ -191,7 +223,10 @@ func (d DishModel) Delete(id int64) error {
 		DELETE FROM dishes
 		WHERE id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "DishModel.Delete")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, d.QueryTimeout)
 	defer cancel()
 
 	result, err := d.DB.ExecContext(ctx, query, id)
@@ -211,17 +246,72 @@ func (d DishModel) Delete(id int64) error {
 	return nil
 }
 
-func (d DishModel) GetAll(name string, categories []string, available sql.NullBool, filters Filters) ([]*Dish, Metadata, error) {
-	query := fmt.Sprintf(`
-		SELECT COUNT(*) OVER(), id, restaurant_id, name, price, description, categories, photo, available, updated_at
+// GetPhotoKeysForRestaurant returns the storage keys of every dish photo and
+// dish gallery image of a restaurant. Deleting the restaurant removes their rows
+// but not their files, so they have to be read first
+func (d DishModel) GetPhotoKeysForRestaurant(ctx context.Context, restaurantID int64) ([]string, error) {
+	query := `
+		SELECT photo
 		FROM dishes
-		WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
-		AND (categories @> $2 OR $2 = '{}')
-		AND (available = $3 OR $3 IS NULL)
-		ORDER BY %s %s, id ASC
-		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+		WHERE restaurant_id = $1 AND photo <> ''
+		UNION ALL
+		SELECT i.key
+		FROM dish_images i
+		JOIN dishes d ON d.id = i.dish_id
+		WHERE d.restaurant_id = $1`
+
+	ctx, span := startSpan(ctx, "DishModel.GetPhotoKeysForRestaurant")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, d.QueryTimeout)
+	defer cancel()
+
+	rows, err := d.DB.QueryContext(ctx, query, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+
+	for rows.Next() {
+		var key string
+
+		err := rows.Scan(&key)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	return keys, nil
+}
+
+func (d DishModel) GetAll(ctx context.Context, name string, categories []string, available sql.NullBool, filters Filters) ([]*Dish, Metadata, error) {
+	// the page is selected first, so ratings are only aggregated for the dishes being returned
+	query := fmt.Sprintf(`
+		SELECT d.total_records, d.id, d.restaurant_id, d.name, d.price, d.description, d.categories, d.photo, d.available,
+		       ratings.rating, ratings.rating_count, d.updated_at
+		FROM (
+			SELECT COUNT(*) OVER() AS total_records, id, restaurant_id, name, price, description, categories, photo, available, updated_at
+			FROM dishes
+			WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+			AND (categories @> $2 OR $2 = '{}')
+			AND (available = $3 OR $3 IS NULL)
+			ORDER BY %[1]s %[2]s, id ASC
+			LIMIT $4 OFFSET $5
+		) d`+dishRatingsJoin+`
+		ORDER BY d.%[1]s %[2]s, d.id ASC`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, span := startSpan(ctx, "DishModel.GetAll")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, d.QueryTimeout)
 	defer cancel()
 
 	rows, err := d.DB.QueryContext(ctx, query, name, pq.Array(categories), available, filters.limit(), filters.offset())
@@ -246,6 +336,8 @@ func (d DishModel) GetAll(name string, categories []string, available sql.NullBo
 			pq.Array(&dish.Categories),
 			&dish.Photo,
 			&dish.Available,
+			&dish.Rating,
+			&dish.RatingCount,
 			&dish.UpdatedAt,
 		)
 		if err != nil {
@@ -264,21 +356,32 @@ func (d DishModel) GetAll(name string, categories []string, available sql.NullBo
 	return dishes, metadata, nil
 }
 
-func (d DishModel) GetAllForRestaurant(restaurantID int64, name string, categories []string, available sql.NullBool, filters Filters) ([]*Dish, Metadata, error) {
+func (d DishModel) GetAllForRestaurant(ctx context.Context, restaurantID int64, userID int64, name string, categories []string, available sql.NullBool, filters Filters) ([]*Dish, Metadata, error) {
+	// the page is selected first, so ratings and favourites are only looked up for the dishes being returned
 	query := fmt.Sprintf(`
-		SELECT COUNT(*) OVER(), id, restaurant_id, name, price, description, categories, photo, available, updated_at
-		FROM dishes
-		WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
-		AND (categories @> $2 OR $2 = '{}')
-		AND (available = $3 OR $3 IS NULL)
-		AND restaurant_id = $4
-		ORDER BY %s %s, id ASC
-		LIMIT $5 OFFSET $6`, filters.sortColumn(), filters.sortDirection())
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		SELECT d.total_records, d.id, d.restaurant_id, d.name, d.price, d.description, d.categories, d.photo, d.available,
+		       ratings.rating, ratings.rating_count,
+		       EXISTS (SELECT 1 FROM favorite_dishes fd WHERE fd.dish_id = d.id AND fd.user_id = $7),
+		       d.updated_at
+		FROM (
+			SELECT COUNT(*) OVER() AS total_records, id, restaurant_id, name, price, description, categories, photo, available, updated_at
+			FROM dishes
+			WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+			AND (categories @> $2 OR $2 = '{}')
+			AND (available = $3 OR $3 IS NULL)
+			AND restaurant_id = $4
+			ORDER BY %[1]s %[2]s, id ASC
+			LIMIT $5 OFFSET $6
+		) d`+dishRatingsJoin+`
+		ORDER BY d.%[1]s %[2]s, d.id ASC`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, span := startSpan(ctx, "DishModel.GetAllForRestaurant")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, d.QueryTimeout)
 	defer cancel()
 
-	rows, err := d.DB.QueryContext(ctx, query, name, pq.Array(categories), available, restaurantID, filters.limit(), filters.offset())
+	rows, err := d.DB.QueryContext(ctx, query, name, pq.Array(categories), available, restaurantID, filters.limit(), filters.offset(), userID)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
@@ -300,6 +403,9 @@ func (d DishModel) GetAllForRestaurant(restaurantID int64, name string, categori
 			pq.Array(&dish.Categories),
 			&dish.Photo,
 			&dish.Available,
+			&dish.Rating,
+			&dish.RatingCount,
+			&dish.IsFavorite,
 			&dish.UpdatedAt,
 		)
 		if err != nil {
@@ -1,7 +1,9 @@
 package data
 
 import (
+	"context"
 	"database/sql"
+	"slices"
 	"testing"
 	"time"
 
@@ -41,10 +43,10 @@ func newTestDish(restaurantID int64) *Dish {
 func insertTestDish(t *testing.T, model DishModel, restaurantID int64) *Dish {
 	t.Helper()
 	dish := newTestDish(restaurantID)
-	if err := model.Insert(dish); err != nil {
+	if err := model.Insert(t.Context(), dish); err != nil {
 		t.Fatalf("failed to insert test dish: %v", err)
 	}
-	t.Cleanup(func() { model.Delete(dish.ID) })
+	t.Cleanup(func() { model.Delete(context.Background(), dish.ID) })
 	return dish
 }
 
@@ -55,8 +57,8 @@ func TestDishModel_Insert(t *testing.T) {
 	restaurantID := seedRestaurant(t)
 
 	dish := newTestDish(restaurantID)
-	err := model.Insert(dish)
-	t.Cleanup(func() { model.Delete(dish.ID) })
+	err := model.Insert(t.Context(), dish)
+	t.Cleanup(func() { model.Delete(context.Background(), dish.ID) })
 
 	if err != nil {
 		t.Fatalf("Insert() error = %v", err)
@@ -92,9 +94,9 @@ func TestDishModel_Insert_ValidationEdgeCases(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			dish := newTestDish(restaurantID)
 			tt.mutate(dish)
-			err := model.Insert(dish)
+			err := model.Insert(t.Context(), dish)
 			if err == nil {
-				t.Cleanup(func() { model.Delete(dish.ID) })
+				t.Cleanup(func() { model.Delete(context.Background(), dish.ID) })
 			}
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Insert() error = %v, wantErr = %v", err, tt.wantErr)
@@ -110,7 +112,7 @@ func TestDishModel_Get(t *testing.T) {
 	restaurantID := seedRestaurant(t)
 	dish := insertTestDish(t, model, restaurantID)
 
-	fetched, err := model.Get(dish.ID)
+	fetched, err := model.Get(t.Context(), dish.ID)
 	if err != nil {
 		t.Fatalf("Get() error = %v", err)
 	}
@@ -132,7 +134,7 @@ func TestDishModel_Get(t *testing.T) {
 func TestDishModel_Get_NotFound(t *testing.T) {
 	model := DishModel{DB: testDB}
 
-	_, err := model.Get(999999)
+	_, err := model.Get(t.Context(), 999999)
 	if err != ErrRecordNotFound {
 		t.Errorf("Get() error = %v, want ErrRecordNotFound", err)
 	}
@@ -141,12 +143,12 @@ func TestDishModel_Get_NotFound(t *testing.T) {
 func TestDishModel_Get_InvalidID(t *testing.T) {
 	model := DishModel{DB: testDB}
 
-	_, err := model.Get(0)
+	_, err := model.Get(t.Context(), 0)
 	if err != ErrRecordNotFound {
 		t.Errorf("Get() with id=0 error = %v, want ErrRecordNotFound", err)
 	}
 
-	_, err = model.Get(-1)
+	_, err = model.Get(t.Context(), -1)
 	if err != ErrRecordNotFound {
 		t.Errorf("Get() with id=-1 error = %v, want ErrRecordNotFound", err)
 	}
@@ -163,11 +165,11 @@ func TestDishModel_Update(t *testing.T) {
 	dish.Price = 999
 	dish.Available = false
 
-	if err := model.Update(dish); err != nil {
+	if err := model.Update(t.Context(), dish); err != nil {
 		t.Fatalf("Update() error = %v", err)
 	}
 
-	fetched, err := model.Get(dish.ID)
+	fetched, err := model.Get(t.Context(), dish.ID)
 	if err != nil {
 		t.Fatalf("Get() after Update() error = %v", err)
 	}
@@ -192,7 +194,7 @@ func TestDishModel_Update_SetsUpdatedAt(t *testing.T) {
 	time.Sleep(1100 * time.Millisecond)
 
 	dish.Name = "New Name"
-	if err := model.Update(dish); err != nil {
+	if err := model.Update(t.Context(), dish); err != nil {
 		t.Fatalf("Update() error = %v", err)
 	}
 
@@ -214,7 +216,7 @@ func TestDishModel_Update_NotFound(t *testing.T) {
 		Categories:   []string{"none"},
 	}
 
-	err := model.Update(ghost)
+	err := model.Update(t.Context(), ghost)
 	if err != ErrRecordNotFound {
 		t.Errorf("Update() error = %v, want ErrRecordNotFound", err)
 	}
@@ -227,24 +229,57 @@ func TestDishModel_Delete(t *testing.T) {
 	restaurantID := seedRestaurant(t)
 
 	dish := newTestDish(restaurantID)
-	if err := model.Insert(dish); err != nil {
+	if err := model.Insert(t.Context(), dish); err != nil {
 		t.Fatalf("Insert() error = %v", err)
 	}
 
-	if err := model.Delete(dish.ID); err != nil {
+	if err := model.Delete(t.Context(), dish.ID); err != nil {
 		t.Fatalf("Delete() error = %v", err)
 	}
 
-	_, err := model.Get(dish.ID)
+	_, err := model.Get(t.Context(), dish.ID)
 	if err != ErrRecordNotFound {
 		t.Errorf("Get() after Delete() error = %v, want ErrRecordNotFound", err)
 	}
 }
 
+func TestDishModel_GetPhotoKeysForRestaurant(t *testing.T) {
+	model := DishModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+
+	withPhoto := newTestDish(restaurantID)
+	withPhoto.Photo = "dishes/test-photo.jpg"
+	if err := model.Insert(t.Context(), withPhoto); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	t.Cleanup(func() { model.Delete(context.Background(), withPhoto.ID) })
+
+	withoutPhoto := insertTestDish(t, model, restaurantID)
+	image := insertTestGalleryImage(t, GalleryModel{DB: testDB, gallery: dishGallery}, withoutPhoto.ID, false)
+
+	other := insertTestDish(t, model, seedRestaurant(t))
+	other.Photo = "dishes/other-photo.jpg"
+	if err := model.Update(t.Context(), other); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	keys, err := model.GetPhotoKeysForRestaurant(t.Context(), restaurantID)
+	if err != nil {
+		t.Fatalf("GetPhotoKeysForRestaurant() error = %v", err)
+	}
+
+	slices.Sort(keys)
+	want := []string{withPhoto.Photo, image.Key}
+	slices.Sort(want)
+	if !slices.Equal(keys, want) {
+		t.Errorf("GetPhotoKeysForRestaurant() = %v, want %v", keys, want)
+	}
+}
+
 func TestDishModel_Delete_NotFound(t *testing.T) {
 	model := DishModel{DB: testDB}
 
-	err := model.Delete(999999)
+	err := model.Delete(t.Context(), 999999)
 	if err != ErrRecordNotFound {
 		t.Errorf("Delete() error = %v, want ErrRecordNotFound", err)
 	}
@@ -253,7 +288,7 @@ func TestDishModel_Delete_NotFound(t *testing.T) {
 func TestDishModel_Delete_InvalidID(t *testing.T) {
 	model := DishModel{DB: testDB}
 
-	err := model.Delete(0)
+	err := model.Delete(t.Context(), 0)
 	if err != ErrRecordNotFound {
 		t.Errorf("Delete() with id=0 error = %v, want ErrRecordNotFound", err)
 	}
@@ -273,7 +308,7 @@ func TestDishModel_GetAllForRestaurant(t *testing.T) {
 	insertTestDish(t, model, otherRestaurantID)
 
 	filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}}
-	dishes, metadata, err := model.GetAllForRestaurant(restaurantID, "", []string{}, sql.NullBool{}, filters)
+	dishes, metadata, err := model.GetAllForRestaurant(t.Context(), restaurantID, 0, "", []string{}, sql.NullBool{}, filters)
 	if err != nil {
 		t.Fatalf("GetAllForRestaurant() error = %v", err)
 	}
@@ -294,10 +329,10 @@ func TestDishModel_GetAllForRestaurant_FilterByName(t *testing.T) {
 	dish := insertTestDish(t, model, restaurantID)
 
 	dish.Name = "Unique Searchable Name"
-	model.Update(dish)
+	model.Update(t.Context(), dish)
 
 	filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}}
-	results, _, err := model.GetAllForRestaurant(restaurantID, "Unique Searchable", []string{}, sql.NullBool{}, filters)
+	results, _, err := model.GetAllForRestaurant(t.Context(), restaurantID, 0, "Unique Searchable", []string{}, sql.NullBool{}, filters)
 	if err != nil {
 		t.Fatalf("GetAllForRestaurant() error = %v", err)
 	}
@@ -319,11 +354,11 @@ func TestDishModel_GetAllForRestaurant_FilterByAvailability(t *testing.T) {
 	dish := insertTestDish(t, model, restaurantID)
 
 	dish.Available = false
-	model.Update(dish)
+	model.Update(t.Context(), dish)
 
 	filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}}
 
-	unavailable, _, err := model.GetAllForRestaurant(restaurantID, "", []string{}, sql.NullBool{Valid: true, Bool: false}, filters)
+	unavailable, _, err := model.GetAllForRestaurant(t.Context(), restaurantID, 0, "", []string{}, sql.NullBool{Valid: true, Bool: false}, filters)
 	if err != nil {
 		t.Fatalf("GetAllForRestaurant() error = %v", err)
 	}
@@ -333,7 +368,7 @@ func TestDishModel_GetAllForRestaurant_FilterByAvailability(t *testing.T) {
 		}
 	}
 
-	available, _, err := model.GetAllForRestaurant(restaurantID, "", []string{}, sql.NullBool{Valid: true, Bool: true}, filters)
+	available, _, err := model.GetAllForRestaurant(t.Context(), restaurantID, 0, "", []string{}, sql.NullBool{Valid: true, Bool: true}, filters)
 	if err != nil {
 		t.Fatalf("GetAllForRestaurant() error = %v", err)
 	}
@@ -350,10 +385,10 @@ func TestDishModel_GetAllForRestaurant_FilterByCategory(t *testing.T) {
 	dish := insertTestDish(t, model, restaurantID)
 
 	dish.Categories = []string{"vegan", "salad"}
-	model.Update(dish)
+	model.Update(t.Context(), dish)
 
 	filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}}
-	results, _, err := model.GetAllForRestaurant(restaurantID, "", []string{"vegan"}, sql.NullBool{}, filters)
+	results, _, err := model.GetAllForRestaurant(t.Context(), restaurantID, 0, "", []string{"vegan"}, sql.NullBool{}, filters)
 	if err != nil {
 		t.Fatalf("GetAllForRestaurant() error = %v", err)
 	}
@@ -380,11 +415,11 @@ func TestDishModel_GetAllForRestaurant_Pagination(t *testing.T) {
 	page1Filters := Filters{Page: 1, PageSize: 2, Sort: "id", SortSafelist: []string{"id"}}
 	page2Filters := Filters{Page: 2, PageSize: 2, Sort: "id", SortSafelist: []string{"id"}}
 
-	page1, meta1, err := model.GetAllForRestaurant(restaurantID, "", []string{}, sql.NullBool{}, page1Filters)
+	page1, meta1, err := model.GetAllForRestaurant(t.Context(), restaurantID, 0, "", []string{}, sql.NullBool{}, page1Filters)
 	if err != nil {
 		t.Fatalf("GetAllForRestaurant() page 1 error = %v", err)
 	}
-	page2, _, err := model.GetAllForRestaurant(restaurantID, "", []string{}, sql.NullBool{}, page2Filters)
+	page2, _, err := model.GetAllForRestaurant(t.Context(), restaurantID, 0, "", []string{}, sql.NullBool{}, page2Filters)
 	if err != nil {
 		t.Fatalf("GetAllForRestaurant() page 2 error = %v", err)
 	}
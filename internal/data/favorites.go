@@ -0,0 +1,275 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type FavoriteModel struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+// addFavorite saves a favourite, doing nothing if it is already saved. A
+// restaurant or dish that doesn't exist returns ErrRecordNotFound
+func (m FavoriteModel) addFavorite(ctx context.Context, query string, userID, targetID int64) error {
+	if targetID < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, targetID)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Code == "23503":
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m FavoriteModel) removeFavorite(ctx context.Context, query string, userID, targetID int64) error {
+	if targetID < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, targetID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m FavoriteModel) AddRestaurant(ctx context.Context, userID, restaurantID int64) error {
+	query := `
+		INSERT INTO favorite_restaurants (user_id, restaurant_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`
+
+	ctx, span := startSpan(ctx, "FavoriteModel.AddRestaurant")
+	defer span.End()
+
+	return m.addFavorite(ctx, query, userID, restaurantID)
+}
+
+func (m FavoriteModel) RemoveRestaurant(ctx context.Context, userID, restaurantID int64) error {
+	query := `
+		DELETE FROM favorite_restaurants
+		WHERE user_id = $1 AND restaurant_id = $2`
+
+	ctx, span := startSpan(ctx, "FavoriteModel.RemoveRestaurant")
+	defer span.End()
+
+	return m.removeFavorite(ctx, query, userID, restaurantID)
+}
+
+func (m FavoriteModel) AddDish(ctx context.Context, userID, dishID int64) error {
+	query := `
+		INSERT INTO favorite_dishes (user_id, dish_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`
+
+	ctx, span := startSpan(ctx, "FavoriteModel.AddDish")
+	defer span.End()
+
+	return m.addFavorite(ctx, query, userID, dishID)
+}
+
+func (m FavoriteModel) RemoveDish(ctx context.Context, userID, dishID int64) error {
+	query := `
+		DELETE FROM favorite_dishes
+		WHERE user_id = $1 AND dish_id = $2`
+
+	ctx, span := startSpan(ctx, "FavoriteModel.RemoveDish")
+	defer span.End()
+
+	return m.removeFavorite(ctx, query, userID, dishID)
+}
+
+// IsFavoriteRestaurant reports whether the user saved the restaurant, for
+// single-restaurant responses. Listings compute the flag in their own query
+func (m FavoriteModel) IsFavoriteRestaurant(ctx context.Context, userID, restaurantID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM favorite_restaurants WHERE user_id = $1 AND restaurant_id = $2)`
+
+	ctx, span := startSpan(ctx, "FavoriteModel.IsFavoriteRestaurant")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, query, userID, restaurantID).Scan(&exists)
+	return exists, err
+}
+
+// IsFavoriteDish reports whether the user saved the dish, for single-dish
+// responses. Listings compute the flag in their own query
+func (m FavoriteModel) IsFavoriteDish(ctx context.Context, userID, dishID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM favorite_dishes WHERE user_id = $1 AND dish_id = $2)`
+
+	ctx, span := startSpan(ctx, "FavoriteModel.IsFavoriteDish")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, query, userID, dishID).Scan(&exists)
+	return exists, err
+}
+
+func (m FavoriteModel) GetRestaurantsForUser(ctx context.Context, userID int64, filters Filters) ([]*Restaurant, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), r.id, r.name, r.photo, r.address, r.city, r.state, r.province, r.country, r.latitude, r.longitude,
+		       ratings.rating, ratings.rating_count, r.created_at, r.version
+		FROM favorite_restaurants f
+		INNER JOIN restaurants r ON r.id = f.restaurant_id`+restaurantRatingsJoin+`
+		WHERE f.user_id = $1
+		ORDER BY %s %s, r.id ASC
+		LIMIT $2 OFFSET $3`, favoriteSortColumn(filters, "r"), filters.sortDirection())
+
+	ctx, span := startSpan(ctx, "FavoriteModel.GetRestaurantsForUser")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	restaurants := []*Restaurant{}
+
+	for rows.Next() {
+		r := Restaurant{IsFavorite: true}
+
+		err := rows.Scan(
+			&totalRecords,
+			&r.ID,
+			&r.Name,
+			&r.Photo,
+			&r.Address,
+			&r.City,
+			&r.State,
+			&r.Province,
+			&r.Country,
+			&r.Latitude,
+			&r.Longitude,
+			&r.Rating,
+			&r.RatingCount,
+			&r.CreatedAt,
+			&r.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		restaurants = append(restaurants, &r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return restaurants, metadata, nil
+}
+
+func (m FavoriteModel) GetDishesForUser(ctx context.Context, userID int64, filters Filters) ([]*Dish, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), d.id, d.restaurant_id, d.name, d.price, d.description, d.categories, d.photo, d.available,
+		       ratings.rating, ratings.rating_count, d.updated_at
+		FROM favorite_dishes f
+		INNER JOIN dishes d ON d.id = f.dish_id`+dishRatingsJoin+`
+		WHERE f.user_id = $1
+		ORDER BY %s %s, d.id ASC
+		LIMIT $2 OFFSET $3`, favoriteSortColumn(filters, "d"), filters.sortDirection())
+
+	ctx, span := startSpan(ctx, "FavoriteModel.GetDishesForUser")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	dishes := []*Dish{}
+
+	for rows.Next() {
+		dish := Dish{IsFavorite: true}
+
+		err := rows.Scan(
+			&totalRecords,
+			&dish.ID,
+			&dish.RestaurantID,
+			&dish.Name,
+			&dish.Price,
+			&dish.Description,
+			pq.Array(&dish.Categories),
+			&dish.Photo,
+			&dish.Available,
+			&dish.Rating,
+			&dish.RatingCount,
+			&dish.UpdatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		dishes = append(dishes, &dish)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return dishes, metadata, nil
+}
+
+// favoriteSortColumn qualifies the sort column, since favourites are listed by
+// joining two tables. "created_at" sorts by when the favourite was saved
+func favoriteSortColumn(filters Filters, alias string) string {
+	column := filters.sortColumn()
+	if column == "created_at" {
+		return "f.created_at"
+	}
+
+	return alias + "." + column
+}
@@ -0,0 +1,148 @@
+package data
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func newTestFavoriteFilters() Filters {
+	return Filters{
+		Page:         1,
+		PageSize:     10,
+		Sort:         "-created_at",
+		SortSafelist: []string{"id", "name", "created_at", "-id", "-name", "-created_at"},
+	}
+}
+
+func TestFavoriteModel_Restaurants(t *testing.T) {
+	model := FavoriteModel{DB: testDB}
+	user := insertTestUser(t, UserModel{DB: testDB})
+	restaurantID := seedRestaurant(t)
+
+	if err := model.AddRestaurant(t.Context(), user.Id, restaurantID); err != nil {
+		t.Fatalf("AddRestaurant() error = %v", err)
+	}
+	// adding the same favourite again is a no-op
+	if err := model.AddRestaurant(t.Context(), user.Id, restaurantID); err != nil {
+		t.Fatalf("AddRestaurant() twice error = %v", err)
+	}
+
+	restaurants, metadata, err := model.GetRestaurantsForUser(t.Context(), user.Id, newTestFavoriteFilters())
+	if err != nil {
+		t.Fatalf("GetRestaurantsForUser() error = %v", err)
+	}
+	if len(restaurants) != 1 || restaurants[0].ID != restaurantID {
+		t.Fatalf("GetRestaurantsForUser() = %v, want restaurant %d", restaurants, restaurantID)
+	}
+	if !restaurants[0].IsFavorite {
+		t.Error("GetRestaurantsForUser() IsFavorite = false, want true")
+	}
+	if metadata.TotalRecords != 1 {
+		t.Errorf("GetRestaurantsForUser() TotalRecords = %d, want 1", metadata.TotalRecords)
+	}
+
+	isFavorite, err := model.IsFavoriteRestaurant(t.Context(), user.Id, restaurantID)
+	if err != nil {
+		t.Fatalf("IsFavoriteRestaurant() error = %v", err)
+	}
+	if !isFavorite {
+		t.Error("IsFavoriteRestaurant() = false, want true")
+	}
+
+	if err := model.RemoveRestaurant(t.Context(), user.Id, restaurantID); err != nil {
+		t.Fatalf("RemoveRestaurant() error = %v", err)
+	}
+	if err := model.RemoveRestaurant(t.Context(), user.Id, restaurantID); err != ErrRecordNotFound {
+		t.Errorf("RemoveRestaurant() twice error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestFavoriteModel_AddRestaurant_NotFound(t *testing.T) {
+	model := FavoriteModel{DB: testDB}
+	user := insertTestUser(t, UserModel{DB: testDB})
+
+	err := model.AddRestaurant(t.Context(), user.Id, 999999)
+	if err != ErrRecordNotFound {
+		t.Errorf("AddRestaurant() error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestFavoriteModel_Dishes(t *testing.T) {
+	model := FavoriteModel{DB: testDB}
+	user := insertTestUser(t, UserModel{DB: testDB})
+	restaurantID := seedRestaurant(t)
+	dish := insertTestDish(t, DishModel{DB: testDB}, restaurantID)
+
+	if err := model.AddDish(t.Context(), user.Id, dish.ID); err != nil {
+		t.Fatalf("AddDish() error = %v", err)
+	}
+
+	dishes, _, err := model.GetDishesForUser(t.Context(), user.Id, newTestFavoriteFilters())
+	if err != nil {
+		t.Fatalf("GetDishesForUser() error = %v", err)
+	}
+	if len(dishes) != 1 || dishes[0].ID != dish.ID {
+		t.Fatalf("GetDishesForUser() = %v, want dish %d", dishes, dish.ID)
+	}
+
+	if err := model.RemoveDish(t.Context(), user.Id, dish.ID); err != nil {
+		t.Fatalf("RemoveDish() error = %v", err)
+	}
+
+	dishes, _, err = model.GetDishesForUser(t.Context(), user.Id, newTestFavoriteFilters())
+	if err != nil {
+		t.Fatalf("GetDishesForUser() after RemoveDish() error = %v", err)
+	}
+	if len(dishes) != 0 {
+		t.Errorf("GetDishesForUser() after RemoveDish() returned %d dishes, want 0", len(dishes))
+	}
+}
+
+func TestIsFavoriteInListings(t *testing.T) {
+	user := insertTestUser(t, UserModel{DB: testDB})
+	other := insertTestUser(t, UserModel{DB: testDB})
+	restaurantID := seedRestaurant(t)
+	dishModel := DishModel{DB: testDB}
+	favorite := insertTestDish(t, dishModel, restaurantID)
+	insertTestDish(t, dishModel, restaurantID)
+
+	model := FavoriteModel{DB: testDB}
+	if err := model.AddDish(t.Context(), user.Id, favorite.ID); err != nil {
+		t.Fatalf("AddDish() error = %v", err)
+	}
+	if err := model.AddRestaurant(t.Context(), user.Id, restaurantID); err != nil {
+		t.Fatalf("AddRestaurant() error = %v", err)
+	}
+
+	filters := Filters{Page: 1, PageSize: 10, Sort: "id", SortSafelist: []string{"id"}}
+
+	dishes, _, err := dishModel.GetAllForRestaurant(t.Context(), restaurantID, user.Id, "", []string{}, sql.NullBool{}, filters)
+	if err != nil {
+		t.Fatalf("GetAllForRestaurant() error = %v", err)
+	}
+	for _, d := range dishes {
+		if d.IsFavorite != (d.ID == favorite.ID) {
+			t.Errorf("GetAllForRestaurant() dish %d IsFavorite = %v", d.ID, d.IsFavorite)
+		}
+	}
+
+	dishes, _, err = dishModel.GetAllForRestaurant(t.Context(), restaurantID, other.Id, "", []string{}, sql.NullBool{}, filters)
+	if err != nil {
+		t.Fatalf("GetAllForRestaurant() error = %v", err)
+	}
+	for _, d := range dishes {
+		if d.IsFavorite {
+			t.Errorf("GetAllForRestaurant() dish %d is a favourite of another user", d.ID)
+		}
+	}
+
+	restaurants, err := RestaurantModel{DB: testDB}.GetAll(t.Context(), user.Id)
+	if err != nil {
+		t.Fatalf("Restaurants.GetAll() error = %v", err)
+	}
+	for _, r := range restaurants {
+		if r.IsFavorite != (r.ID == restaurantID) {
+			t.Errorf("Restaurants.GetAll() restaurant %d IsFavorite = %v", r.ID, r.IsFavorite)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+// MaxGalleryImages is the number of images a single dish or restaurant can have
+const MaxGalleryImages = 20
+
+var (
+	ErrGalleryFull     = errors.New("gallery full")
+	ErrGalleryMismatch = errors.New("image ids do not match the gallery")
+)
+
+// GalleryImage is one photo of a dish or restaurant gallery. Key is where the
+// photo is stored, and URLs holds a link to each of its sizes
+type GalleryImage struct {
+	ID        int64             `json:"id"`
+	OwnerID   int64             `json:"-"`
+	Key       string            `json:"-"`
+	Caption   string            `json:"caption"`
+	Position  int               `json:"position"`
+	IsPrimary bool              `json:"is_primary"`
+	URLs      map[string]string `json:"urls,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+func ValidateGalleryImage(v *validator.Validator, image *GalleryImage) {
+	v.Check(utf8.RuneCountInString(image.Caption) <= 200, "caption", "must be no more than 200 characters long")
+}
+
+// gallery names the table holding the images of one kind of owner
+type gallery struct {
+	table       string
+	ownerTable  string
+	ownerColumn string
+}
+
+var (
+	dishGallery       = gallery{table: "dish_images", ownerTable: "dishes", ownerColumn: "dish_id"}
+	restaurantGallery = gallery{table: "restaurant_images", ownerTable: "restaurants", ownerColumn: "restaurant_id"}
+)
+
+// GalleryModel manages the images of either dishes or restaurants. The first
+// image added to a gallery becomes its primary image, and a gallery that has
+// images always has exactly one primary image
+type GalleryModel struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+	gallery      gallery
+}
+
+// Insert appends an image to the end of its owner's gallery
+func (m GalleryModel) Insert(ctx context.Context, image *GalleryImage) error {
+	ctx, span := startSpan(ctx, "GalleryModel.Insert")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.lockOwner(ctx, tx, image.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*), COALESCE(MAX(position), 0)
+		FROM %s
+		WHERE %s = $1`, m.gallery.table, m.gallery.ownerColumn)
+
+	var count, last int
+
+	err = tx.QueryRowContext(ctx, query, image.OwnerID).Scan(&count, &last)
+	if err != nil {
+		return err
+	}
+
+	if count >= MaxGalleryImages {
+		return ErrGalleryFull
+	}
+
+	if count == 0 {
+		image.IsPrimary = true
+	}
+
+	if image.IsPrimary {
+		err = m.clearPrimary(ctx, tx, image.OwnerID)
+		if err != nil {
+			return err
+		}
+	}
+
+	image.Position = last + 1
+
+	query = fmt.Sprintf(`
+		INSERT INTO %s (%s, key, caption, position, is_primary)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`, m.gallery.table, m.gallery.ownerColumn)
+
+	args := []any{image.OwnerID, image.Key, image.Caption, image.Position, image.IsPrimary}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&image.ID, &image.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m GalleryModel) Get(ctx context.Context, id int64, ownerID int64) (*GalleryImage, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, %[2]s, key, caption, position, is_primary, created_at
+		FROM %[1]s
+		WHERE id = $1 AND %[2]s = $2`, m.gallery.table, m.gallery.ownerColumn)
+
+	var image GalleryImage
+
+	ctx, span := startSpan(ctx, "GalleryModel.Get")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, ownerID).Scan(
+		&image.ID,
+		&image.OwnerID,
+		&image.Key,
+		&image.Caption,
+		&image.Position,
+		&image.IsPrimary,
+		&image.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &image, nil
+}
+
+// GetAll returns the images of a gallery in display order
+func (m GalleryModel) GetAll(ctx context.Context, ownerID int64) ([]*GalleryImage, error) {
+	query := fmt.Sprintf(`
+		SELECT id, %[2]s, key, caption, position, is_primary, created_at
+		FROM %[1]s
+		WHERE %[2]s = $1
+		ORDER BY position ASC`, m.gallery.table, m.gallery.ownerColumn)
+
+	ctx, span := startSpan(ctx, "GalleryModel.GetAll")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []*GalleryImage{}
+
+	for rows.Next() {
+		var image GalleryImage
+
+		err := rows.Scan(
+			&image.ID,
+			&image.OwnerID,
+			&image.Key,
+			&image.Caption,
+			&image.Position,
+			&image.IsPrimary,
+			&image.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, &image)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// Update saves the caption of an image. Making an image primary unsets the
+// previous primary image; the primary image can't be unset directly
+func (m GalleryModel) Update(ctx context.Context, image *GalleryImage) error {
+	ctx, span := startSpan(ctx, "GalleryModel.Update")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.lockOwner(ctx, tx, image.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	if image.IsPrimary {
+		err = m.clearPrimary(ctx, tx, image.OwnerID)
+		if err != nil {
+			return err
+		}
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET caption = $1, is_primary = is_primary OR $2
+		WHERE id = $3 AND %s = $4
+		RETURNING position, is_primary`, m.gallery.table, m.gallery.ownerColumn)
+
+	args := []any{image.Caption, image.IsPrimary, image.ID, image.OwnerID}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&image.Position, &image.IsPrimary)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Reorder sets the display order of a gallery. ids must list every image of
+// the gallery exactly once, or ErrGalleryMismatch is returned
+func (m GalleryModel) Reorder(ctx context.Context, ownerID int64, ids []int64) error {
+	ctx, span := startSpan(ctx, "GalleryModel.Reorder")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.lockOwner(ctx, tx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id
+		FROM %s
+		WHERE %s = $1
+		ORDER BY id`, m.gallery.table, m.gallery.ownerColumn)
+
+	rows, err := tx.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var current []int64
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		current = append(current, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+
+	if !slices.Equal(sorted, current) {
+		return ErrGalleryMismatch
+	}
+
+	query = fmt.Sprintf(`
+		UPDATE %[1]s AS g
+		SET position = o.position
+		FROM UNNEST($2::bigint[]) WITH ORDINALITY AS o(id, position)
+		WHERE g.id = o.id AND g.%[2]s = $1`, m.gallery.table, m.gallery.ownerColumn)
+
+	_, err = tx.ExecContext(ctx, query, ownerID, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes an image. When it was the primary image, the first remaining
+// image becomes primary
+func (m GalleryModel) Delete(ctx context.Context, id int64, ownerID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, span := startSpan(ctx, "GalleryModel.Delete")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.lockOwner(ctx, tx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE id = $1 AND %s = $2
+		RETURNING is_primary`, m.gallery.table, m.gallery.ownerColumn)
+
+	var wasPrimary bool
+
+	err = tx.QueryRowContext(ctx, query, id, ownerID).Scan(&wasPrimary)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if wasPrimary {
+		query = fmt.Sprintf(`
+			UPDATE %[1]s
+			SET is_primary = TRUE
+			WHERE id = (
+				SELECT id FROM %[1]s
+				WHERE %[2]s = $1
+				ORDER BY position ASC
+				LIMIT 1
+			)`, m.gallery.table, m.gallery.ownerColumn)
+
+		_, err = tx.ExecContext(ctx, query, ownerID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// lockOwner locks the dish or restaurant row, so concurrent changes to its
+// gallery are applied one at a time
+func (m GalleryModel) lockOwner(ctx context.Context, tx *sql.Tx, ownerID int64) error {
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE id = $1 FOR UPDATE`, m.gallery.ownerTable)
+
+	err := tx.QueryRowContext(ctx, query, ownerID).Scan(&ownerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m GalleryModel) clearPrimary(ctx context.Context, tx *sql.Tx, ownerID int64) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET is_primary = FALSE
+		WHERE %s = $1 AND is_primary`, m.gallery.table, m.gallery.ownerColumn)
+
+	_, err := tx.ExecContext(ctx, query, ownerID)
+	return err
+}
@@ -0,0 +1,210 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func insertTestGalleryImage(t *testing.T, model GalleryModel, ownerID int64, isPrimary bool) *GalleryImage {
+	t.Helper()
+
+	image := &GalleryImage{
+		OwnerID:   ownerID,
+		Key:       fmt.Sprintf("test/%d/%s.jpg", ownerID, t.Name()),
+		Caption:   "Fresh from the oven",
+		IsPrimary: isPrimary,
+	}
+
+	if err := model.Insert(t.Context(), image); err != nil {
+		t.Fatalf("failed to insert test gallery image: %v", err)
+	}
+
+	return image
+}
+
+func galleryIDs(t *testing.T, model GalleryModel, ownerID int64) []int64 {
+	t.Helper()
+
+	images, err := model.GetAll(t.Context(), ownerID)
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+
+	ids := []int64{}
+	for _, image := range images {
+		ids = append(ids, image.ID)
+	}
+	return ids
+}
+
+func primaryID(t *testing.T, model GalleryModel, ownerID int64) int64 {
+	t.Helper()
+
+	images, err := model.GetAll(t.Context(), ownerID)
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+
+	var id int64
+	for _, image := range images {
+		if image.IsPrimary {
+			if id != 0 {
+				t.Fatalf("gallery has more than one primary image")
+			}
+			id = image.ID
+		}
+	}
+	return id
+}
+
+func TestGalleryModel_InsertAppendsAndFirstIsPrimary(t *testing.T) {
+	restaurant := insertTestRestaurant(t, RestaurantModel{DB: testDB})
+	dish := insertTestDish(t, DishModel{DB: testDB}, restaurant.ID)
+	model := GalleryModel{DB: testDB, gallery: dishGallery}
+
+	first := insertTestGalleryImage(t, model, dish.ID, false)
+	second := insertTestGalleryImage(t, model, dish.ID, false)
+
+	if !first.IsPrimary {
+		t.Error("the first image of a gallery should be primary")
+	}
+	if second.IsPrimary {
+		t.Error("later images should not be primary unless asked to")
+	}
+	if second.Position <= first.Position {
+		t.Errorf("second Position = %d, want after %d", second.Position, first.Position)
+	}
+
+	third := insertTestGalleryImage(t, model, dish.ID, true)
+	if got := primaryID(t, model, dish.ID); got != third.ID {
+		t.Errorf("primary image = %d, want %d", got, third.ID)
+	}
+}
+
+func TestGalleryModel_Insert_MissingOwner(t *testing.T) {
+	model := GalleryModel{DB: testDB, gallery: restaurantGallery}
+
+	err := model.Insert(t.Context(), &GalleryImage{OwnerID: 0, Key: "restaurants/0/gallery/x.jpg"})
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Insert() error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestGalleryModel_Insert_Full(t *testing.T) {
+	restaurant := insertTestRestaurant(t, RestaurantModel{DB: testDB})
+	model := GalleryModel{DB: testDB, gallery: restaurantGallery}
+
+	for range MaxGalleryImages {
+		insertTestGalleryImage(t, model, restaurant.ID, false)
+	}
+
+	err := model.Insert(t.Context(), &GalleryImage{OwnerID: restaurant.ID, Key: "restaurants/full.jpg"})
+	if !errors.Is(err, ErrGalleryFull) {
+		t.Errorf("Insert() error = %v, want ErrGalleryFull", err)
+	}
+}
+
+func TestGalleryModel_Get_OtherOwner(t *testing.T) {
+	restaurantModel := RestaurantModel{DB: testDB}
+	model := GalleryModel{DB: testDB, gallery: restaurantGallery}
+	owner := insertTestRestaurant(t, restaurantModel)
+	other := insertTestRestaurant(t, restaurantModel)
+	image := insertTestGalleryImage(t, model, owner.ID, false)
+
+	_, err := model.Get(t.Context(), image.ID, other.ID)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Get() error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestGalleryModel_Reorder(t *testing.T) {
+	restaurant := insertTestRestaurant(t, RestaurantModel{DB: testDB})
+	model := GalleryModel{DB: testDB, gallery: restaurantGallery}
+
+	a := insertTestGalleryImage(t, model, restaurant.ID, false)
+	b := insertTestGalleryImage(t, model, restaurant.ID, false)
+	c := insertTestGalleryImage(t, model, restaurant.ID, false)
+
+	want := []int64{c.ID, a.ID, b.ID}
+
+	if err := model.Reorder(t.Context(), restaurant.ID, want); err != nil {
+		t.Fatalf("Reorder() error = %v", err)
+	}
+
+	got := galleryIDs(t, model, restaurant.ID)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("order after Reorder() = %v, want %v", got, want)
+	}
+
+	mismatches := [][]int64{
+		{c.ID, a.ID},
+		{c.ID, a.ID, a.ID},
+		{c.ID, a.ID, b.ID, b.ID + 1000},
+	}
+
+	for _, ids := range mismatches {
+		if err := model.Reorder(t.Context(), restaurant.ID, ids); !errors.Is(err, ErrGalleryMismatch) {
+			t.Errorf("Reorder(%v) error = %v, want ErrGalleryMismatch", ids, err)
+		}
+	}
+}
+
+func TestGalleryModel_UpdatePrimary(t *testing.T) {
+	restaurant := insertTestRestaurant(t, RestaurantModel{DB: testDB})
+	model := GalleryModel{DB: testDB, gallery: restaurantGallery}
+
+	first := insertTestGalleryImage(t, model, restaurant.ID, false)
+	second := insertTestGalleryImage(t, model, restaurant.ID, false)
+
+	second.Caption = "Our terrace"
+	second.IsPrimary = true
+
+	if err := model.Update(t.Context(), second); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if got := primaryID(t, model, restaurant.ID); got != second.ID {
+		t.Errorf("primary image = %d, want %d", got, second.ID)
+	}
+
+	// saving the caption of a non-primary image leaves the primary alone
+	first.Caption = "Dining room"
+	first.IsPrimary = false
+
+	if err := model.Update(t.Context(), first); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	fetched, err := model.Get(t.Context(), first.ID, restaurant.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if fetched.Caption != "Dining room" || fetched.IsPrimary {
+		t.Errorf("Get() = %q (primary %t), want %q (primary false)", fetched.Caption, fetched.IsPrimary, "Dining room")
+	}
+	if got := primaryID(t, model, restaurant.ID); got != second.ID {
+		t.Errorf("primary image = %d, want %d", got, second.ID)
+	}
+}
+
+func TestGalleryModel_DeletePromotesNextPrimary(t *testing.T) {
+	restaurant := insertTestRestaurant(t, RestaurantModel{DB: testDB})
+	dish := insertTestDish(t, DishModel{DB: testDB}, restaurant.ID)
+	model := GalleryModel{DB: testDB, gallery: dishGallery}
+
+	first := insertTestGalleryImage(t, model, dish.ID, false)
+	second := insertTestGalleryImage(t, model, dish.ID, false)
+
+	if err := model.Delete(t.Context(), first.ID, dish.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if got := primaryID(t, model, dish.ID); got != second.ID {
+		t.Errorf("primary image = %d, want %d", got, second.ID)
+	}
+
+	if err := model.Delete(t.Context(), first.ID, dish.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("second Delete() error = %v, want ErrRecordNotFound", err)
+	}
+}
@@ -1,64 +1,138 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"time"
 )
 
+type AddressModelInterface interface {
+	Insert(ctx context.Context, address *Address) error
+	Get(ctx context.Context, id int64, userID int64) (*Address, error)
+	GetDefaultForUser(ctx context.Context, userID int64) (*Address, error)
+	GetAllForUser(ctx context.Context, userID int64) ([]*Address, error)
+	Update(ctx context.Context, address *Address) error
+	Delete(ctx context.Context, id int64, userID int64) error
+}
+
+type AnalyticsModelInterface interface {
+	GetForRestaurant(ctx context.Context, restaurantID int64, filters AnalyticsFilters) (*RestaurantAnalytics, error)
+}
+
+type AuditLogModelInterface interface {
+	Insert(ctx context.Context, entry *AuditEntry) error
+	GetAll(ctx context.Context, auditFilters AuditLogFilters, filters Filters) ([]*AuditEntry, Metadata, error)
+}
+
+type CourierLocationModelInterface interface {
+	Insert(ctx context.Context, location *CourierLocation, minInterval time.Duration) error
+	GetLatestForOrder(ctx context.Context, orderID int64) (*CourierLocation, error)
+	DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type DeliveryZoneModelInterface interface {
+	Insert(ctx context.Context, zone *DeliveryZone) error
+	Get(ctx context.Context, id int64, restaurantID int64) (*DeliveryZone, error)
+	GetAllForRestaurant(ctx context.Context, restaurantID int64) ([]*DeliveryZone, error)
+	Update(ctx context.Context, zone *DeliveryZone) error
+	Delete(ctx context.Context, id int64, restaurantID int64) error
+}
+
 type DishModelInterface interface {
-	Insert(dish *Dish) error
-	Get(id int64) (*Dish, error)
-	Update(dish *Dish) error
-	Delete(id int64) error
-	GetAllForRestaurant(restaurantID int64, name string, categories []string, available sql.NullBool, filters Filters) ([]*Dish, Metadata, error)
+	Insert(ctx context.Context, dish *Dish) error
+	Get(ctx context.Context, id int64) (*Dish, error)
+	Update(ctx context.Context, dish *Dish) error
+	Delete(ctx context.Context, id int64) error
+	GetPhotoKeysForRestaurant(ctx context.Context, restaurantID int64) ([]string, error)
+	GetAllForRestaurant(ctx context.Context, restaurantID int64, userID int64, name string, categories []string, available sql.NullBool, filters Filters) ([]*Dish, Metadata, error)
+	GetMenu(ctx context.Context, restaurantID int64) ([]*Dish, error)
+	ImportMenu(ctx context.Context, restaurantID int64, dishes []*Dish, dryRun bool) (*MenuImportResult, error)
+}
+
+type FavoriteModelInterface interface {
+	AddRestaurant(ctx context.Context, userID, restaurantID int64) error
+	RemoveRestaurant(ctx context.Context, userID, restaurantID int64) error
+	AddDish(ctx context.Context, userID, dishID int64) error
+	RemoveDish(ctx context.Context, userID, dishID int64) error
+	IsFavoriteRestaurant(ctx context.Context, userID, restaurantID int64) (bool, error)
+	IsFavoriteDish(ctx context.Context, userID, dishID int64) (bool, error)
+	GetRestaurantsForUser(ctx context.Context, userID int64, filters Filters) ([]*Restaurant, Metadata, error)
+	GetDishesForUser(ctx context.Context, userID int64, filters Filters) ([]*Dish, Metadata, error)
+}
+
+type GalleryModelInterface interface {
+	Insert(ctx context.Context, image *GalleryImage) error
+	Get(ctx context.Context, id int64, ownerID int64) (*GalleryImage, error)
+	GetAll(ctx context.Context, ownerID int64) ([]*GalleryImage, error)
+	Update(ctx context.Context, image *GalleryImage) error
+	Reorder(ctx context.Context, ownerID int64, ids []int64) error
+	Delete(ctx context.Context, id int64, ownerID int64) error
 }
 
 type OrderItemModelInterface interface {
-	Insert(orderItem *OrderItem) error
-	InsertFromDish(orderId int64, dish *Dish, quantity int) (*OrderItem, error)
-	Update(orderItem *OrderItem) error
-	GetForOrder(orderID int64) ([]*OrderItem, error)
-	DeleteForOrder(orderID int64) error
+	Insert(ctx context.Context, orderItem *OrderItem) error
+	InsertFromDish(ctx context.Context, orderId int64, dish *Dish, quantity int) (*OrderItem, error)
+	Update(ctx context.Context, orderItem *OrderItem) error
+	GetForOrder(ctx context.Context, orderID int64) ([]*OrderItem, error)
+	GetForOrders(ctx context.Context, orderIDs []int64) (map[int64][]*OrderItem, error)
+	DeleteForOrder(ctx context.Context, orderID int64) error
 }
 
 type OrderModelInterface interface {
-	Insert(order *Order) error
-	GetForRestaurant(id int64, restaurantID int64) (*Order, error)
-	GetForUser(id int64, userID int64) (*Order, error)
-	Update(order *Order) error
-	GetAllForRestaurant(restaurantID int64, status string, filters Filters) ([]*Order, Metadata, error)
-	GetAllForUser(userID int64, status string, filters Filters) ([]*Order, Metadata, error)
+	Insert(ctx context.Context, order *Order) error
+	GetForRestaurant(ctx context.Context, id int64, restaurantID int64) (*Order, error)
+	GetForUser(ctx context.Context, id int64, userID int64) (*Order, error)
+	Update(ctx context.Context, order *Order) error
+	GetAllForRestaurant(ctx context.Context, restaurantID int64, status string, fulfilmentType string, filters Filters) ([]*Order, Metadata, error)
+	GetAllForUser(ctx context.Context, userID int64, status string, filters Filters) ([]*Order, Metadata, error)
+	GetForCourier(ctx context.Context, id int64, courierID int64) (*Order, error)
+	GetAllForCourier(ctx context.Context, courierID int64, status string, filters Filters) ([]*Order, Metadata, error)
+	GetUnclaimed(ctx context.Context, filters Filters) ([]*Order, Metadata, error)
+	Claim(ctx context.Context, id int64, courierID int64) (*Order, error)
+	UpdateStatusForCourier(ctx context.Context, order *Order, courierID int64, from string) error
+	Export(ctx context.Context, restaurantID int64, status string, fulfilmentType string, from, to time.Time, fn func(*OrderExportRow) error) error
 }
 
 type PermissionModelInterface interface {
-	GetAllForUser(userId int64) (Permissions, error)
-	AddForUser(userId int64, codes ...string) error
-	DeleteForUser(userId int64, code string) error
+	GetAllForUser(ctx context.Context, userId int64) (Permissions, error)
+	AddForUser(ctx context.Context, userId int64, codes ...string) error
+	DeleteForUser(ctx context.Context, userId int64, code string) error
 }
 
 type RestaurantModelInterface interface {
-	Insert(restaurant *Restaurant) error
-	Get(id int64) (*Restaurant, error)
-	Update(restaurant *Restaurant) error
-	Delete(id int64) error
-	GetAll() ([]*Restaurant, error)
-	GetStaff(restaurantID int64) ([]*User, error)
-	AddStaff(restaurantID, userID int64, role string) error
-	RemoveStaff(restaurantID, userID int64) error
-	IsStaff(restaurantID, userID int64) (bool, error)
-	GetStaffRole(restaurantID, userID int64) (string, error)
+	Insert(ctx context.Context, restaurant *Restaurant) error
+	Get(ctx context.Context, id int64) (*Restaurant, error)
+	Update(ctx context.Context, restaurant *Restaurant) error
+	Delete(ctx context.Context, id int64) error
+	GetAll(ctx context.Context, userID int64) ([]*Restaurant, error)
+	GetStaff(ctx context.Context, restaurantID int64) ([]*User, error)
+	AddStaff(ctx context.Context, restaurantID, userID int64, role string) error
+	RemoveStaff(ctx context.Context, restaurantID, userID int64) error
+	IsStaff(ctx context.Context, restaurantID, userID int64) (bool, error)
+	GetStaffRole(ctx context.Context, restaurantID, userID int64) (string, error)
+}
+
+type ReviewModelInterface interface {
+	Insert(ctx context.Context, review *Review) error
+	Get(ctx context.Context, id int64) (*Review, error)
+	GetAll(ctx context.Context, restaurantID int64, hidden sql.NullBool, filters Filters) ([]*Review, Metadata, error)
+	Reply(ctx context.Context, id int64, restaurantID int64, reply string) (*Review, error)
+	Update(ctx context.Context, review *Review) error
 }
 
 type TokenModelInterface interface {
-	New(userID int64, ttl time.Duration, scope string) (*Token, error)
-	Insert(token *Token) error
-	DeleteAllForUser(scope string, userID int64) error
+	New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error)
+	Insert(ctx context.Context, token *Token) error
+	DeleteAllForUser(ctx context.Context, scope string, userID int64) error
 }
 
 type UserModelInterface interface {
-	Insert(user *User) error
-	GetByEmail(email string) (*User, error)
-	Update(user *User) error
-	GetForToken(tokenScope, tokenPlaintext string) (*User, error)
-	Get(id int64) (*User, error)
+	Insert(ctx context.Context, user *User) error
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	Update(ctx context.Context, user *User) error
+	UpdateRole(ctx context.Context, user *User, codes ...string) error
+	GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error)
+	Get(ctx context.Context, id int64) (*User, error)
+	GetAll(ctx context.Context, userFilters UserFilters, filters Filters) ([]*User, Metadata, error)
+	Anonymize(ctx context.Context, user *User) error
 }
@@ -0,0 +1,231 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MaxMenuImportRows caps how many dishes a single menu import may contain
+const MaxMenuImportRows = 1000
+
+// menuImportTimeout and menuExportTimeout bound a whole menu import and export,
+// which touch every dish of a restaurant, unless QueryTimeout is longer
+const (
+	menuImportTimeout = 30 * time.Second
+	menuExportTimeout = 10 * time.Second
+)
+
+// MenuItem is a dish in the portable menu format shared by import and export.
+// It leaves out ids, photos and ratings so menus can move between restaurants
+type MenuItem struct {
+	Name        string   `json:"name"`
+	Price       int64    `json:"price"`
+	Description string   `json:"description"`
+	Categories  []string `json:"categories"`
+	Available   *bool    `json:"available,omitempty"`
+}
+
+// Dish converts the item into a dish of the given restaurant. Items without an
+// availability are imported as available
+func (item MenuItem) Dish(restaurantID int64) *Dish {
+	available := true
+	if item.Available != nil {
+		available = *item.Available
+	}
+
+	return &Dish{
+		RestaurantID: restaurantID,
+		Name:         item.Name,
+		Price:        item.Price,
+		Description:  item.Description,
+		Categories:   item.Categories,
+		Available:    available,
+	}
+}
+
+func NewMenuItem(dish *Dish) MenuItem {
+	available := dish.Available
+
+	return MenuItem{
+		Name:        dish.Name,
+		Price:       dish.Price,
+		Description: dish.Description,
+		Categories:  dish.Categories,
+		Available:   &available,
+	}
+}
+
+type MenuImportResult struct {
+	Created int  `json:"created"`
+	Updated int  `json:"updated"`
+	DryRun  bool `json:"dry_run"`
+}
+
+// ImportMenu upserts dishes into a restaurant's menu by name in one transaction.
+// Existing dishes keep their id and photo. With dryRun the changes are made and
+// rolled back, so the counts reflect exactly what a real import would do
+func (d DishModel) ImportMenu(ctx context.Context, restaurantID int64, dishes []*Dish, dryRun bool) (*MenuImportResult, error) {
+	ctx, span := startSpan(ctx, "DishModel.ImportMenu")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, max(d.QueryTimeout, menuImportTimeout))
+	defer cancel()
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// locking the restaurant serialises imports, so two of them can't both
+	// create a dish with the same name
+	var id int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM restaurants WHERE id = $1 FOR UPDATE`, restaurantID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	existing, err := menuDishIDs(ctx, tx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MenuImportResult{DryRun: dryRun}
+
+	for _, dish := range dishes {
+		dish.RestaurantID = restaurantID
+
+		if id, ok := existing[dish.Name]; ok {
+			dish.ID = id
+
+			query := `
+				UPDATE dishes
+				SET price = $1, description = $2, categories = $3, available = $4
+				WHERE id = $5
+				RETURNING photo, updated_at`
+
+			args := []any{dish.Price, dish.Description, pq.Array(dish.Categories), dish.Available, dish.ID}
+
+			err = tx.QueryRowContext(ctx, query, args...).Scan(&dish.Photo, &dish.UpdatedAt)
+			if err != nil {
+				return nil, err
+			}
+
+			result.Updated++
+			continue
+		}
+
+		query := `
+			INSERT INTO dishes (restaurant_id, name, price, description, categories, available)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, updated_at`
+
+		args := []any{restaurantID, dish.Name, dish.Price, dish.Description, pq.Array(dish.Categories), dish.Available}
+
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&dish.ID, &dish.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		existing[dish.Name] = dish.ID
+		result.Created++
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	return result, tx.Commit()
+}
+
+// menuDishIDs maps the restaurant's dish names to ids. When a name is used by
+// more than one dish, the oldest one is updated
+func menuDishIDs(ctx context.Context, tx *sql.Tx, restaurantID int64) (map[string]int64, error) {
+	query := `
+		SELECT id, name
+		FROM dishes
+		WHERE restaurant_id = $1
+		ORDER BY id ASC`
+
+	rows, err := tx.QueryContext(ctx, query, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := map[string]int64{}
+
+	for rows.Next() {
+		var id int64
+		var name string
+
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+
+		if _, ok := ids[name]; !ok {
+			ids[name] = id
+		}
+	}
+
+	return ids, rows.Err()
+}
+
+// GetMenu returns every dish of a restaurant, ordered by name, for export
+func (d DishModel) GetMenu(ctx context.Context, restaurantID int64) ([]*Dish, error) {
+	query := `
+		SELECT id, restaurant_id, name, price, description, categories, photo, available, updated_at
+		FROM dishes
+		WHERE restaurant_id = $1
+		ORDER BY name ASC, id ASC`
+
+	ctx, span := startSpan(ctx, "DishModel.GetMenu")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, max(d.QueryTimeout, menuExportTimeout))
+	defer cancel()
+
+	rows, err := d.DB.QueryContext(ctx, query, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dishes := []*Dish{}
+
+	for rows.Next() {
+		var dish Dish
+
+		err := rows.Scan(
+			&dish.ID,
+			&dish.RestaurantID,
+			&dish.Name,
+			&dish.Price,
+			&dish.Description,
+			pq.Array(&dish.Categories),
+			&dish.Photo,
+			&dish.Available,
+			&dish.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		dishes = append(dishes, &dish)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dishes, nil
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestMenu(restaurantID int64) []*Dish {
+	unavailable := false
+
+	items := []MenuItem{
+		{Name: "Margherita", Price: 1299, Description: "Tomato and mozzarella", Categories: []string{"pizza"}},
+		{Name: "Tiramisu", Price: 650, Description: "Coffee dessert", Categories: []string{"dessert"}, Available: &unavailable},
+	}
+
+	dishes := make([]*Dish, len(items))
+	for i, item := range items {
+		dishes[i] = item.Dish(restaurantID)
+	}
+
+	return dishes
+}
+
+func TestMenuItem_Dish(t *testing.T) {
+	item := MenuItem{Name: "Soup", Price: 400, Description: "Hot", Categories: []string{"starter"}}
+
+	if dish := item.Dish(7); !dish.Available || dish.RestaurantID != 7 {
+		t.Errorf("Dish() = %+v, want an available dish of restaurant 7", dish)
+	}
+
+	unavailable := false
+	item.Available = &unavailable
+
+	if dish := item.Dish(7); dish.Available {
+		t.Error("Dish() should keep an explicit availability of false")
+	}
+}
+
+func TestDishModel_ImportMenu(t *testing.T) {
+	model := DishModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+
+	existing := newTestDish(restaurantID)
+	existing.Name = "Margherita"
+	existing.Photo = "images/dishes/margherita.jpg"
+	if err := model.Insert(t.Context(), existing); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	result, err := model.ImportMenu(t.Context(), restaurantID, newTestMenu(restaurantID), false)
+	if err != nil {
+		t.Fatalf("ImportMenu() error = %v", err)
+	}
+
+	if result.Created != 1 || result.Updated != 1 || result.DryRun {
+		t.Errorf("ImportMenu() = %+v, want 1 created and 1 updated", result)
+	}
+
+	updated, err := model.Get(t.Context(), existing.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if updated.Price != 1299 || updated.Description != "Tomato and mozzarella" {
+		t.Errorf("existing dish = %+v, want the imported price and description", updated)
+	}
+	if updated.Photo != existing.Photo {
+		t.Errorf("existing dish Photo = %q, want %q", updated.Photo, existing.Photo)
+	}
+
+	menu, err := model.GetMenu(t.Context(), restaurantID)
+	if err != nil {
+		t.Fatalf("GetMenu() error = %v", err)
+	}
+	if len(menu) != 2 {
+		t.Fatalf("GetMenu() returned %d dishes, want 2", len(menu))
+	}
+	if menu[1].Name != "Tiramisu" || menu[1].Available {
+		t.Errorf("GetMenu()[1] = %+v, want an unavailable Tiramisu", menu[1])
+	}
+}
+
+func TestDishModel_ImportMenu_DryRun(t *testing.T) {
+	model := DishModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+
+	result, err := model.ImportMenu(t.Context(), restaurantID, newTestMenu(restaurantID), true)
+	if err != nil {
+		t.Fatalf("ImportMenu() error = %v", err)
+	}
+
+	if result.Created != 2 || !result.DryRun {
+		t.Errorf("ImportMenu() = %+v, want 2 created in a dry run", result)
+	}
+
+	menu, err := model.GetMenu(t.Context(), restaurantID)
+	if err != nil {
+		t.Fatalf("GetMenu() error = %v", err)
+	}
+	if len(menu) != 0 {
+		t.Errorf("dry run left %d dishes behind, want 0", len(menu))
+	}
+}
+
+func TestDishModel_ImportMenu_RestaurantNotFound(t *testing.T) {
+	model := DishModel{DB: testDB}
+
+	_, err := model.ImportMenu(t.Context(), 999999, newTestMenu(999999), false)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("ImportMenu() error = %v, want ErrRecordNotFound", err)
+	}
+}
@@ -1,8 +1,10 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 )
 
 var (
@@ -11,23 +13,57 @@ var (
 )
 
 type Models struct {
-	Dishes      DishModelInterface
-	Users       UserModelInterface
-	Permissions PermissionModelInterface
-	Tokens      TokenModelInterface
-	Orders      OrderModelInterface
-	OrderItems  OrderItemModelInterface
-	Restaurants RestaurantModelInterface
+	Dishes           DishModelInterface
+	Users            UserModelInterface
+	Permissions      PermissionModelInterface
+	Tokens           TokenModelInterface
+	Orders           OrderModelInterface
+	OrderItems       OrderItemModelInterface
+	Restaurants      RestaurantModelInterface
+	CourierLocations CourierLocationModelInterface
+	Addresses        AddressModelInterface
+	DeliveryZones    DeliveryZoneModelInterface
+	Reviews          ReviewModelInterface
+	Favorites        FavoriteModelInterface
+	Analytics        AnalyticsModelInterface
+	DishImages       GalleryModelInterface
+	RestaurantImages GalleryModelInterface
+	AuditLog         AuditLogModelInterface
 }
 
-func NewModels(db *sql.DB) Models {
+// DefaultQueryTimeout bounds the queries of a model method when the model has no QueryTimeout
+const DefaultQueryTimeout = 3 * time.Second
+
+// withTimeout derives the context of a model method's queries from the caller's
+// context, so they also stop when the client disconnects or the server shuts down
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DefaultQueryTimeout
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// NewModels wires every model to db. queryTimeout bounds each model method; bulk
+// operations such as imports, exports and analytics get a longer limit of their
+// own unless queryTimeout exceeds it
+func NewModels(db *sql.DB, queryTimeout time.Duration) Models {
 	return Models{
-		Dishes:      DishModel{DB: db},
-		Users:       UserModel{DB: db},
-		Permissions: PermissionModel{DB: db},
-		Tokens:      TokenModel{DB: db},
-		Orders:      OrderModel{DB: db},
-		OrderItems:  OrderItemModel{DB: db},
-		Restaurants: RestaurantModel{DB: db},
+		Dishes:           DishModel{DB: db, QueryTimeout: queryTimeout},
+		Users:            UserModel{DB: db, QueryTimeout: queryTimeout},
+		Permissions:      PermissionModel{DB: db, QueryTimeout: queryTimeout},
+		Tokens:           TokenModel{DB: db, QueryTimeout: queryTimeout},
+		Orders:           OrderModel{DB: db, QueryTimeout: queryTimeout},
+		OrderItems:       OrderItemModel{DB: db, QueryTimeout: queryTimeout},
+		Restaurants:      RestaurantModel{DB: db, QueryTimeout: queryTimeout},
+		CourierLocations: CourierLocationModel{DB: db, QueryTimeout: queryTimeout},
+		Addresses:        AddressModel{DB: db, QueryTimeout: queryTimeout},
+		DeliveryZones:    DeliveryZoneModel{DB: db, QueryTimeout: queryTimeout},
+		Reviews:          ReviewModel{DB: db, QueryTimeout: queryTimeout},
+		Favorites:        FavoriteModel{DB: db, QueryTimeout: queryTimeout},
+		Analytics:        AnalyticsModel{DB: db, QueryTimeout: queryTimeout},
+		DishImages:       GalleryModel{DB: db, QueryTimeout: queryTimeout, gallery: dishGallery},
+		RestaurantImages: GalleryModel{DB: db, QueryTimeout: queryTimeout, gallery: restaurantGallery},
+		AuditLog:         AuditLogModel{DB: db, QueryTimeout: queryTimeout},
 	}
 }
@@ -6,13 +6,14 @@ import (
 	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/xtommas/food-backend/internal/validator"
 )
 
 type OrderItem struct {
 	ID        int64  `json:"id"`
 	OrderID   int64  `json:"order_id"`
-	DishID    int64  `json:"dish_id"`
+	DishID    int64  `json:"dish_id,omitempty"`
 	DishName  string `json:"dish_name"`
 	UnitPrice int64  `json:"unit_price"`
 	Quantity  int    `json:"quantity"`
@@ -28,12 +29,13 @@ func ValidateOrderItem(v *validator.Validator, item *OrderItem) {
 }
 
 type OrderItemModel struct {
-	DB *sql.DB
+	DB           *sql.DB
+	QueryTimeout time.Duration
 }
 
 // Insert snapshots the dish name and unit price at the time of the order,
 // so order history remains accurate even if the dish is later changed or deleted.
-func (i OrderItemModel) Insert(orderItem *OrderItem) error {
+func (i OrderItemModel) Insert(ctx context.Context, orderItem *OrderItem) error {
 	query := `
 		INSERT INTO order_items (order_id, dish_id, dish_name, unit_price, quantity, subtotal)
 		VALUES ($1, $2, $3, $4, $5, $6)
@@ -48,13 +50,16 @@ func (i OrderItemModel) Insert(orderItem *OrderItem) error {
 		orderItem.Subtotal,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "OrderItemModel.Insert")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, i.QueryTimeout)
 	defer cancel()
 
 	return i.DB.QueryRowContext(ctx, query, args...).Scan(&orderItem.ID)
 }
 
-func (i OrderItemModel) InsertFromDish(orderID int64, dish *Dish, quantity int) (*OrderItem, error) {
+func (i OrderItemModel) InsertFromDish(ctx context.Context, orderID int64, dish *Dish, quantity int) (*OrderItem, error) {
 	item := &OrderItem{
 		OrderID:   orderID,
 		DishID:    dish.ID,
@@ -64,7 +69,7 @@ func (i OrderItemModel) InsertFromDish(orderID int64, dish *Dish, quantity int)
 		Subtotal:  dish.Price * int64(quantity),
 	}
 
-	err := i.Insert(item)
+	err := i.Insert(ctx, item)
 	if err != nil {
 		return nil, err
 	}
@@ -72,13 +77,16 @@ func (i OrderItemModel) InsertFromDish(orderID int64, dish *Dish, quantity int)
 	return item, nil
 }
 
-func (i OrderItemModel) Update(orderItem *OrderItem) error {
+func (i OrderItemModel) Update(ctx context.Context, orderItem *OrderItem) error {
 	query := `
 		UPDATE order_items
 		SET quantity = $1, subtotal = $2
 		WHERE id = $3`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "OrderItemModel.Update")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, i.QueryTimeout)
 	defer cancel()
 
 	result, err := i.DB.ExecContext(ctx, query, orderItem.Quantity, orderItem.Subtotal, orderItem.ID)
@@ -103,13 +111,17 @@ func (i OrderItemModel) Update(orderItem *OrderItem) error {
 	return nil
 }
 
-func (i OrderItemModel) GetForOrder(orderID int64) ([]*OrderItem, error) {
+func (i OrderItemModel) GetForOrder(ctx context.Context, orderID int64) ([]*OrderItem, error) {
 	query := `
-		SELECT id, order_id, dish_id, dish_name, unit_price, quantity, subtotal
+		SELECT id, order_id, COALESCE(dish_id, 0), dish_name, unit_price, quantity, subtotal
 		FROM order_items
-		WHERE order_id = $1`
+		WHERE order_id = $1
+		ORDER BY id`
+
+	ctx, span := startSpan(ctx, "OrderItemModel.GetForOrder")
+	defer span.End()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withTimeout(ctx, i.QueryTimeout)
 	defer cancel()
 
 	rows, err := i.DB.QueryContext(ctx, query, orderID)
@@ -146,10 +158,68 @@ func (i OrderItemModel) GetForOrder(orderID int64) ([]*OrderItem, error) {
 	return orderItems, nil
 }
 
-func (i OrderItemModel) DeleteForOrder(orderID int64) error {
+// GetForOrders loads the items of many orders with a single query. Items carry
+// the dish name and unit price snapshotted when they were added, so dishes that
+// were changed or deleted since don't affect them. Orders without items are
+// missing from the result
+func (i OrderItemModel) GetForOrders(ctx context.Context, orderIDs []int64) (map[int64][]*OrderItem, error) {
+	itemsForOrders := make(map[int64][]*OrderItem, len(orderIDs))
+
+	if len(orderIDs) == 0 {
+		return itemsForOrders, nil
+	}
+
+	query := `
+		SELECT id, order_id, COALESCE(dish_id, 0), dish_name, unit_price, quantity, subtotal
+		FROM order_items
+		WHERE order_id = ANY($1)
+		ORDER BY order_id, id`
+
+	ctx, span := startSpan(ctx, "OrderItemModel.GetForOrders")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, i.QueryTimeout)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, pq.Array(orderIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item OrderItem
+
+		err := rows.Scan(
+			&item.ID,
+			&item.OrderID,
+			&item.DishID,
+			&item.DishName,
+			&item.UnitPrice,
+			&item.Quantity,
+			&item.Subtotal,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		itemsForOrders[item.OrderID] = append(itemsForOrders[item.OrderID], &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return itemsForOrders, nil
+}
+
+func (i OrderItemModel) DeleteForOrder(ctx context.Context, orderID int64) error {
 	query := `DELETE FROM order_items WHERE order_id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "OrderItemModel.DeleteForOrder")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, i.QueryTimeout)
 	defer cancel()
 
 	_, err := i.DB.ExecContext(ctx, query, orderID)
@@ -20,7 +20,7 @@ func TestOrderItemModel_Insert(t *testing.T) {
 		Subtotal:  dish.Price * 2,
 	}
 
-	if err := itemModel.Insert(item); err != nil {
+	if err := itemModel.Insert(t.Context(), item); err != nil {
 		t.Fatalf("Insert() error = %v", err)
 	}
 
@@ -38,7 +38,7 @@ func TestOrderItemModel_InsertFromDish(t *testing.T) {
 	order := insertTestOrder(t, orderModel, user.Id, restaurantID)
 	dish := insertTestDish(t, DishModel{DB: testDB}, restaurantID)
 
-	item, err := itemModel.InsertFromDish(order.ID, dish, 3)
+	item, err := itemModel.InsertFromDish(t.Context(), order.ID, dish, 3)
 	if err != nil {
 		t.Fatalf("InsertFromDish() error = %v", err)
 	}
@@ -65,12 +65,12 @@ func TestOrderItemModel_GetForOrder(t *testing.T) {
 	user := insertTestUser(t, userModel)
 	order := insertTestOrder(t, orderModel, user.Id, restaurantID)
 	dish := insertTestDish(t, DishModel{DB: testDB}, restaurantID)
-	item, err := itemModel.InsertFromDish(order.ID, dish, 2)
+	item, err := itemModel.InsertFromDish(t.Context(), order.ID, dish, 2)
 	if err != nil {
 		t.Fatalf("InsertFromDish() error = %v", err)
 	}
 
-	items, err := itemModel.GetForOrder(order.ID)
+	items, err := itemModel.GetForOrder(t.Context(), order.ID)
 	if err != nil {
 		t.Fatalf("GetForOrder() error = %v", err)
 	}
@@ -91,18 +91,18 @@ func TestOrderItemModel_Update(t *testing.T) {
 	user := insertTestUser(t, userModel)
 	order := insertTestOrder(t, orderModel, user.Id, restaurantID)
 	dish := insertTestDish(t, DishModel{DB: testDB}, restaurantID)
-	item, err := itemModel.InsertFromDish(order.ID, dish, 2)
+	item, err := itemModel.InsertFromDish(t.Context(), order.ID, dish, 2)
 	if err != nil {
 		t.Fatalf("InsertFromDish() error = %v", err)
 	}
 
 	item.Quantity = 5
 	item.Subtotal = item.UnitPrice * 5
-	if err := itemModel.Update(item); err != nil {
+	if err := itemModel.Update(t.Context(), item); err != nil {
 		t.Fatalf("Update() error = %v", err)
 	}
 
-	items, err := itemModel.GetForOrder(order.ID)
+	items, err := itemModel.GetForOrder(t.Context(), order.ID)
 	if err != nil {
 		t.Fatalf("GetForOrder() after Update() error = %v", err)
 	}
@@ -118,7 +118,7 @@ func TestOrderItemModel_Update_NotFound(t *testing.T) {
 	model := OrderItemModel{DB: testDB}
 	item := &OrderItem{ID: 999999, Quantity: 1, Subtotal: 100}
 
-	err := model.Update(item)
+	err := model.Update(t.Context(), item)
 	if err != ErrRecordNotFound {
 		t.Errorf("Update() error = %v, want ErrRecordNotFound", err)
 	}
@@ -132,15 +132,15 @@ func TestOrderItemModel_DeleteForOrder(t *testing.T) {
 	user := insertTestUser(t, userModel)
 	order := insertTestOrder(t, orderModel, user.Id, restaurantID)
 	dish := insertTestDish(t, DishModel{DB: testDB}, restaurantID)
-	if _, err := itemModel.InsertFromDish(order.ID, dish, 2); err != nil {
+	if _, err := itemModel.InsertFromDish(t.Context(), order.ID, dish, 2); err != nil {
 		t.Fatalf("InsertFromDish() error = %v", err)
 	}
 
-	if err := itemModel.DeleteForOrder(order.ID); err != nil {
+	if err := itemModel.DeleteForOrder(t.Context(), order.ID); err != nil {
 		t.Fatalf("DeleteForOrder() error = %v", err)
 	}
 
-	items, err := itemModel.GetForOrder(order.ID)
+	items, err := itemModel.GetForOrder(t.Context(), order.ID)
 	if err != nil {
 		t.Fatalf("GetForOrder() after DeleteForOrder() error = %v", err)
 	}
@@ -161,3 +161,83 @@ func TestCalculateTotal(t *testing.T) {
 		t.Errorf("CalculateTotal() = %d, want 750", total)
 	}
 }
+
+func TestOrderItemModel_GetForOrders(t *testing.T) {
+	userModel := UserModel{DB: testDB}
+	orderModel := OrderModel{DB: testDB}
+	itemModel := OrderItemModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	user := insertTestUser(t, userModel)
+	first := insertTestOrder(t, orderModel, user.Id, restaurantID)
+	second := insertTestOrder(t, orderModel, user.Id, restaurantID)
+	empty := insertTestOrder(t, orderModel, user.Id, restaurantID)
+	dish := insertTestDish(t, DishModel{DB: testDB}, restaurantID)
+
+	for _, order := range []*Order{first, first, second} {
+		if _, err := itemModel.InsertFromDish(t.Context(), order.ID, dish, 1); err != nil {
+			t.Fatalf("InsertFromDish() error = %v", err)
+		}
+	}
+
+	itemsForOrders, err := itemModel.GetForOrders(t.Context(), []int64{first.ID, second.ID, empty.ID})
+	if err != nil {
+		t.Fatalf("GetForOrders() error = %v", err)
+	}
+
+	if len(itemsForOrders[first.ID]) != 2 {
+		t.Errorf("GetForOrders() returned %d items for the first order, want 2", len(itemsForOrders[first.ID]))
+	}
+	if len(itemsForOrders[second.ID]) != 1 {
+		t.Errorf("GetForOrders() returned %d items for the second order, want 1", len(itemsForOrders[second.ID]))
+	}
+	if len(itemsForOrders[empty.ID]) != 0 {
+		t.Errorf("GetForOrders() returned %d items for the empty order, want 0", len(itemsForOrders[empty.ID]))
+	}
+}
+
+func TestOrderItemModel_GetForOrders_DeletedDish(t *testing.T) {
+	userModel := UserModel{DB: testDB}
+	orderModel := OrderModel{DB: testDB}
+	itemModel := OrderItemModel{DB: testDB}
+	dishModel := DishModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	user := insertTestUser(t, userModel)
+	order := insertTestOrder(t, orderModel, user.Id, restaurantID)
+	dish := insertTestDish(t, dishModel, restaurantID)
+
+	if _, err := itemModel.InsertFromDish(t.Context(), order.ID, dish, 2); err != nil {
+		t.Fatalf("InsertFromDish() error = %v", err)
+	}
+
+	if err := dishModel.Delete(t.Context(), dish.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	itemsForOrders, err := itemModel.GetForOrders(t.Context(), []int64{order.ID})
+	if err != nil {
+		t.Fatalf("GetForOrders() error = %v", err)
+	}
+
+	items := itemsForOrders[order.ID]
+	if len(items) != 1 {
+		t.Fatalf("GetForOrders() returned %d items after the dish was deleted, want 1", len(items))
+	}
+	if items[0].DishName != dish.Name {
+		t.Errorf("GetForOrders() DishName = %q, want the snapshotted %q", items[0].DishName, dish.Name)
+	}
+	if items[0].DishID != 0 {
+		t.Errorf("GetForOrders() DishID = %d, want 0 for a deleted dish", items[0].DishID)
+	}
+}
+
+func TestOrderItemModel_GetForOrders_NoOrders(t *testing.T) {
+	model := OrderItemModel{DB: testDB}
+
+	itemsForOrders, err := model.GetForOrders(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("GetForOrders() error = %v", err)
+	}
+	if len(itemsForOrders) != 0 {
+		t.Errorf("GetForOrders() returned %d orders, want 0", len(itemsForOrders))
+	}
+}
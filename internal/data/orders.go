@@ -6,86 +6,222 @@ import (
 	"errors"
 	"fmt"
 	"time"
+	"unicode/utf8"
 
 	"github.com/xtommas/food-backend/internal/validator"
 )
 
 type Order struct {
-	ID           int64     `json:"id"`
-	UserID       int64     `json:"user_id"`
-	RestaurantID int64     `json:"restaurant_id"`
-	Total        int64     `json:"total"`
-	Address      string    `json:"address"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Status       string    `json:"status"`
+	ID                   int64     `json:"id"`
+	UserID               int64     `json:"user_id"`
+	RestaurantID         int64     `json:"restaurant_id"`
+	Total                int64     `json:"total"`
+	FulfilmentType       string    `json:"fulfilment_type"`
+	CourierID            int64     `json:"courier_id,omitempty"`
+	Address              string    `json:"address,omitempty"`
+	DeliveryInstructions string    `json:"delivery_instructions,omitempty"`
+	TableNumber          int       `json:"table_number,omitempty"`
+	Latitude             float64   `json:"latitude,omitempty"`
+	Longitude            float64   `json:"longitude,omitempty"`
+	HasDestination       bool      `json:"-"`
+	DeliveryFee          int64     `json:"delivery_fee,omitempty"`
+	MinimumOrder         int64     `json:"minimum_order,omitempty"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+	Status               string    `json:"status"`
 }
 
-var validStatuses = []string{"pending", "confirmed", "preparing", "ready", "delivered", "cancelled"}
+const (
+	FulfilmentDelivery = "delivery"
+	FulfilmentPickup   = "pickup"
+	FulfilmentDineIn   = "dine_in"
+)
+
+var validFulfilmentTypes = []string{FulfilmentDelivery, FulfilmentPickup, FulfilmentDineIn}
+
+var ErrOrderAlreadyClaimed = errors.New("order already claimed")
+
+// actors that can move an order from one status to another
+const (
+	ActorStaff   = "staff"
+	ActorCourier = "courier"
+)
+
+var validStatuses = []string{"pending", "confirmed", "preparing", "ready", "out_for_delivery", "delivered", "picked_up", "served", "cancelled"}
+
+// each fulfilment type has its own final step once the order is ready:
+// delivery orders are taken out and delivered by a courier, pickup orders are picked up and dine-in orders are served
+var validTransitions = map[string]map[string][]string{
+	FulfilmentDelivery: {
+		"pending":          {"confirmed", "cancelled"},
+		"confirmed":        {"preparing", "cancelled"},
+		"preparing":        {"ready"},
+		"ready":            {"out_for_delivery"},
+		"out_for_delivery": {"delivered"},
+		"delivered":        {},
+		"cancelled":        {},
+	},
+	FulfilmentPickup: {
+		"pending":   {"confirmed", "cancelled"},
+		"confirmed": {"preparing", "cancelled"},
+		"preparing": {"ready"},
+		"ready":     {"picked_up"},
+		"picked_up": {},
+		"cancelled": {},
+	},
+	FulfilmentDineIn: {
+		"pending":   {"confirmed", "cancelled"},
+		"confirmed": {"preparing", "cancelled"},
+		"preparing": {"ready"},
+		"ready":     {"served"},
+		"served":    {},
+		"cancelled": {},
+	},
+}
+
+// the actor allowed to move an order into each status. Statuses that are not
+// listed are set by restaurant staff
+var statusActors = map[string]string{
+	"out_for_delivery": ActorCourier,
+	"delivered":        ActorCourier,
+}
 
-var validTransitions = map[string][]string{
-	"pending":   {"confirmed", "cancelled"},
-	"confirmed": {"preparing", "cancelled"},
-	"preparing": {"ready"},
-	"ready":     {"delivered"},
-	"delivered": {},
-	"cancelled": {},
+// IsClosed reports whether the order has reached a status with no further transitions
+func (o *Order) IsClosed() bool {
+	next, ok := validTransitions[o.FulfilmentType][o.Status]
+	return ok && len(next) == 0
+}
+
+// IsCompleted reports whether the order reached the customer, as opposed to being cancelled
+func (o *Order) IsCompleted() bool {
+	return o.IsClosed() && o.Status != "cancelled"
 }
 
 func ValidateAddress(v *validator.Validator, address string) {
 	v.Check(address != "", "address", "must be provided")
 }
 
+func ValidateFulfilmentType(v *validator.Validator, fulfilmentType string) {
+	v.Check(fulfilmentType != "", "fulfilment_type", "must be provided")
+	v.Check(validator.PermittedValue(fulfilmentType, validFulfilmentTypes...), "fulfilment_type", "must be one of delivery, pickup or dine_in")
+}
+
+func ValidateFulfilment(v *validator.Validator, order *Order) {
+	ValidateFulfilmentType(v, order.FulfilmentType)
+
+	switch order.FulfilmentType {
+	case FulfilmentDelivery:
+		ValidateAddress(v, order.Address)
+	case FulfilmentDineIn:
+		v.Check(order.TableNumber > 0, "table_number", "must be provided for dine-in orders")
+	}
+
+	if order.FulfilmentType != FulfilmentDineIn {
+		v.Check(order.TableNumber == 0, "table_number", "must only be provided for dine-in orders")
+	}
+
+	if order.FulfilmentType != FulfilmentDelivery {
+		v.Check(order.Address == "", "address", "must only be provided for delivery orders")
+		v.Check(!order.HasDestination, "latitude", "must only be provided for delivery orders")
+	}
+
+	v.Check(utf8.RuneCountInString(order.DeliveryInstructions) <= 280, "delivery_instructions", "must be no more than 280 characters long")
+
+	if order.HasDestination {
+		v.Check(order.Latitude >= -90 && order.Latitude <= 90, "latitude", "must be between -90 and 90")
+		v.Check(order.Longitude >= -180 && order.Longitude <= 180, "longitude", "must be between -180 and 180")
+	}
+}
+
 func ValidateStatus(v *validator.Validator, status string) {
 	v.Check(status != "", "status", "must be provided")
 	v.Check(validator.PermittedValue(status, validStatuses...), "status", "invalid status")
 }
 
-func ValidateStatusTransition(v *validator.Validator, from, to string) {
-	allowed, ok := validTransitions[from]
+func ValidateStatusTransition(v *validator.Validator, fulfilmentType, actor, from, to string) {
+	allowed, ok := validTransitions[fulfilmentType][from]
 	if !ok {
 		v.AddError("status", "current status is unrecognised")
 		return
 	}
 	v.Check(validator.PermittedValue(to, allowed...), "status", "invalid transition from "+from+" to "+to)
+
+	required, ok := statusActors[to]
+	if !ok {
+		required = ActorStaff
+	}
+	v.Check(actor == required, "status", "only "+required+" can change the status to "+to)
+}
+
+// ValidateMinimumOrder checks that a delivery order has reached the minimum order of
+// its delivery zone. Items are added after the order is created, so this is checked
+// when the restaurant confirms the order rather than on creation
+func ValidateMinimumOrder(v *validator.Validator, order *Order) {
+	v.Check(order.Total >= order.MinimumOrder, "total", fmt.Sprintf("must be at least %d to meet the delivery zone's minimum order", order.MinimumOrder))
 }
 
 func ValidateOrder(v *validator.Validator, order *Order) {
-	ValidateAddress(v, order.Address)
+	ValidateFulfilment(v, order)
 	ValidateStatus(v, order.Status)
+
+	if transitions, ok := validTransitions[order.FulfilmentType]; ok {
+		_, known := transitions[order.Status]
+		v.Check(known, "status", "invalid status for "+order.FulfilmentType+" orders")
+	}
 }
 
 type OrderModel struct {
-	DB *sql.DB
+	DB           *sql.DB
+	QueryTimeout time.Duration
 }
 
-func (o OrderModel) Insert(order *Order) error {
+func (o OrderModel) Insert(ctx context.Context, order *Order) error {
 	query := `
-		INSERT INTO orders (user_id, restaurant_id, total, address, status)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO orders (user_id, restaurant_id, total, fulfilment_type, address, delivery_instructions, table_number, latitude, longitude, has_destination, delivery_fee, minimum_order, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		RETURNING id, created_at, updated_at`
 
-	args := []any{order.UserID, order.RestaurantID, order.Total, order.Address, order.Status}
+	args := []any{
+		order.UserID,
+		order.RestaurantID,
+		order.Total,
+		order.FulfilmentType,
+		order.Address,
+		order.DeliveryInstructions,
+		order.TableNumber,
+		order.Latitude,
+		order.Longitude,
+		order.HasDestination,
+		order.DeliveryFee,
+		order.MinimumOrder,
+		order.Status,
+	}
+
+	ctx, span := startSpan(ctx, "OrderModel.Insert")
+	defer span.End()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withTimeout(ctx, o.QueryTimeout)
 	defer cancel()
 
 	return o.DB.QueryRowContext(ctx, query, args...).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
 }
 
-func (o OrderModel) GetForRestaurant(id int64, restaurantID int64) (*Order, error) {
+func (o OrderModel) GetForRestaurant(ctx context.Context, id int64, restaurantID int64) (*Order, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT id, user_id, restaurant_id, total, address, created_at, updated_at, status
+		SELECT id, user_id, restaurant_id, total, fulfilment_type, COALESCE(courier_id, 0), address, delivery_instructions, table_number, latitude, longitude, has_destination, delivery_fee, minimum_order, created_at, updated_at, status
 		FROM orders
 		WHERE id = $1 AND restaurant_id = $2`
 
 	var order Order
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "OrderModel.GetForRestaurant")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, o.QueryTimeout)
 	defer cancel()
 
 	err := o.DB.QueryRowContext(ctx, query, id, restaurantID).Scan(
@@ -93,7 +229,16 @@ func (o OrderModel) GetForRestaurant(id int64, restaurantID int64) (*Order, erro
 		&order.UserID,
 		&order.RestaurantID,
 		&order.Total,
+		&order.FulfilmentType,
+		&order.CourierID,
 		&order.Address,
+		&order.DeliveryInstructions,
+		&order.TableNumber,
+		&order.Latitude,
+		&order.Longitude,
+		&order.HasDestination,
+		&order.DeliveryFee,
+		&order.MinimumOrder,
 		&order.CreatedAt,
 		&order.UpdatedAt,
 		&order.Status,
@@ -110,19 +255,22 @@ func (o OrderModel) GetForRestaurant(id int64, restaurantID int64) (*Order, erro
 	return &order, nil
 }
 
-func (o OrderModel) GetForUser(id int64, userID int64) (*Order, error) {
+func (o OrderModel) GetForUser(ctx context.Context, id int64, userID int64) (*Order, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT id, user_id, restaurant_id, total, address, created_at, updated_at, status
+		SELECT id, user_id, restaurant_id, total, fulfilment_type, COALESCE(courier_id, 0), address, delivery_instructions, table_number, latitude, longitude, has_destination, delivery_fee, minimum_order, created_at, updated_at, status
 		FROM orders
 		WHERE id = $1 AND user_id = $2`
 
 	var order Order
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "OrderModel.GetForUser")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, o.QueryTimeout)
 	defer cancel()
 
 	err := o.DB.QueryRowContext(ctx, query, id, userID).Scan(
@@ -130,7 +278,16 @@ func (o OrderModel) GetForUser(id int64, userID int64) (*Order, error) {
 		&order.UserID,
 		&order.RestaurantID,
 		&order.Total,
+		&order.FulfilmentType,
+		&order.CourierID,
 		&order.Address,
+		&order.DeliveryInstructions,
+		&order.TableNumber,
+		&order.Latitude,
+		&order.Longitude,
+		&order.HasDestination,
+		&order.DeliveryFee,
+		&order.MinimumOrder,
 		&order.CreatedAt,
 		&order.UpdatedAt,
 		&order.Status,
@@ -147,14 +304,17 @@ func (o OrderModel) GetForUser(id int64, userID int64) (*Order, error) {
 	return &order, nil
 }
 
-func (o OrderModel) Update(order *Order) error {
+func (o OrderModel) Update(ctx context.Context, order *Order) error {
 	query := `
 		UPDATE orders
 		SET total = $1, status = $2
 		WHERE id = $3
 		RETURNING updated_at`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "OrderModel.Update")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, o.QueryTimeout)
 	defer cancel()
 
 	err := o.DB.QueryRowContext(ctx, query, order.Total, order.Status, order.ID).Scan(&order.UpdatedAt)
@@ -170,19 +330,53 @@ func (o OrderModel) Update(order *Order) error {
 	return nil
 }
 
-func (o OrderModel) GetAllForRestaurant(restaurantID int64, status string, filters Filters) ([]*Order, Metadata, error) {
+// UpdateStatusForCourier moves an order assigned to the courier from one
+// status to order.Status. The write only happens while the order is still in
+// the from status, so a change made by the restaurant or the customer since
+// the order was read isn't overwritten and ErrEditConflict is returned instead
+func (o OrderModel) UpdateStatusForCourier(ctx context.Context, order *Order, courierID int64, from string) error {
+	query := `
+		UPDATE orders
+		SET status = $1
+		WHERE id = $2 AND courier_id = $3 AND status = $4
+		RETURNING updated_at`
+
+	ctx, span := startSpan(ctx, "OrderModel.UpdateStatusForCourier")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, o.QueryTimeout)
+	defer cancel()
+
+	err := o.DB.QueryRowContext(ctx, query, order.Status, order.ID, courierID, from).Scan(&order.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o OrderModel) GetAllForRestaurant(ctx context.Context, restaurantID int64, status string, fulfilmentType string, filters Filters) ([]*Order, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT COUNT(*) OVER(), id, user_id, restaurant_id, total, address, created_at, updated_at, status
+		SELECT COUNT(*) OVER(), id, user_id, restaurant_id, total, fulfilment_type, COALESCE(courier_id, 0), address, delivery_instructions, table_number, latitude, longitude, has_destination, delivery_fee, minimum_order, created_at, updated_at, status
 		FROM orders
 		WHERE restaurant_id = $1
 		AND (status = $2 OR $2 = '')
+		AND (fulfilment_type = $3 OR $3 = '')
 		ORDER BY %s %s, id ASC
-		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, span := startSpan(ctx, "OrderModel.GetAllForRestaurant")
+	defer span.End()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withTimeout(ctx, o.QueryTimeout)
 	defer cancel()
 
-	rows, err := o.DB.QueryContext(ctx, query, restaurantID, status, filters.limit(), filters.offset())
+	rows, err := o.DB.QueryContext(ctx, query, restaurantID, status, fulfilmentType, filters.limit(), filters.offset())
 	if err != nil {
 		return nil, Metadata{}, err
 	}
@@ -200,7 +394,16 @@ func (o OrderModel) GetAllForRestaurant(restaurantID int64, status string, filte
 			&order.UserID,
 			&order.RestaurantID,
 			&order.Total,
+			&order.FulfilmentType,
+			&order.CourierID,
 			&order.Address,
+			&order.DeliveryInstructions,
+			&order.TableNumber,
+			&order.Latitude,
+			&order.Longitude,
+			&order.HasDestination,
+			&order.DeliveryFee,
+			&order.MinimumOrder,
 			&order.CreatedAt,
 			&order.UpdatedAt,
 			&order.Status,
@@ -221,16 +424,19 @@ func (o OrderModel) GetAllForRestaurant(restaurantID int64, status string, filte
 	return orders, metadata, nil
 }
 
-func (o OrderModel) GetAllForUser(userID int64, status string, filters Filters) ([]*Order, Metadata, error) {
+func (o OrderModel) GetAllForUser(ctx context.Context, userID int64, status string, filters Filters) ([]*Order, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT COUNT(*) OVER(), id, user_id, restaurant_id, total, address, created_at, updated_at, status
+		SELECT COUNT(*) OVER(), id, user_id, restaurant_id, total, fulfilment_type, COALESCE(courier_id, 0), address, delivery_instructions, table_number, latitude, longitude, has_destination, delivery_fee, minimum_order, created_at, updated_at, status
 		FROM orders
 		WHERE user_id = $1
 		AND (status = $2 OR $2 = '')
 		ORDER BY %s %s, id ASC
 		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "OrderModel.GetAllForUser")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, o.QueryTimeout)
 	defer cancel()
 
 	rows, err := o.DB.QueryContext(ctx, query, userID, status, filters.limit(), filters.offset())
@@ -251,7 +457,193 @@ func (o OrderModel) GetAllForUser(userID int64, status string, filters Filters)
 			&order.UserID,
 			&order.RestaurantID,
 			&order.Total,
+			&order.FulfilmentType,
+			&order.CourierID,
+			&order.Address,
+			&order.DeliveryInstructions,
+			&order.TableNumber,
+			&order.Latitude,
+			&order.Longitude,
+			&order.HasDestination,
+			&order.DeliveryFee,
+			&order.MinimumOrder,
+			&order.CreatedAt,
+			&order.UpdatedAt,
+			&order.Status,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		orders = append(orders, &order)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return orders, metadata, nil
+}
+
+func (o OrderModel) GetForCourier(ctx context.Context, id int64, courierID int64) (*Order, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, user_id, restaurant_id, total, fulfilment_type, COALESCE(courier_id, 0), address, delivery_instructions, table_number, latitude, longitude, has_destination, delivery_fee, minimum_order, created_at, updated_at, status
+		FROM orders
+		WHERE id = $1 AND courier_id = $2`
+
+	var order Order
+
+	ctx, span := startSpan(ctx, "OrderModel.GetForCourier")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, o.QueryTimeout)
+	defer cancel()
+
+	err := o.DB.QueryRowContext(ctx, query, id, courierID).Scan(
+		&order.ID,
+		&order.UserID,
+		&order.RestaurantID,
+		&order.Total,
+		&order.FulfilmentType,
+		&order.CourierID,
+		&order.Address,
+		&order.DeliveryInstructions,
+		&order.TableNumber,
+		&order.Latitude,
+		&order.Longitude,
+		&order.HasDestination,
+		&order.DeliveryFee,
+		&order.MinimumOrder,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&order.Status,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &order, nil
+}
+
+func (o OrderModel) GetAllForCourier(ctx context.Context, courierID int64, status string, filters Filters) ([]*Order, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), id, user_id, restaurant_id, total, fulfilment_type, COALESCE(courier_id, 0), address, delivery_instructions, table_number, latitude, longitude, has_destination, delivery_fee, minimum_order, created_at, updated_at, status
+		FROM orders
+		WHERE courier_id = $1
+		AND (status = $2 OR $2 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, span := startSpan(ctx, "OrderModel.GetAllForCourier")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, o.QueryTimeout)
+	defer cancel()
+
+	rows, err := o.DB.QueryContext(ctx, query, courierID, status, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	var orders []*Order
+
+	for rows.Next() {
+		var order Order
+
+		err := rows.Scan(
+			&totalRecords,
+			&order.ID,
+			&order.UserID,
+			&order.RestaurantID,
+			&order.Total,
+			&order.FulfilmentType,
+			&order.CourierID,
+			&order.Address,
+			&order.DeliveryInstructions,
+			&order.TableNumber,
+			&order.Latitude,
+			&order.Longitude,
+			&order.HasDestination,
+			&order.DeliveryFee,
+			&order.MinimumOrder,
+			&order.CreatedAt,
+			&order.UpdatedAt,
+			&order.Status,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		orders = append(orders, &order)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return orders, metadata, nil
+}
+
+// GetUnclaimed returns the queue of ready delivery orders that no courier has claimed yet
+func (o OrderModel) GetUnclaimed(ctx context.Context, filters Filters) ([]*Order, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), id, user_id, restaurant_id, total, fulfilment_type, COALESCE(courier_id, 0), address, delivery_instructions, table_number, latitude, longitude, has_destination, delivery_fee, minimum_order, created_at, updated_at, status
+		FROM orders
+		WHERE status = 'ready'
+		AND fulfilment_type = 'delivery'
+		AND courier_id IS NULL
+		ORDER BY %s %s, id ASC
+		LIMIT $1 OFFSET $2`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, span := startSpan(ctx, "OrderModel.GetUnclaimed")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, o.QueryTimeout)
+	defer cancel()
+
+	rows, err := o.DB.QueryContext(ctx, query, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	var orders []*Order
+
+	for rows.Next() {
+		var order Order
+
+		err := rows.Scan(
+			&totalRecords,
+			&order.ID,
+			&order.UserID,
+			&order.RestaurantID,
+			&order.Total,
+			&order.FulfilmentType,
+			&order.CourierID,
 			&order.Address,
+			&order.DeliveryInstructions,
+			&order.TableNumber,
+			&order.Latitude,
+			&order.Longitude,
+			&order.HasDestination,
+			&order.DeliveryFee,
+			&order.MinimumOrder,
 			&order.CreatedAt,
 			&order.UpdatedAt,
 			&order.Status,
@@ -271,3 +663,211 @@ func (o OrderModel) GetAllForUser(userID int64, status string, filters Filters)
 
 	return orders, metadata, nil
 }
+
+// Claim assigns a ready delivery order to a courier. The assignment is a single
+// conditional UPDATE, so when several couriers race for the same order only one
+// of them wins and the others get ErrOrderAlreadyClaimed
+func (o OrderModel) Claim(ctx context.Context, id int64, courierID int64) (*Order, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		UPDATE orders
+		SET courier_id = $1
+		WHERE id = $2
+		AND status = 'ready'
+		AND fulfilment_type = 'delivery'
+		AND courier_id IS NULL
+		RETURNING id, user_id, restaurant_id, total, fulfilment_type, courier_id, address, delivery_instructions, table_number, latitude, longitude, has_destination, delivery_fee, minimum_order, created_at, updated_at, status`
+
+	var order Order
+
+	ctx, span := startSpan(ctx, "OrderModel.Claim")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, o.QueryTimeout)
+	defer cancel()
+
+	err := o.DB.QueryRowContext(ctx, query, courierID, id).Scan(
+		&order.ID,
+		&order.UserID,
+		&order.RestaurantID,
+		&order.Total,
+		&order.FulfilmentType,
+		&order.CourierID,
+		&order.Address,
+		&order.DeliveryInstructions,
+		&order.TableNumber,
+		&order.Latitude,
+		&order.Longitude,
+		&order.HasDestination,
+		&order.DeliveryFee,
+		&order.MinimumOrder,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&order.Status,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, o.claimFailure(ctx, id)
+		default:
+			return nil, err
+		}
+	}
+
+	return &order, nil
+}
+
+// claimFailure tells apart an order that another courier claimed first from one
+// that doesn't exist or isn't waiting in the delivery queue
+func (o OrderModel) claimFailure(ctx context.Context, id int64) error {
+	query := `
+		SELECT courier_id IS NOT NULL
+		FROM orders
+		WHERE id = $1 AND fulfilment_type = 'delivery'`
+
+	var claimed bool
+	err := o.DB.QueryRowContext(ctx, query, id).Scan(&claimed)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if claimed {
+		return ErrOrderAlreadyClaimed
+	}
+
+	return ErrRecordNotFound
+}
+
+// OrderExportRow is one line of an order export: an order together with one of
+// its items. Item is nil for orders without items
+type OrderExportRow struct {
+	Order Order
+	Item  *OrderItem
+}
+
+// rows fetched from the export cursor per round trip
+const exportBatchSize = 500
+
+// ExportTimeout is how long an export may keep reading orders
+const ExportTimeout = 5 * time.Minute
+
+// Export streams a restaurant's orders created in [from, to), joined with their
+// items, to fn in creation order. Rows are read in batches from a server-side
+// cursor so the result set is never held in memory
+func (o OrderModel) Export(ctx context.Context, restaurantID int64, status string, fulfilmentType string, from, to time.Time, fn func(*OrderExportRow) error) error {
+	ctx, span := startSpan(ctx, "OrderModel.Export")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, ExportTimeout)
+	defer cancel()
+
+	tx, err := o.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		DECLARE order_export NO SCROLL CURSOR FOR
+		SELECT o.id, o.user_id, o.restaurant_id, o.total, o.fulfilment_type, COALESCE(o.courier_id, 0), o.address, o.delivery_instructions, o.table_number,
+		       o.latitude, o.longitude, o.has_destination, o.delivery_fee, o.minimum_order, o.created_at, o.updated_at, o.status,
+		       oi.id, COALESCE(oi.dish_id, 0), oi.dish_name, oi.unit_price, oi.quantity, oi.subtotal
+		FROM orders o
+		LEFT JOIN order_items oi ON oi.order_id = o.id
+		WHERE o.restaurant_id = $1
+		AND (o.status = $2 OR $2 = '')
+		AND (o.fulfilment_type = $3 OR $3 = '')
+		AND o.created_at >= $4 AND o.created_at < $5
+		ORDER BY o.created_at ASC, o.id ASC, oi.id ASC`
+
+	_, err = tx.ExecContext(ctx, query, restaurantID, status, fulfilmentType, from, to)
+	if err != nil {
+		return err
+	}
+
+	for {
+		n, err := fetchExportRows(ctx, tx, fn)
+		if err != nil {
+			return err
+		}
+		if n < exportBatchSize {
+			break
+		}
+	}
+
+	return tx.Commit()
+}
+
+// fetchExportRows passes the next batch of cursor rows to fn and reports how many were read
+func fetchExportRows(ctx context.Context, tx *sql.Tx, fn func(*OrderExportRow) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("FETCH %d FROM order_export", exportBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+
+	for rows.Next() {
+		var row OrderExportRow
+		var itemID, dishID, unitPrice, quantity, subtotal sql.NullInt64
+		var dishName sql.NullString
+
+		err := rows.Scan(
+			&row.Order.ID,
+			&row.Order.UserID,
+			&row.Order.RestaurantID,
+			&row.Order.Total,
+			&row.Order.FulfilmentType,
+			&row.Order.CourierID,
+			&row.Order.Address,
+			&row.Order.DeliveryInstructions,
+			&row.Order.TableNumber,
+			&row.Order.Latitude,
+			&row.Order.Longitude,
+			&row.Order.HasDestination,
+			&row.Order.DeliveryFee,
+			&row.Order.MinimumOrder,
+			&row.Order.CreatedAt,
+			&row.Order.UpdatedAt,
+			&row.Order.Status,
+			&itemID,
+			&dishID,
+			&dishName,
+			&unitPrice,
+			&quantity,
+			&subtotal,
+		)
+		if err != nil {
+			return n, err
+		}
+
+		if itemID.Valid {
+			row.Item = &OrderItem{
+				ID:        itemID.Int64,
+				OrderID:   row.Order.ID,
+				DishID:    dishID.Int64,
+				DishName:  dishName.String,
+				UnitPrice: unitPrice.Int64,
+				Quantity:  int(quantity.Int64),
+				Subtotal:  subtotal.Int64,
+			}
+		}
+
+		n++
+
+		if err := fn(&row); err != nil {
+			return n, err
+		}
+	}
+
+	return n, rows.Err()
+}
@@ -1,14 +1,21 @@
 package data
 
-import "testing"
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xtommas/food-backend/internal/validator"
+)
 
 func newTestOrder(userID, restaurantID int64) *Order {
 	return &Order{
-		UserID:       userID,
-		RestaurantID: restaurantID,
-		Total:        1500,
-		Address:      "123 Test Street",
-		Status:       "pending",
+		UserID:         userID,
+		RestaurantID:   restaurantID,
+		Total:          1500,
+		FulfilmentType: FulfilmentDelivery,
+		Address:        "123 Test Street",
+		Status:         "pending",
 	}
 }
 
@@ -16,7 +23,7 @@ func insertTestOrder(t *testing.T, model OrderModel, userID, restaurantID int64)
 	t.Helper()
 
 	order := newTestOrder(userID, restaurantID)
-	if err := model.Insert(order); err != nil {
+	if err := model.Insert(t.Context(), order); err != nil {
 		t.Fatalf("failed to insert test order: %v", err)
 	}
 
@@ -43,7 +50,7 @@ func TestOrderModel_Insert(t *testing.T) {
 	user := insertTestUser(t, userModel)
 	order := newTestOrder(user.Id, restaurantID)
 
-	err := orderModel.Insert(order)
+	err := orderModel.Insert(t.Context(), order)
 	t.Cleanup(func() {
 		testDB.Exec(`DELETE FROM orders WHERE id = $1`, order.ID)
 	})
@@ -69,7 +76,7 @@ func TestOrderModel_GetForRestaurant(t *testing.T) {
 	user := insertTestUser(t, userModel)
 	order := insertTestOrder(t, orderModel, user.Id, restaurantID)
 
-	fetched, err := orderModel.GetForRestaurant(order.ID, restaurantID)
+	fetched, err := orderModel.GetForRestaurant(t.Context(), order.ID, restaurantID)
 	if err != nil {
 		t.Fatalf("GetForRestaurant() error = %v", err)
 	}
@@ -85,12 +92,12 @@ func TestOrderModel_GetForRestaurant(t *testing.T) {
 func TestOrderModel_GetForRestaurant_NotFound(t *testing.T) {
 	model := OrderModel{DB: testDB}
 
-	_, err := model.GetForRestaurant(999999, 999999)
+	_, err := model.GetForRestaurant(t.Context(), 999999, 999999)
 	if err != ErrRecordNotFound {
 		t.Errorf("GetForRestaurant() error = %v, want ErrRecordNotFound", err)
 	}
 
-	_, err = model.GetForRestaurant(0, 999999)
+	_, err = model.GetForRestaurant(t.Context(), 0, 999999)
 	if err != ErrRecordNotFound {
 		t.Errorf("GetForRestaurant() with id=0 error = %v, want ErrRecordNotFound", err)
 	}
@@ -103,7 +110,7 @@ func TestOrderModel_GetForUser(t *testing.T) {
 	user := insertTestUser(t, userModel)
 	order := insertTestOrder(t, orderModel, user.Id, restaurantID)
 
-	fetched, err := orderModel.GetForUser(order.ID, user.Id)
+	fetched, err := orderModel.GetForUser(t.Context(), order.ID, user.Id)
 	if err != nil {
 		t.Fatalf("GetForUser() error = %v", err)
 	}
@@ -119,12 +126,12 @@ func TestOrderModel_GetForUser(t *testing.T) {
 func TestOrderModel_GetForUser_NotFound(t *testing.T) {
 	model := OrderModel{DB: testDB}
 
-	_, err := model.GetForUser(999999, 999999)
+	_, err := model.GetForUser(t.Context(), 999999, 999999)
 	if err != ErrRecordNotFound {
 		t.Errorf("GetForUser() error = %v, want ErrRecordNotFound", err)
 	}
 
-	_, err = model.GetForUser(0, 999999)
+	_, err = model.GetForUser(t.Context(), 0, 999999)
 	if err != ErrRecordNotFound {
 		t.Errorf("GetForUser() with id=0 error = %v, want ErrRecordNotFound", err)
 	}
@@ -139,11 +146,11 @@ func TestOrderModel_Update(t *testing.T) {
 
 	order.Total = 2200
 	order.Status = "confirmed"
-	if err := orderModel.Update(order); err != nil {
+	if err := orderModel.Update(t.Context(), order); err != nil {
 		t.Fatalf("Update() error = %v", err)
 	}
 
-	fetched, err := orderModel.GetForUser(order.ID, user.Id)
+	fetched, err := orderModel.GetForUser(t.Context(), order.ID, user.Id)
 	if err != nil {
 		t.Fatalf("GetForUser() after Update() error = %v", err)
 	}
@@ -164,7 +171,7 @@ func TestOrderModel_Update_NotFound(t *testing.T) {
 		Address: "123 Test Street",
 	}
 
-	err := model.Update(order)
+	err := model.Update(t.Context(), order)
 	if err != ErrRecordNotFound {
 		t.Errorf("Update() error = %v, want ErrRecordNotFound", err)
 	}
@@ -178,11 +185,11 @@ func TestOrderModel_GetAllForRestaurant(t *testing.T) {
 	pending := insertTestOrder(t, orderModel, user.Id, restaurantID)
 	confirmed := insertTestOrder(t, orderModel, user.Id, restaurantID)
 	confirmed.Status = "confirmed"
-	if err := orderModel.Update(confirmed); err != nil {
+	if err := orderModel.Update(t.Context(), confirmed); err != nil {
 		t.Fatalf("Update() confirmed order error = %v", err)
 	}
 
-	orders, metadata, err := orderModel.GetAllForRestaurant(restaurantID, "pending", newTestFilters())
+	orders, metadata, err := orderModel.GetAllForRestaurant(t.Context(), restaurantID, "pending", "", newTestFilters())
 	if err != nil {
 		t.Fatalf("GetAllForRestaurant() error = %v", err)
 	}
@@ -198,6 +205,111 @@ func TestOrderModel_GetAllForRestaurant(t *testing.T) {
 	}
 }
 
+func TestOrderModel_GetAllForRestaurant_FilterByFulfilmentType(t *testing.T) {
+	userModel := UserModel{DB: testDB}
+	orderModel := OrderModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	user := insertTestUser(t, userModel)
+	insertTestOrder(t, orderModel, user.Id, restaurantID)
+
+	pickup := newTestOrder(user.Id, restaurantID)
+	pickup.FulfilmentType = FulfilmentPickup
+	pickup.Address = ""
+	if err := orderModel.Insert(t.Context(), pickup); err != nil {
+		t.Fatalf("Insert() pickup order error = %v", err)
+	}
+	t.Cleanup(func() {
+		testDB.Exec(`DELETE FROM orders WHERE id = $1`, pickup.ID)
+	})
+
+	orders, metadata, err := orderModel.GetAllForRestaurant(t.Context(), restaurantID, "", FulfilmentPickup, newTestFilters())
+	if err != nil {
+		t.Fatalf("GetAllForRestaurant() error = %v", err)
+	}
+
+	if len(orders) != 1 {
+		t.Fatalf("GetAllForRestaurant() returned %d orders, want 1", len(orders))
+	}
+	if orders[0].ID != pickup.ID {
+		t.Errorf("GetAllForRestaurant() order ID = %d, want %d", orders[0].ID, pickup.ID)
+	}
+	if orders[0].FulfilmentType != FulfilmentPickup {
+		t.Errorf("GetAllForRestaurant() FulfilmentType = %q, want %q", orders[0].FulfilmentType, FulfilmentPickup)
+	}
+	if metadata.TotalRecords != 1 {
+		t.Errorf("GetAllForRestaurant() TotalRecords = %d, want 1", metadata.TotalRecords)
+	}
+}
+
+func TestValidateOrder_Fulfilment(t *testing.T) {
+	tests := []struct {
+		name  string
+		order Order
+		valid bool
+	}{
+		{"delivery with address", Order{FulfilmentType: FulfilmentDelivery, Address: "123 Test Street", Status: "pending"}, true},
+		{"delivery without address", Order{FulfilmentType: FulfilmentDelivery, Status: "pending"}, false},
+		{"pickup without address", Order{FulfilmentType: FulfilmentPickup, Status: "pending"}, true},
+		{"dine-in with table", Order{FulfilmentType: FulfilmentDineIn, TableNumber: 4, Status: "pending"}, true},
+		{"dine-in without table", Order{FulfilmentType: FulfilmentDineIn, Status: "pending"}, false},
+		{"pickup with table", Order{FulfilmentType: FulfilmentPickup, TableNumber: 4, Status: "pending"}, false},
+		{"pickup with address", Order{FulfilmentType: FulfilmentPickup, Address: "123 Test Street", Status: "pending"}, false},
+		{"dine-in with coordinates", Order{FulfilmentType: FulfilmentDineIn, TableNumber: 4, Latitude: -34.60, Longitude: -58.38, HasDestination: true, Status: "pending"}, false},
+		{"delivery at zero coordinates", Order{FulfilmentType: FulfilmentDelivery, Address: "123 Test Street", HasDestination: true, Status: "pending"}, true},
+		{"delivery with latitude out of range", Order{FulfilmentType: FulfilmentDelivery, Address: "123 Test Street", Latitude: 91, HasDestination: true, Status: "pending"}, false},
+		{"unknown type", Order{FulfilmentType: "drone", Status: "pending"}, false},
+		{"pickup marked delivered", Order{FulfilmentType: FulfilmentPickup, Status: "delivered"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateOrder(v, &tt.order)
+			if v.Valid() != tt.valid {
+				t.Errorf("ValidateOrder() valid = %v, want %v (errors: %v)", v.Valid(), tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestValidateStatusTransition_PerFulfilmentType(t *testing.T) {
+	tests := []struct {
+		fulfilmentType string
+		actor          string
+		from, to       string
+		valid          bool
+	}{
+		{FulfilmentDelivery, ActorCourier, "ready", "out_for_delivery", true},
+		{FulfilmentDelivery, ActorCourier, "out_for_delivery", "delivered", true},
+		{FulfilmentDelivery, ActorStaff, "out_for_delivery", "delivered", false},
+		{FulfilmentDelivery, ActorCourier, "ready", "delivered", false},
+		{FulfilmentDelivery, ActorCourier, "preparing", "ready", false},
+		{FulfilmentDelivery, ActorStaff, "ready", "picked_up", false},
+		{FulfilmentPickup, ActorStaff, "ready", "picked_up", true},
+		{FulfilmentPickup, ActorStaff, "ready", "delivered", false},
+		{FulfilmentDineIn, ActorStaff, "ready", "served", true},
+		{FulfilmentDineIn, ActorStaff, "pending", "cancelled", true},
+		{FulfilmentDineIn, ActorStaff, "served", "cancelled", false},
+	}
+
+	for _, tt := range tests {
+		v := validator.New()
+		ValidateStatusTransition(v, tt.fulfilmentType, tt.actor, tt.from, tt.to)
+		if v.Valid() != tt.valid {
+			t.Errorf("ValidateStatusTransition(%s, %s, %s, %s) valid = %v, want %v", tt.fulfilmentType, tt.actor, tt.from, tt.to, v.Valid(), tt.valid)
+		}
+	}
+}
+
+func TestOrder_IsClosed(t *testing.T) {
+	if !(&Order{FulfilmentType: FulfilmentPickup, Status: "picked_up"}).IsClosed() {
+		t.Error("IsClosed() = false for picked_up pickup order, want true")
+	}
+	if (&Order{FulfilmentType: FulfilmentDelivery, Status: "ready"}).IsClosed() {
+		t.Error("IsClosed() = true for ready delivery order, want false")
+	}
+}
+
 func TestOrderModel_GetAllForUser(t *testing.T) {
 	userModel := UserModel{DB: testDB}
 	orderModel := OrderModel{DB: testDB}
@@ -206,7 +318,7 @@ func TestOrderModel_GetAllForUser(t *testing.T) {
 	first := insertTestOrder(t, orderModel, user.Id, restaurantID)
 	second := insertTestOrder(t, orderModel, user.Id, restaurantID)
 
-	orders, metadata, err := orderModel.GetAllForUser(user.Id, "", newTestFilters())
+	orders, metadata, err := orderModel.GetAllForUser(t.Context(), user.Id, "", newTestFilters())
 	if err != nil {
 		t.Fatalf("GetAllForUser() error = %v", err)
 	}
@@ -221,3 +333,344 @@ func TestOrderModel_GetAllForUser(t *testing.T) {
 		t.Errorf("GetAllForUser() TotalRecords = %d, want 2", metadata.TotalRecords)
 	}
 }
+
+func insertTestCourier(t *testing.T, model UserModel) *User {
+	t.Helper()
+
+	courier := newTestUser(t)
+	courier.Role = "courier"
+	if err := model.Insert(t.Context(), courier); err != nil {
+		t.Fatalf("failed to insert test courier: %v", err)
+	}
+
+	t.Cleanup(func() {
+		testDB.Exec(`DELETE FROM users WHERE id = $1`, courier.Id)
+	})
+
+	return courier
+}
+
+func insertReadyDeliveryOrder(t *testing.T, model OrderModel, userID, restaurantID int64) *Order {
+	t.Helper()
+
+	order := insertTestOrder(t, model, userID, restaurantID)
+	order.Status = "ready"
+	if err := model.Update(t.Context(), order); err != nil {
+		t.Fatalf("failed to mark test order ready: %v", err)
+	}
+
+	return order
+}
+
+func TestOrderModel_Claim(t *testing.T) {
+	userModel := UserModel{DB: testDB}
+	orderModel := OrderModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	user := insertTestUser(t, userModel)
+	first := insertTestCourier(t, userModel)
+	second := insertTestCourier(t, userModel)
+	order := insertReadyDeliveryOrder(t, orderModel, user.Id, restaurantID)
+
+	claimed, err := orderModel.Claim(t.Context(), order.ID, first.Id)
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if claimed.CourierID != first.Id {
+		t.Errorf("Claim() CourierID = %d, want %d", claimed.CourierID, first.Id)
+	}
+
+	_, err = orderModel.Claim(t.Context(), order.ID, second.Id)
+	if err != ErrOrderAlreadyClaimed {
+		t.Errorf("Claim() by second courier error = %v, want ErrOrderAlreadyClaimed", err)
+	}
+
+	fetched, err := orderModel.GetForCourier(t.Context(), order.ID, first.Id)
+	if err != nil {
+		t.Fatalf("GetForCourier() error = %v", err)
+	}
+	if fetched.ID != order.ID {
+		t.Errorf("GetForCourier() ID = %d, want %d", fetched.ID, order.ID)
+	}
+
+	_, err = orderModel.GetForCourier(t.Context(), order.ID, second.Id)
+	if err != ErrRecordNotFound {
+		t.Errorf("GetForCourier() by second courier error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestOrderModel_Claim_Concurrent(t *testing.T) {
+	userModel := UserModel{DB: testDB}
+	orderModel := OrderModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	user := insertTestUser(t, userModel)
+	order := insertReadyDeliveryOrder(t, orderModel, user.Id, restaurantID)
+
+	const couriers = 5
+	ids := make([]int64, couriers)
+	for i := range ids {
+		ids[i] = insertTestCourier(t, userModel).Id
+	}
+
+	errs := make(chan error, couriers)
+	for _, id := range ids {
+		go func() {
+			_, err := orderModel.Claim(t.Context(), order.ID, id)
+			errs <- err
+		}()
+	}
+
+	wins := 0
+	for range couriers {
+		err := <-errs
+		switch err {
+		case nil:
+			wins++
+		case ErrOrderAlreadyClaimed:
+		default:
+			t.Errorf("Claim() unexpected error = %v", err)
+		}
+	}
+
+	if wins != 1 {
+		t.Errorf("Claim() succeeded %d times, want exactly 1", wins)
+	}
+}
+
+func TestOrderModel_Claim_NotReady(t *testing.T) {
+	userModel := UserModel{DB: testDB}
+	orderModel := OrderModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	user := insertTestUser(t, userModel)
+	courier := insertTestCourier(t, userModel)
+	order := insertTestOrder(t, orderModel, user.Id, restaurantID)
+
+	_, err := orderModel.Claim(t.Context(), order.ID, courier.Id)
+	if err != ErrRecordNotFound {
+		t.Errorf("Claim() on pending order error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestOrderModel_UpdateStatusForCourier(t *testing.T) {
+	userModel := UserModel{DB: testDB}
+	orderModel := OrderModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	user := insertTestUser(t, userModel)
+	courier := insertTestCourier(t, userModel)
+	order := insertReadyDeliveryOrder(t, orderModel, user.Id, restaurantID)
+
+	claimed, err := orderModel.Claim(t.Context(), order.ID, courier.Id)
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	claimed.Status = "out_for_delivery"
+	if err := orderModel.UpdateStatusForCourier(t.Context(), claimed, courier.Id, "ready"); err != nil {
+		t.Fatalf("UpdateStatusForCourier() error = %v", err)
+	}
+
+	fetched, err := orderModel.GetForCourier(t.Context(), order.ID, courier.Id)
+	if err != nil {
+		t.Fatalf("GetForCourier() error = %v", err)
+	}
+	if fetched.Status != "out_for_delivery" {
+		t.Errorf("UpdateStatusForCourier() Status = %q, want out_for_delivery", fetched.Status)
+	}
+}
+
+func TestOrderModel_UpdateStatusForCourier_Conflict(t *testing.T) {
+	userModel := UserModel{DB: testDB}
+	orderModel := OrderModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	user := insertTestUser(t, userModel)
+	courier := insertTestCourier(t, userModel)
+	order := insertReadyDeliveryOrder(t, orderModel, user.Id, restaurantID)
+
+	claimed, err := orderModel.Claim(t.Context(), order.ID, courier.Id)
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	// the restaurant cancels after the courier read the order
+	cancelled := *claimed
+	cancelled.Status = "cancelled"
+	if err := orderModel.Update(t.Context(), &cancelled); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	claimed.Status = "out_for_delivery"
+	err = orderModel.UpdateStatusForCourier(t.Context(), claimed, courier.Id, "ready")
+	if err != ErrEditConflict {
+		t.Fatalf("UpdateStatusForCourier() error = %v, want ErrEditConflict", err)
+	}
+
+	fetched, err := orderModel.GetForCourier(t.Context(), order.ID, courier.Id)
+	if err != nil {
+		t.Fatalf("GetForCourier() error = %v", err)
+	}
+	if fetched.Status != "cancelled" {
+		t.Errorf("UpdateStatusForCourier() overwrote Status with %q, want cancelled", fetched.Status)
+	}
+
+	// another courier can't move the order either
+	other := insertTestCourier(t, userModel)
+	fetched.Status = "delivered"
+	err = orderModel.UpdateStatusForCourier(t.Context(), fetched, other.Id, "cancelled")
+	if err != ErrEditConflict {
+		t.Errorf("UpdateStatusForCourier() by another courier error = %v, want ErrEditConflict", err)
+	}
+}
+
+func TestOrderModel_GetUnclaimed(t *testing.T) {
+	userModel := UserModel{DB: testDB}
+	orderModel := OrderModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	user := insertTestUser(t, userModel)
+	courier := insertTestCourier(t, userModel)
+	waiting := insertReadyDeliveryOrder(t, orderModel, user.Id, restaurantID)
+	taken := insertReadyDeliveryOrder(t, orderModel, user.Id, restaurantID)
+
+	if _, err := orderModel.Claim(t.Context(), taken.ID, courier.Id); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	orders, _, err := orderModel.GetUnclaimed(t.Context(), newTestFilters())
+	if err != nil {
+		t.Fatalf("GetUnclaimed() error = %v", err)
+	}
+
+	var sawWaiting bool
+	for _, order := range orders {
+		if order.ID == taken.ID {
+			t.Error("GetUnclaimed() returned an order that was already claimed")
+		}
+		if order.ID == waiting.ID {
+			sawWaiting = true
+		}
+	}
+	if !sawWaiting {
+		t.Error("GetUnclaimed() did not return the unclaimed ready order")
+	}
+
+	mine, metadata, err := orderModel.GetAllForCourier(t.Context(), courier.Id, "", newTestFilters())
+	if err != nil {
+		t.Fatalf("GetAllForCourier() error = %v", err)
+	}
+	if len(mine) != 1 || mine[0].ID != taken.ID {
+		t.Errorf("GetAllForCourier() returned %d orders, want only order %d", len(mine), taken.ID)
+	}
+	if metadata.TotalRecords != 1 {
+		t.Errorf("GetAllForCourier() TotalRecords = %d, want 1", metadata.TotalRecords)
+	}
+}
+
+func TestValidateMinimumOrder(t *testing.T) {
+	order := &Order{Total: 1000, MinimumOrder: 1500}
+
+	v := validator.New()
+	ValidateMinimumOrder(v, order)
+	if v.Valid() {
+		t.Error("ValidateMinimumOrder() accepted a total below the minimum order")
+	}
+
+	order.Total = 1500
+	v = validator.New()
+	ValidateMinimumOrder(v, order)
+	if !v.Valid() {
+		t.Errorf("ValidateMinimumOrder() errors = %v, want none", v.Errors)
+	}
+}
+
+func TestOrder_IsCompleted(t *testing.T) {
+	tests := []struct {
+		fulfilmentType string
+		status         string
+		want           bool
+	}{
+		{FulfilmentDelivery, "delivered", true},
+		{FulfilmentPickup, "picked_up", true},
+		{FulfilmentDineIn, "served", true},
+		{FulfilmentDelivery, "cancelled", false},
+		{FulfilmentDelivery, "out_for_delivery", false},
+	}
+
+	for _, tt := range tests {
+		order := &Order{FulfilmentType: tt.fulfilmentType, Status: tt.status}
+		if got := order.IsCompleted(); got != tt.want {
+			t.Errorf("IsCompleted() for %s order in %s = %v, want %v", tt.fulfilmentType, tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestOrderModel_Export(t *testing.T) {
+	orderModel := OrderModel{DB: testDB}
+	itemModel := OrderItemModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	user := insertTestUser(t, UserModel{DB: testDB})
+	dish := insertTestDish(t, DishModel{DB: testDB}, restaurantID)
+
+	withItems := insertTestOrder(t, orderModel, user.Id, restaurantID)
+	for _, quantity := range []int{1, 2} {
+		if _, err := itemModel.InsertFromDish(t.Context(), withItems.ID, dish, quantity); err != nil {
+			t.Fatalf("InsertFromDish() error = %v", err)
+		}
+	}
+	empty := insertTestOrder(t, orderModel, user.Id, restaurantID)
+	empty.Status = "cancelled"
+	if err := orderModel.Update(t.Context(), empty); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	from := time.Now().Add(-time.Hour)
+	to := time.Now().Add(time.Hour)
+
+	var rows []OrderExportRow
+	err := orderModel.Export(t.Context(), restaurantID, "", "", from, to, func(row *OrderExportRow) error {
+		rows = append(rows, *row)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if len(rows) != 3 {
+		t.Fatalf("Export() returned %d rows, want 3", len(rows))
+	}
+	if rows[0].Order.ID != withItems.ID || rows[0].Item == nil || rows[0].Item.Quantity != 1 {
+		t.Errorf("first row = %+v, want the first item of order %d", rows[0], withItems.ID)
+	}
+	if rows[1].Item == nil || rows[1].Item.Subtotal != dish.Price*2 {
+		t.Errorf("second row item = %+v, want subtotal %d", rows[1].Item, dish.Price*2)
+	}
+	if rows[2].Order.ID != empty.ID || rows[2].Item != nil {
+		t.Errorf("third row = %+v, want order %d without an item", rows[2], empty.ID)
+	}
+
+	// the status filter matches getOrdersForRestaurantHandler
+	var cancelled int
+	err = orderModel.Export(t.Context(), restaurantID, "cancelled", "", from, to, func(row *OrderExportRow) error {
+		cancelled++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if cancelled != 1 {
+		t.Errorf("Export(status=cancelled) returned %d rows, want 1", cancelled)
+	}
+}
+
+func TestOrderModel_Export_CallbackError(t *testing.T) {
+	orderModel := OrderModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	user := insertTestUser(t, UserModel{DB: testDB})
+	insertTestOrder(t, orderModel, user.Id, restaurantID)
+
+	stop := errors.New("stop")
+
+	err := orderModel.Export(t.Context(), restaurantID, "", "", time.Now().Add(-time.Hour), time.Now().Add(time.Hour), func(row *OrderExportRow) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Export() error = %v, want %v", err, stop)
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/lib/pq"
 )
 
+// PermissionCodes lists every permission a user can be granted
+var PermissionCodes = []string{"dishes:read", "dishes:write", "restaurants:read", "orders:read", "orders:write"}
+
 type Permissions []string
 
 func (p Permissions) Include(code string) bool {
@@ -16,10 +19,11 @@ func (p Permissions) Include(code string) bool {
 }
 
 type PermissionModel struct {
-	DB *sql.DB
+	DB           *sql.DB
+	QueryTimeout time.Duration
 }
 
-func (m PermissionModel) GetAllForUser(userId int64) (Permissions, error) {
+func (m PermissionModel) GetAllForUser(ctx context.Context, userId int64) (Permissions, error) {
 	query := `
 		SELECT permissions.code
 		FROM permissions
@@ -27,7 +31,10 @@ func (m PermissionModel) GetAllForUser(userId int64) (Permissions, error) {
 		INNER JOIN users ON users_permissions.user_id = users.id
 		WHERE users.id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "PermissionModel.GetAllForUser")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, userId)
@@ -55,26 +62,51 @@ func (m PermissionModel) GetAllForUser(userId int64) (Permissions, error) {
 	return permissions, nil
 }
 
-func (m PermissionModel) AddForUser(userId int64, codes ...string) error {
-	query := `
-		INSERT INTO users_permissions
-		SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)`
+// addPermissionsQuery grants the codes in $2 to user $1, skipping the ones
+// they already have
+const addPermissionsQuery = `
+	INSERT INTO users_permissions
+	SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)
+	ON CONFLICT DO NOTHING`
+
+// AddForUser grants the codes to the user. Codes the user already has are skipped
+func (m PermissionModel) AddForUser(ctx context.Context, userId int64, codes ...string) error {
+	ctx, span := startSpan(ctx, "PermissionModel.AddForUser")
+	defer span.End()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, userId, pq.Array(codes))
+	_, err := m.DB.ExecContext(ctx, addPermissionsQuery, userId, pq.Array(codes))
 	return err
 }
 
-func (m PermissionModel) DeleteForUser(userId int64, code string) error {
+// DeleteForUser revokes the code, returning ErrRecordNotFound if the user
+// doesn't have it
+func (m PermissionModel) DeleteForUser(ctx context.Context, userId int64, code string) error {
 	query := `
 		DELETE FROM users_permissions
 		WHERE user_id = $1 AND permission_id IN (SELECT id FROM permissions WHERE permissions.code = $2)`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "PermissionModel.DeleteForUser")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, userId, code)
-	return err
+	result, err := m.DB.ExecContext(ctx, query, userId, code)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
 }
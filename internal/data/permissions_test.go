@@ -1,6 +1,9 @@
 package data
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestPermissions_Include(t *testing.T) {
 	permissions := Permissions{"dishes:read", "orders:write"}
@@ -18,11 +21,11 @@ func TestPermissionModel_AddGetDeleteForUser(t *testing.T) {
 	permissionModel := PermissionModel{DB: testDB}
 	user := insertTestUser(t, userModel)
 
-	if err := permissionModel.AddForUser(user.Id, "dishes:read", "orders:write"); err != nil {
+	if err := permissionModel.AddForUser(t.Context(), user.Id, "dishes:read", "orders:write"); err != nil {
 		t.Fatalf("AddForUser() error = %v", err)
 	}
 
-	permissions, err := permissionModel.GetAllForUser(user.Id)
+	permissions, err := permissionModel.GetAllForUser(t.Context(), user.Id)
 	if err != nil {
 		t.Fatalf("GetAllForUser() error = %v", err)
 	}
@@ -33,11 +36,11 @@ func TestPermissionModel_AddGetDeleteForUser(t *testing.T) {
 		t.Error("GetAllForUser() did not include orders:write")
 	}
 
-	if err := permissionModel.DeleteForUser(user.Id, "dishes:read"); err != nil {
+	if err := permissionModel.DeleteForUser(t.Context(), user.Id, "dishes:read"); err != nil {
 		t.Fatalf("DeleteForUser() error = %v", err)
 	}
 
-	permissions, err = permissionModel.GetAllForUser(user.Id)
+	permissions, err = permissionModel.GetAllForUser(t.Context(), user.Id)
 	if err != nil {
 		t.Fatalf("GetAllForUser() after DeleteForUser() error = %v", err)
 	}
@@ -54,7 +57,7 @@ func TestPermissionModel_GetAllForUser_NoPermissions(t *testing.T) {
 	permissionModel := PermissionModel{DB: testDB}
 	user := insertTestUser(t, userModel)
 
-	permissions, err := permissionModel.GetAllForUser(user.Id)
+	permissions, err := permissionModel.GetAllForUser(t.Context(), user.Id)
 	if err != nil {
 		t.Fatalf("GetAllForUser() error = %v", err)
 	}
@@ -62,3 +65,33 @@ func TestPermissionModel_GetAllForUser_NoPermissions(t *testing.T) {
 		t.Errorf("GetAllForUser() returned %d permissions, want 0", len(permissions))
 	}
 }
+
+func TestPermissionModel_AddForUser_Existing(t *testing.T) {
+	permissionModel := PermissionModel{DB: testDB}
+	user := insertTestUser(t, UserModel{DB: testDB})
+
+	if err := permissionModel.AddForUser(t.Context(), user.Id, "dishes:read"); err != nil {
+		t.Fatalf("AddForUser() error = %v", err)
+	}
+	if err := permissionModel.AddForUser(t.Context(), user.Id, "dishes:read", "orders:read"); err != nil {
+		t.Fatalf("AddForUser() with a code the user already has error = %v", err)
+	}
+
+	permissions, err := permissionModel.GetAllForUser(t.Context(), user.Id)
+	if err != nil {
+		t.Fatalf("GetAllForUser() error = %v", err)
+	}
+	if len(permissions) != 2 {
+		t.Errorf("GetAllForUser() returned %v, want dishes:read and orders:read", permissions)
+	}
+}
+
+func TestPermissionModel_DeleteForUser_NotGranted(t *testing.T) {
+	permissionModel := PermissionModel{DB: testDB}
+	user := insertTestUser(t, UserModel{DB: testDB})
+
+	err := permissionModel.DeleteForUser(t.Context(), user.Id, "orders:write")
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("DeleteForUser() error = %v, want ErrRecordNotFound", err)
+	}
+}
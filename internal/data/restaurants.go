@@ -10,20 +10,32 @@ import (
 )
 
 type Restaurant struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Photo     string    `json:"photo,omitempty"`
-	Address   string    `json:"address"`
-	City      string    `json:"city"`
-	State     string    `json:"state,omitempty"`
-	Province  string    `json:"province,omitempty"`
-	Country   string    `json:"country"`
-	Latitude  float64   `json:"latitude,omitempty"`
-	Longitude float64   `json:"longitude,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	Version   int       `json:"-"`
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Photo       string    `json:"photo,omitempty"`
+	Address     string    `json:"address"`
+	City        string    `json:"city"`
+	State       string    `json:"state,omitempty"`
+	Province    string    `json:"province,omitempty"`
+	Country     string    `json:"country"`
+	Latitude    float64   `json:"latitude,omitempty"`
+	Longitude   float64   `json:"longitude,omitempty"`
+	Rating      float64   `json:"rating"`
+	RatingCount int       `json:"rating_count"`
+	IsFavorite  bool      `json:"is_favorite"`
+	CreatedAt   time.Time `json:"created_at"`
+	Version     int       `json:"-"`
 }
 
+// restaurantRatingsJoin adds the average rating and number of visible reviews
+// of each restaurant, for a query that aliases restaurants as r
+const restaurantRatingsJoin = `
+		LEFT JOIN LATERAL (
+			SELECT COALESCE(ROUND(AVG(rv.rating), 2), 0)::float8 AS rating, COUNT(*) AS rating_count
+			FROM reviews rv
+			WHERE rv.restaurant_id = r.id AND NOT rv.hidden
+		) ratings ON TRUE`
+
 func ValidateRestaurant(v *validator.Validator, r *Restaurant) {
 	v.Check(r.Name != "", "name", "must be provided")
 	v.Check(len(r.Name) <= 500, "name", "must be no more than 500 characters long")
@@ -39,10 +51,11 @@ func ValidateRestaurant(v *validator.Validator, r *Restaurant) {
 }
 
 type RestaurantModel struct {
-	DB *sql.DB
+	DB           *sql.DB
+	QueryTimeout time.Duration
 }
 
-func (m RestaurantModel) Insert(restaurant *Restaurant) error {
+func (m RestaurantModel) Insert(ctx context.Context, restaurant *Restaurant) error {
 	query := `
 		INSERT INTO restaurants (name, photo, address, city, state, province, country, latitude, longitude)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
@@ -60,7 +73,10 @@ func (m RestaurantModel) Insert(restaurant *Restaurant) error {
 		restaurant.Longitude,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "RestaurantModel.Insert")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	return m.DB.QueryRowContext(ctx, query, args...).Scan(
@@ -70,19 +86,23 @@ func (m RestaurantModel) Insert(restaurant *Restaurant) error {
 	)
 }
 
-func (m RestaurantModel) Get(id int64) (*Restaurant, error) {
+func (m RestaurantModel) Get(ctx context.Context, id int64) (*Restaurant, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-		SELECT id, name, photo, address, city, state, province, country, latitude, longitude, created_at, version
-		FROM restaurants
-		WHERE id = $1`
+		SELECT r.id, r.name, r.photo, r.address, r.city, r.state, r.province, r.country, r.latitude, r.longitude,
+		       ratings.rating, ratings.rating_count, r.created_at, r.version
+		FROM restaurants r` + restaurantRatingsJoin + `
+		WHERE r.id = $1`
 
 	var r Restaurant
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "RestaurantModel.Get")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(
@@ -96,6 +116,8 @@ func (m RestaurantModel) Get(id int64) (*Restaurant, error) {
 		&r.Country,
 		&r.Latitude,
 		&r.Longitude,
+		&r.Rating,
+		&r.RatingCount,
 		&r.CreatedAt,
 		&r.Version,
 	)
@@ -111,7 +133,7 @@ func (m RestaurantModel) Get(id int64) (*Restaurant, error) {
 	return &r, nil
 }
 
-func (m RestaurantModel) Update(restaurant *Restaurant) error {
+func (m RestaurantModel) Update(ctx context.Context, restaurant *Restaurant) error {
 	query := `
 		UPDATE restaurants
 		SET name = $1, photo = $2, address = $3, city = $4, state = $5, province = $6,
@@ -133,7 +155,10 @@ func (m RestaurantModel) Update(restaurant *Restaurant) error {
 		restaurant.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "RestaurantModel.Update")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&restaurant.Version)
@@ -149,14 +174,17 @@ func (m RestaurantModel) Update(restaurant *Restaurant) error {
 	return nil
 }
 
-func (m RestaurantModel) Delete(id int64) error {
+func (m RestaurantModel) Delete(ctx context.Context, id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
 	query := `DELETE FROM restaurants WHERE id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "RestaurantModel.Delete")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	result, err := m.DB.ExecContext(ctx, query, id)
@@ -176,16 +204,23 @@ func (m RestaurantModel) Delete(id int64) error {
 	return nil
 }
 
-func (m RestaurantModel) GetAll() ([]*Restaurant, error) {
+// GetAll lists every restaurant, flagging the ones the given user has saved as favourites
+func (m RestaurantModel) GetAll(ctx context.Context, userID int64) ([]*Restaurant, error) {
 	query := `
-		SELECT id, name, photo, address, city, state, province, country, latitude, longitude, created_at, version
-		FROM restaurants
-		ORDER BY name ASC`
+		SELECT r.id, r.name, r.photo, r.address, r.city, r.state, r.province, r.country, r.latitude, r.longitude,
+		       ratings.rating, ratings.rating_count,
+		       EXISTS (SELECT 1 FROM favorite_restaurants fr WHERE fr.restaurant_id = r.id AND fr.user_id = $1),
+		       r.created_at, r.version
+		FROM restaurants r` + restaurantRatingsJoin + `
+		ORDER BY r.name ASC`
+
+	ctx, span := startSpan(ctx, "RestaurantModel.GetAll")
+	defer span.End()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query)
+	rows, err := m.DB.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -207,6 +242,9 @@ func (m RestaurantModel) GetAll() ([]*Restaurant, error) {
 			&r.Country,
 			&r.Latitude,
 			&r.Longitude,
+			&r.Rating,
+			&r.RatingCount,
+			&r.IsFavorite,
 			&r.CreatedAt,
 			&r.Version,
 		)
@@ -224,14 +262,17 @@ func (m RestaurantModel) GetAll() ([]*Restaurant, error) {
 	return restaurants, nil
 }
 
-func (m RestaurantModel) GetStaff(restaurantID int64) ([]*User, error) {
+func (m RestaurantModel) GetStaff(ctx context.Context, restaurantID int64) ([]*User, error) {
 	query := `
 		SELECT u.id, u.photo, u.created_at, u.name, u.email, u.activated, u.version, u.role
 		FROM users u
 		INNER JOIN restaurant_staff rs ON rs.user_id = u.id
 		WHERE rs.restaurant_id = $1`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "RestaurantModel.GetStaff")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, restaurantID)
@@ -269,25 +310,31 @@ func (m RestaurantModel) GetStaff(restaurantID int64) ([]*User, error) {
 	return users, nil
 }
 
-func (m RestaurantModel) AddStaff(restaurantID, userID int64, role string) error {
+func (m RestaurantModel) AddStaff(ctx context.Context, restaurantID, userID int64, role string) error {
 	query := `
 		INSERT INTO restaurant_staff (restaurant_id, user_id, role)
 		VALUES ($1, $2, $3)
 		ON CONFLICT (restaurant_id, user_id) DO UPDATE SET role = EXCLUDED.role`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "RestaurantModel.AddStaff")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, restaurantID, userID, role)
 	return err
 }
 
-func (m RestaurantModel) RemoveStaff(restaurantID, userID int64) error {
+func (m RestaurantModel) RemoveStaff(ctx context.Context, restaurantID, userID int64) error {
 	query := `
 		DELETE FROM restaurant_staff
 		WHERE restaurant_id = $1 AND user_id = $2`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "RestaurantModel.RemoveStaff")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	result, err := m.DB.ExecContext(ctx, query, restaurantID, userID)
@@ -307,14 +354,17 @@ func (m RestaurantModel) RemoveStaff(restaurantID, userID int64) error {
 	return nil
 }
 
-func (m RestaurantModel) IsStaff(restaurantID, userID int64) (bool, error) {
+func (m RestaurantModel) IsStaff(ctx context.Context, restaurantID, userID int64) (bool, error) {
 	query := `
 		SELECT EXISTS (
 			SELECT 1 FROM restaurant_staff
 			WHERE restaurant_id = $1 AND user_id = $2
 		)`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "RestaurantModel.IsStaff")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	var exists bool
@@ -322,13 +372,16 @@ func (m RestaurantModel) IsStaff(restaurantID, userID int64) (bool, error) {
 	return exists, err
 }
 
-func (m RestaurantModel) GetStaffRole(restaurantID, userID int64) (string, error) {
+func (m RestaurantModel) GetStaffRole(ctx context.Context, restaurantID, userID int64) (string, error) {
 	query := `
 		SELECT role
 		FROM restaurant_staff
 		WHERE restaurant_id = $1 AND user_id = $2`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "RestaurantModel.GetStaffRole")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	var role string
@@ -1,6 +1,10 @@
 package data
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"testing"
+)
 
 func newTestRestaurant() *Restaurant {
 	return &Restaurant{
@@ -20,12 +24,12 @@ func insertTestRestaurant(t *testing.T, model RestaurantModel) *Restaurant {
 	t.Helper()
 
 	restaurant := newTestRestaurant()
-	if err := model.Insert(restaurant); err != nil {
+	if err := model.Insert(t.Context(), restaurant); err != nil {
 		t.Fatalf("failed to insert test restaurant: %v", err)
 	}
 
 	t.Cleanup(func() {
-		model.Delete(restaurant.ID)
+		model.Delete(context.Background(), restaurant.ID)
 	})
 
 	return restaurant
@@ -35,9 +39,9 @@ func TestRestaurantModel_Insert(t *testing.T) {
 	model := RestaurantModel{DB: testDB}
 	restaurant := newTestRestaurant()
 
-	err := model.Insert(restaurant)
+	err := model.Insert(t.Context(), restaurant)
 	t.Cleanup(func() {
-		model.Delete(restaurant.ID)
+		model.Delete(context.Background(), restaurant.ID)
 	})
 
 	if err != nil {
@@ -58,7 +62,7 @@ func TestRestaurantModel_Get(t *testing.T) {
 	model := RestaurantModel{DB: testDB}
 	restaurant := insertTestRestaurant(t, model)
 
-	fetched, err := model.Get(restaurant.ID)
+	fetched, err := model.Get(t.Context(), restaurant.ID)
 	if err != nil {
 		t.Fatalf("Get() error = %v", err)
 	}
@@ -77,17 +81,30 @@ func TestRestaurantModel_Get(t *testing.T) {
 func TestRestaurantModel_Get_NotFound(t *testing.T) {
 	model := RestaurantModel{DB: testDB}
 
-	_, err := model.Get(999999)
+	_, err := model.Get(t.Context(), 999999)
 	if err != ErrRecordNotFound {
 		t.Errorf("Get() error = %v, want ErrRecordNotFound", err)
 	}
 
-	_, err = model.Get(0)
+	_, err = model.Get(t.Context(), 0)
 	if err != ErrRecordNotFound {
 		t.Errorf("Get() with id=0 error = %v, want ErrRecordNotFound", err)
 	}
 }
 
+func TestRestaurantModel_Get_CancelledContext(t *testing.T) {
+	model := RestaurantModel{DB: testDB}
+	restaurant := insertTestRestaurant(t, model)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := model.Get(ctx, restaurant.ID)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() error = %v, want context.Canceled", err)
+	}
+}
+
 func TestRestaurantModel_Update(t *testing.T) {
 	model := RestaurantModel{DB: testDB}
 	restaurant := insertTestRestaurant(t, model)
@@ -98,7 +115,7 @@ func TestRestaurantModel_Update(t *testing.T) {
 	restaurant.Longitude = -70.66927
 	oldVersion := restaurant.Version
 
-	if err := model.Update(restaurant); err != nil {
+	if err := model.Update(t.Context(), restaurant); err != nil {
 		t.Fatalf("Update() error = %v", err)
 	}
 
@@ -106,7 +123,7 @@ func TestRestaurantModel_Update(t *testing.T) {
 		t.Errorf("Update() Version = %d, want %d", restaurant.Version, oldVersion+1)
 	}
 
-	fetched, err := model.Get(restaurant.ID)
+	fetched, err := model.Get(t.Context(), restaurant.ID)
 	if err != nil {
 		t.Fatalf("Get() after Update() error = %v", err)
 	}
@@ -125,12 +142,12 @@ func TestRestaurantModel_Update_EditConflict(t *testing.T) {
 	stale := *restaurant
 
 	restaurant.Name = "Current Restaurant"
-	if err := model.Update(restaurant); err != nil {
+	if err := model.Update(t.Context(), restaurant); err != nil {
 		t.Fatalf("Update() current restaurant error = %v", err)
 	}
 
 	stale.Name = "Stale Restaurant"
-	err := model.Update(&stale)
+	err := model.Update(t.Context(), &stale)
 	if err != ErrEditConflict {
 		t.Errorf("Update() stale restaurant error = %v, want ErrEditConflict", err)
 	}
@@ -139,15 +156,15 @@ func TestRestaurantModel_Update_EditConflict(t *testing.T) {
 func TestRestaurantModel_Delete(t *testing.T) {
 	model := RestaurantModel{DB: testDB}
 	restaurant := newTestRestaurant()
-	if err := model.Insert(restaurant); err != nil {
+	if err := model.Insert(t.Context(), restaurant); err != nil {
 		t.Fatalf("Insert() error = %v", err)
 	}
 
-	if err := model.Delete(restaurant.ID); err != nil {
+	if err := model.Delete(t.Context(), restaurant.ID); err != nil {
 		t.Fatalf("Delete() error = %v", err)
 	}
 
-	_, err := model.Get(restaurant.ID)
+	_, err := model.Get(t.Context(), restaurant.ID)
 	if err != ErrRecordNotFound {
 		t.Errorf("Get() after Delete() error = %v, want ErrRecordNotFound", err)
 	}
@@ -156,12 +173,12 @@ func TestRestaurantModel_Delete(t *testing.T) {
 func TestRestaurantModel_Delete_NotFound(t *testing.T) {
 	model := RestaurantModel{DB: testDB}
 
-	err := model.Delete(999999)
+	err := model.Delete(t.Context(), 999999)
 	if err != ErrRecordNotFound {
 		t.Errorf("Delete() error = %v, want ErrRecordNotFound", err)
 	}
 
-	err = model.Delete(0)
+	err = model.Delete(t.Context(), 0)
 	if err != ErrRecordNotFound {
 		t.Errorf("Delete() with id=0 error = %v, want ErrRecordNotFound", err)
 	}
@@ -174,14 +191,14 @@ func TestRestaurantModel_GetAll(t *testing.T) {
 
 	first.Name = "AAA Test Restaurant"
 	second.Name = "ZZZ Test Restaurant"
-	if err := model.Update(first); err != nil {
+	if err := model.Update(t.Context(), first); err != nil {
 		t.Fatalf("Update() first restaurant error = %v", err)
 	}
-	if err := model.Update(second); err != nil {
+	if err := model.Update(t.Context(), second); err != nil {
 		t.Fatalf("Update() second restaurant error = %v", err)
 	}
 
-	restaurants, err := model.GetAll()
+	restaurants, err := model.GetAll(t.Context(), 0)
 	if err != nil {
 		t.Fatalf("GetAll() error = %v", err)
 	}
@@ -214,7 +231,7 @@ func TestRestaurantModel_Staff(t *testing.T) {
 	restaurant := insertTestRestaurant(t, restaurantModel)
 	user := insertTestUser(t, userModel)
 
-	isStaff, err := restaurantModel.IsStaff(restaurant.ID, user.Id)
+	isStaff, err := restaurantModel.IsStaff(t.Context(), restaurant.ID, user.Id)
 	if err != nil {
 		t.Fatalf("IsStaff() before AddStaff() error = %v", err)
 	}
@@ -222,14 +239,14 @@ func TestRestaurantModel_Staff(t *testing.T) {
 		t.Error("IsStaff() should be false before AddStaff()")
 	}
 
-	if err := restaurantModel.AddStaff(restaurant.ID, user.Id, "manager"); err != nil {
+	if err := restaurantModel.AddStaff(t.Context(), restaurant.ID, user.Id, "manager"); err != nil {
 		t.Fatalf("AddStaff() error = %v", err)
 	}
 	t.Cleanup(func() {
-		restaurantModel.RemoveStaff(restaurant.ID, user.Id)
+		restaurantModel.RemoveStaff(context.Background(), restaurant.ID, user.Id)
 	})
 
-	isStaff, err = restaurantModel.IsStaff(restaurant.ID, user.Id)
+	isStaff, err = restaurantModel.IsStaff(t.Context(), restaurant.ID, user.Id)
 	if err != nil {
 		t.Fatalf("IsStaff() after AddStaff() error = %v", err)
 	}
@@ -237,7 +254,7 @@ func TestRestaurantModel_Staff(t *testing.T) {
 		t.Error("IsStaff() should be true after AddStaff()")
 	}
 
-	role, err := restaurantModel.GetStaffRole(restaurant.ID, user.Id)
+	role, err := restaurantModel.GetStaffRole(t.Context(), restaurant.ID, user.Id)
 	if err != nil {
 		t.Fatalf("GetStaffRole() error = %v", err)
 	}
@@ -245,7 +262,7 @@ func TestRestaurantModel_Staff(t *testing.T) {
 		t.Errorf("GetStaffRole() = %q, want manager", role)
 	}
 
-	staff, err := restaurantModel.GetStaff(restaurant.ID)
+	staff, err := restaurantModel.GetStaff(t.Context(), restaurant.ID)
 	if err != nil {
 		t.Fatalf("GetStaff() error = %v", err)
 	}
@@ -256,11 +273,11 @@ func TestRestaurantModel_Staff(t *testing.T) {
 		t.Errorf("GetStaff() user id = %d, want %d", staff[0].Id, user.Id)
 	}
 
-	if err := restaurantModel.RemoveStaff(restaurant.ID, user.Id); err != nil {
+	if err := restaurantModel.RemoveStaff(t.Context(), restaurant.ID, user.Id); err != nil {
 		t.Fatalf("RemoveStaff() error = %v", err)
 	}
 
-	role, err = restaurantModel.GetStaffRole(restaurant.ID, user.Id)
+	role, err = restaurantModel.GetStaffRole(t.Context(), restaurant.ID, user.Id)
 	if err != ErrRecordNotFound {
 		t.Errorf("GetStaffRole() after RemoveStaff() error = %v, want ErrRecordNotFound", err)
 	}
@@ -272,7 +289,7 @@ func TestRestaurantModel_Staff(t *testing.T) {
 func TestRestaurantModel_RemoveStaff_NotFound(t *testing.T) {
 	model := RestaurantModel{DB: testDB}
 
-	err := model.RemoveStaff(999999, 999999)
+	err := model.RemoveStaff(t.Context(), 999999, 999999)
 	if err != ErrRecordNotFound {
 		t.Errorf("RemoveStaff() error = %v, want ErrRecordNotFound", err)
 	}
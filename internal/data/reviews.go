@@ -0,0 +1,352 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+var (
+	ErrDuplicateReview = errors.New("duplicate review")
+	ErrAlreadyReplied  = errors.New("review already replied")
+)
+
+type Review struct {
+	ID           int64        `json:"id"`
+	OrderID      int64        `json:"order_id"`
+	UserID       int64        `json:"user_id"`
+	RestaurantID int64        `json:"restaurant_id"`
+	Rating       int          `json:"rating"`
+	Comment      string       `json:"comment,omitempty"`
+	Dishes       []DishRating `json:"dishes"`
+	Reply        string       `json:"reply,omitempty"`
+	RepliedAt    *time.Time   `json:"replied_at,omitempty"`
+	Hidden       bool         `json:"hidden,omitempty"`
+	HiddenReason string       `json:"hidden_reason,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	Version      int          `json:"-"`
+}
+
+type DishRating struct {
+	DishID   int64  `json:"dish_id"`
+	DishName string `json:"dish_name,omitempty"`
+	Rating   int    `json:"rating"`
+}
+
+func ValidateRating(v *validator.Validator, key string, rating int) {
+	v.Check(rating >= 1 && rating <= 5, key, "must be between 1 and 5")
+}
+
+func ValidateReview(v *validator.Validator, review *Review) {
+	ValidateRating(v, "rating", review.Rating)
+	v.Check(utf8.RuneCountInString(review.Comment) <= 2000, "comment", "must be no more than 2000 characters long")
+
+	dishIDs := make([]int64, 0, len(review.Dishes))
+	for _, dish := range review.Dishes {
+		ValidateRating(v, "dishes", dish.Rating)
+		dishIDs = append(dishIDs, dish.DishID)
+	}
+	v.Check(validator.Unique(dishIDs), "dishes", "must not rate the same dish more than once")
+}
+
+func ValidateReply(v *validator.Validator, reply string) {
+	v.Check(reply != "", "reply", "must be provided")
+	v.Check(utf8.RuneCountInString(reply) <= 2000, "reply", "must be no more than 2000 characters long")
+}
+
+type ReviewModel struct {
+	DB           *sql.DB
+	QueryTimeout time.Duration
+}
+
+// Insert stores the review together with its dish ratings in a single transaction.
+// Each order can only be reviewed once, so a second review returns ErrDuplicateReview
+func (m ReviewModel) Insert(ctx context.Context, review *Review) error {
+	ctx, span := startSpan(ctx, "ReviewModel.Insert")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO reviews (order_id, user_id, restaurant_id, rating, comment)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, version`
+
+	args := []any{review.OrderID, review.UserID, review.RestaurantID, review.Rating, review.Comment}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&review.ID, &review.CreatedAt, &review.Version)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "reviews_order_id_key":
+			return ErrDuplicateReview
+		default:
+			return err
+		}
+	}
+
+	query = `
+		INSERT INTO dish_reviews (review_id, dish_id, rating)
+		VALUES ($1, $2, $3)`
+
+	for _, dish := range review.Dishes {
+		_, err = tx.ExecContext(ctx, query, review.ID, dish.DishID, dish.Rating)
+		if err != nil {
+			return err
+		}
+	}
+
+	if review.Dishes == nil {
+		review.Dishes = []DishRating{}
+	}
+
+	return tx.Commit()
+}
+
+func (m ReviewModel) Get(ctx context.Context, id int64) (*Review, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, order_id, user_id, restaurant_id, rating, comment, reply, replied_at, hidden, hidden_reason, created_at, version
+		FROM reviews
+		WHERE id = $1`
+
+	var review Review
+
+	ctx, span := startSpan(ctx, "ReviewModel.Get")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&review.ID,
+		&review.OrderID,
+		&review.UserID,
+		&review.RestaurantID,
+		&review.Rating,
+		&review.Comment,
+		&review.Reply,
+		&review.RepliedAt,
+		&review.Hidden,
+		&review.HiddenReason,
+		&review.CreatedAt,
+		&review.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = m.loadDishRatings(ctx, []*Review{&review})
+	if err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+// GetAll lists reviews, optionally restricted to one restaurant (when restaurantID
+// is not zero) and to hidden or visible reviews
+func (m ReviewModel) GetAll(ctx context.Context, restaurantID int64, hidden sql.NullBool, filters Filters) ([]*Review, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), id, order_id, user_id, restaurant_id, rating, comment, reply, replied_at, hidden, hidden_reason, created_at, version
+		FROM reviews
+		WHERE (restaurant_id = $1 OR $1 = 0)
+		AND (hidden = $2 OR $2 IS NULL)
+		ORDER BY %s %s, id DESC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, span := startSpan(ctx, "ReviewModel.GetAll")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, restaurantID, hidden, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	reviews := []*Review{}
+
+	for rows.Next() {
+		var review Review
+
+		err := rows.Scan(
+			&totalRecords,
+			&review.ID,
+			&review.OrderID,
+			&review.UserID,
+			&review.RestaurantID,
+			&review.Rating,
+			&review.Comment,
+			&review.Reply,
+			&review.RepliedAt,
+			&review.Hidden,
+			&review.HiddenReason,
+			&review.CreatedAt,
+			&review.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		reviews = append(reviews, &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	err = m.loadDishRatings(ctx, reviews)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return reviews, metadata, nil
+}
+
+// Reply stores the owner's public reply. Each review takes a single reply, so
+// replying again returns ErrAlreadyReplied
+func (m ReviewModel) Reply(ctx context.Context, id int64, restaurantID int64, reply string) (*Review, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		UPDATE reviews
+		SET reply = $1, replied_at = NOW(), version = version + 1
+		WHERE id = $2 AND restaurant_id = $3 AND reply = '' AND NOT hidden
+		RETURNING version`
+
+	ctx, span := startSpan(ctx, "ReviewModel.Reply")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	var version int
+
+	err := m.DB.QueryRowContext(ctx, query, reply, id, restaurantID).Scan(&version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, m.replyFailure(ctx, id, restaurantID)
+		default:
+			return nil, err
+		}
+	}
+
+	return m.Get(ctx, id)
+}
+
+// replyFailure tells apart a review that already has a reply from one that
+// doesn't exist for the restaurant, or that has been hidden by a moderator
+func (m ReviewModel) replyFailure(ctx context.Context, id int64, restaurantID int64) error {
+	review, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if review.RestaurantID != restaurantID || review.Hidden {
+		return ErrRecordNotFound
+	}
+
+	return ErrAlreadyReplied
+}
+
+// Update saves moderation changes to a review
+func (m ReviewModel) Update(ctx context.Context, review *Review) error {
+	query := `
+		UPDATE reviews
+		SET hidden = $1, hidden_reason = $2, version = version + 1
+		WHERE id = $3 AND version = $4
+		RETURNING version`
+
+	args := []any{review.Hidden, review.HiddenReason, review.ID, review.Version}
+
+	ctx, span := startSpan(ctx, "ReviewModel.Update")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&review.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadDishRatings fills in the dish ratings of every review with a single query
+func (m ReviewModel) loadDishRatings(ctx context.Context, reviews []*Review) error {
+	if len(reviews) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(reviews))
+	byID := make(map[int64]*Review, len(reviews))
+	for i, review := range reviews {
+		ids[i] = review.ID
+		review.Dishes = []DishRating{}
+		byID[review.ID] = review
+	}
+
+	query := `
+		SELECT dr.review_id, dr.dish_id, d.name, dr.rating
+		FROM dish_reviews dr
+		INNER JOIN dishes d ON d.id = dr.dish_id
+		WHERE dr.review_id = ANY($1)
+		ORDER BY dr.review_id, d.name`
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reviewID int64
+		var rating DishRating
+
+		err := rows.Scan(&reviewID, &rating.DishID, &rating.DishName, &rating.Rating)
+		if err != nil {
+			return err
+		}
+
+		review := byID[reviewID]
+		review.Dishes = append(review.Dishes, rating)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,193 @@
+package data
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/xtommas/food-backend/internal/validator"
+)
+
+// insertTestReview reviews a fresh delivered order that contains one dish, rating the dish too
+func insertTestReview(t *testing.T, restaurantID int64, rating int) *Review {
+	t.Helper()
+
+	user := insertTestUser(t, UserModel{DB: testDB})
+	order := insertTestOrder(t, OrderModel{DB: testDB}, user.Id, restaurantID)
+	order.Status = "delivered"
+	if err := (OrderModel{DB: testDB}).Update(t.Context(), order); err != nil {
+		t.Fatalf("failed to mark test order delivered: %v", err)
+	}
+	dish := insertTestDish(t, DishModel{DB: testDB}, restaurantID)
+
+	review := &Review{
+		OrderID:      order.ID,
+		UserID:       user.Id,
+		RestaurantID: restaurantID,
+		Rating:       rating,
+		Comment:      "Great food",
+		Dishes:       []DishRating{{DishID: dish.ID, Rating: rating}},
+	}
+
+	if err := (ReviewModel{DB: testDB}).Insert(t.Context(), review); err != nil {
+		t.Fatalf("failed to insert test review: %v", err)
+	}
+
+	return review
+}
+
+func TestValidateReview(t *testing.T) {
+	tests := []struct {
+		name      string
+		review    Review
+		wantValid bool
+	}{
+		{"valid", Review{Rating: 4, Dishes: []DishRating{{DishID: 1, Rating: 5}}}, true},
+		{"rating too low", Review{Rating: 0}, false},
+		{"rating too high", Review{Rating: 6}, false},
+		{"dish rating out of range", Review{Rating: 4, Dishes: []DishRating{{DishID: 1, Rating: 9}}}, false},
+		{"duplicate dish", Review{Rating: 4, Dishes: []DishRating{{DishID: 1, Rating: 5}, {DishID: 1, Rating: 3}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateReview(v, &tt.review)
+			if v.Valid() != tt.wantValid {
+				t.Errorf("ValidateReview() valid = %v, want %v (errors: %v)", v.Valid(), tt.wantValid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestReviewModel_InsertAndGet(t *testing.T) {
+	model := ReviewModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	review := insertTestReview(t, restaurantID, 4)
+
+	fetched, err := model.Get(t.Context(), review.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if fetched.Rating != 4 {
+		t.Errorf("Get() Rating = %d, want 4", fetched.Rating)
+	}
+	if len(fetched.Dishes) != 1 {
+		t.Fatalf("Get() returned %d dish ratings, want 1", len(fetched.Dishes))
+	}
+	if fetched.Dishes[0].DishName == "" {
+		t.Error("Get() did not load the dish name")
+	}
+}
+
+func TestReviewModel_Insert_Duplicate(t *testing.T) {
+	model := ReviewModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	review := insertTestReview(t, restaurantID, 4)
+
+	duplicate := &Review{
+		OrderID:      review.OrderID,
+		UserID:       review.UserID,
+		RestaurantID: restaurantID,
+		Rating:       1,
+	}
+
+	err := model.Insert(t.Context(), duplicate)
+	if err != ErrDuplicateReview {
+		t.Errorf("Insert() error = %v, want ErrDuplicateReview", err)
+	}
+}
+
+func TestReviewModel_GetAll_HidesModerated(t *testing.T) {
+	model := ReviewModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	visible := insertTestReview(t, restaurantID, 5)
+	hidden := insertTestReview(t, restaurantID, 1)
+
+	hidden.Hidden = true
+	hidden.HiddenReason = "spam"
+	if err := model.Update(t.Context(), hidden); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	filters := Filters{Page: 1, PageSize: 10, Sort: "id", SortSafelist: []string{"id"}}
+
+	reviews, metadata, err := model.GetAll(t.Context(), restaurantID, sql.NullBool{Bool: false, Valid: true}, filters)
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].ID != visible.ID {
+		t.Fatalf("GetAll() visible reviews = %v, want only review %d", reviews, visible.ID)
+	}
+	if metadata.TotalRecords != 1 {
+		t.Errorf("GetAll() TotalRecords = %d, want 1", metadata.TotalRecords)
+	}
+	if len(reviews[0].Dishes) != 1 {
+		t.Errorf("GetAll() loaded %d dish ratings, want 1", len(reviews[0].Dishes))
+	}
+
+	reviews, _, err = model.GetAll(t.Context(), restaurantID, sql.NullBool{}, filters)
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(reviews) != 2 {
+		t.Errorf("GetAll() without hidden filter returned %d reviews, want 2", len(reviews))
+	}
+}
+
+func TestReviewModel_Reply(t *testing.T) {
+	model := ReviewModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	review := insertTestReview(t, restaurantID, 4)
+
+	replied, err := model.Reply(t.Context(), review.ID, restaurantID, "Thanks for coming!")
+	if err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+	if replied.Reply != "Thanks for coming!" {
+		t.Errorf("Reply() Reply = %q, want %q", replied.Reply, "Thanks for coming!")
+	}
+	if replied.RepliedAt == nil {
+		t.Error("Reply() did not set RepliedAt")
+	}
+
+	_, err = model.Reply(t.Context(), review.ID, restaurantID, "Second reply")
+	if err != ErrAlreadyReplied {
+		t.Errorf("Reply() twice error = %v, want ErrAlreadyReplied", err)
+	}
+
+	_, err = model.Reply(t.Context(), review.ID, seedRestaurant(t), "Wrong restaurant")
+	if err != ErrRecordNotFound {
+		t.Errorf("Reply() for another restaurant error = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestRatingAggregates(t *testing.T) {
+	restaurantID := seedRestaurant(t)
+	first := insertTestReview(t, restaurantID, 5)
+	insertTestReview(t, restaurantID, 2)
+
+	hidden := insertTestReview(t, restaurantID, 1)
+	hidden.Hidden = true
+	if err := (ReviewModel{DB: testDB}).Update(t.Context(), hidden); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	restaurant, err := RestaurantModel{DB: testDB}.Get(t.Context(), restaurantID)
+	if err != nil {
+		t.Fatalf("Restaurants.Get() error = %v", err)
+	}
+	if restaurant.RatingCount != 2 {
+		t.Errorf("restaurant RatingCount = %d, want 2", restaurant.RatingCount)
+	}
+	if restaurant.Rating != 3.5 {
+		t.Errorf("restaurant Rating = %v, want 3.5", restaurant.Rating)
+	}
+
+	dish, err := DishModel{DB: testDB}.Get(t.Context(), first.Dishes[0].DishID)
+	if err != nil {
+		t.Fatalf("Dishes.Get() error = %v", err)
+	}
+	if dish.RatingCount != 1 || dish.Rating != 5 {
+		t.Errorf("dish rating = %v (%d), want 5 (1)", dish.Rating, dish.RatingCount)
+	}
+}
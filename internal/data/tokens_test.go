@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"testing"
 	"time"
 )
@@ -10,9 +11,9 @@ func TestTokenModel_New(t *testing.T) {
 	tokenModel := TokenModel{DB: testDB}
 	user := insertTestUser(t, userModel)
 
-	token, err := tokenModel.New(user.Id, time.Hour, ScopeAuthentication)
+	token, err := tokenModel.New(t.Context(), user.Id, time.Hour, ScopeAuthentication)
 	t.Cleanup(func() {
-		tokenModel.DeleteAllForUser(ScopeAuthentication, user.Id)
+		tokenModel.DeleteAllForUser(context.Background(), ScopeAuthentication, user.Id)
 	})
 
 	if err != nil {
@@ -40,15 +41,15 @@ func TestUserModel_GetForToken(t *testing.T) {
 	tokenModel := TokenModel{DB: testDB}
 	user := insertTestUser(t, userModel)
 
-	token, err := tokenModel.New(user.Id, time.Hour, ScopeAuthentication)
+	token, err := tokenModel.New(t.Context(), user.Id, time.Hour, ScopeAuthentication)
 	t.Cleanup(func() {
-		tokenModel.DeleteAllForUser(ScopeAuthentication, user.Id)
+		tokenModel.DeleteAllForUser(context.Background(), ScopeAuthentication, user.Id)
 	})
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
 	}
 
-	fetched, err := userModel.GetForToken(ScopeAuthentication, token.Plaintext)
+	fetched, err := userModel.GetForToken(t.Context(), ScopeAuthentication, token.Plaintext)
 	if err != nil {
 		t.Fatalf("GetForToken() error = %v", err)
 	}
@@ -60,7 +61,7 @@ func TestUserModel_GetForToken(t *testing.T) {
 func TestUserModel_GetForToken_NotFound(t *testing.T) {
 	userModel := UserModel{DB: testDB}
 
-	_, err := userModel.GetForToken(ScopeAuthentication, "AAAAAAAAAAAAAAAAAAAAAAAAAA")
+	_, err := userModel.GetForToken(t.Context(), ScopeAuthentication, "AAAAAAAAAAAAAAAAAAAAAAAAAA")
 	if err != ErrRecordNotFound {
 		t.Errorf("GetForToken() error = %v, want ErrRecordNotFound", err)
 	}
@@ -71,17 +72,51 @@ func TestTokenModel_DeleteAllForUser(t *testing.T) {
 	tokenModel := TokenModel{DB: testDB}
 	user := insertTestUser(t, userModel)
 
-	token, err := tokenModel.New(user.Id, time.Hour, ScopeAuthentication)
+	token, err := tokenModel.New(t.Context(), user.Id, time.Hour, ScopeAuthentication)
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
 	}
 
-	if err := tokenModel.DeleteAllForUser(ScopeAuthentication, user.Id); err != nil {
+	if err := tokenModel.DeleteAllForUser(t.Context(), ScopeAuthentication, user.Id); err != nil {
 		t.Fatalf("DeleteAllForUser() error = %v", err)
 	}
 
-	_, err = userModel.GetForToken(ScopeAuthentication, token.Plaintext)
+	_, err = userModel.GetForToken(t.Context(), ScopeAuthentication, token.Plaintext)
 	if err != ErrRecordNotFound {
 		t.Errorf("GetForToken() after DeleteAllForUser() error = %v, want ErrRecordNotFound", err)
 	}
 }
+
+func TestUserModel_GetForToken_EmailChange(t *testing.T) {
+	userModel := UserModel{DB: testDB}
+	tokenModel := TokenModel{DB: testDB}
+	user := insertTestUser(t, userModel)
+
+	user.PendingEmail = uniqueTestEmail(t)
+	if err := userModel.Update(t.Context(), user); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	token, err := tokenModel.New(t.Context(), user.Id, time.Hour, ScopeEmailChange)
+	t.Cleanup(func() {
+		tokenModel.DeleteAllForUser(context.Background(), ScopeEmailChange, user.Id)
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, err := userModel.GetForToken(t.Context(), ScopeActivation, token.Plaintext); err != ErrRecordNotFound {
+		t.Errorf("GetForToken() with another scope error = %v, want ErrRecordNotFound", err)
+	}
+
+	fetched, err := userModel.GetForToken(t.Context(), ScopeEmailChange, token.Plaintext)
+	if err != nil {
+		t.Fatalf("GetForToken() error = %v", err)
+	}
+	if fetched.PendingEmail != user.PendingEmail {
+		t.Errorf("GetForToken() PendingEmail = %q, want %q", fetched.PendingEmail, user.PendingEmail)
+	}
+	if fetched.Email != user.Email {
+		t.Errorf("GetForToken() Email = %q, want the current email %q", fetched.Email, user.Email)
+	}
+}
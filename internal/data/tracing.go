@@ -0,0 +1,25 @@
+package data
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/xtommas/food-backend/internal/data"
+
+// startSpan starts the span of a model method, as a child of the span in ctx.
+// Without a span in ctx, e.g. in background jobs, nothing is recorded, so
+// queries don't each start a trace of their own
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemNamePostgreSQL),
+	)
+}
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// useInMemoryTracing records the spans started during a test
+func useInMemoryTracing(t *testing.T) (*tracetest.InMemoryExporter, *sdktrace.TracerProvider) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+
+	return exporter, provider
+}
+
+func TestStartSpan_ChildOfRequestSpan(t *testing.T) {
+	exporter, provider := useInMemoryTracing(t)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "GET /restaurants")
+
+	_, span := startSpan(ctx, "RestaurantModel.Get")
+	span.End()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+
+	child := spans[0]
+	if child.Name != "RestaurantModel.Get" {
+		t.Errorf("span name = %q, want RestaurantModel.Get", child.Name)
+	}
+	if child.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("model span is not a child of the request span")
+	}
+	if child.SpanContext.TraceID() != parent.SpanContext().TraceID() {
+		t.Error("model span is not part of the request's trace")
+	}
+}
+
+func TestStartSpan_NoParent(t *testing.T) {
+	exporter, _ := useInMemoryTracing(t)
+
+	_, span := startSpan(context.Background(), "CourierLocationModel.DeleteOlderThan")
+	span.End()
+
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("recorded %d spans without a parent, want 0", len(spans))
+	}
+}
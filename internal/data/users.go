@@ -5,6 +5,8 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
@@ -13,7 +15,22 @@ import (
 	"github.com/xtommas/food-backend/internal/validator"
 )
 
-var ErrDuplicateEmail = errors.New("duplicate email")
+var (
+	ErrDuplicateEmail   = errors.New("duplicate email")
+	ErrLastAdmin        = errors.New("last admin")
+	ErrOrdersInProgress = errors.New("orders in progress")
+)
+
+// Roles lists the roles a user can have
+var Roles = []string{"customer", "courier", "admin"}
+
+// account statuses. Only active users can authenticate, and suspensions with
+// an end date expire on their own
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusBanned    = "banned"
+)
 
 var AnonymousUser = &User{}
 
@@ -27,6 +44,12 @@ type User struct {
 	Activated bool      `json:"activated"`
 	Version   int       `json:"-"`
 	Role      string    `json:"role"`
+	Status    string    `json:"status"`
+	// StatusReason and SuspendedUntil are only set for suspended or banned users
+	StatusReason   string     `json:"status_reason,omitempty"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	// PendingEmail is the address the user is changing to, until they confirm it
+	PendingEmail string `json:"pending_email,omitempty"`
 }
 
 func (u *User) IsAnonymous() bool {
@@ -37,6 +60,49 @@ func (u *User) IsAdmin() bool {
 	return u.Role == "admin"
 }
 
+func (u *User) IsCourier() bool {
+	return u.Role == "courier"
+}
+
+// Suspend suspends the user until the given time, or until they're reinstated
+// when until is nil
+func (u *User) Suspend(reason string, until *time.Time) {
+	u.Status = UserStatusSuspended
+	u.StatusReason = reason
+	u.SuspendedUntil = until
+}
+
+func (u *User) Ban(reason string) {
+	u.Status = UserStatusBanned
+	u.StatusReason = reason
+	u.SuspendedUntil = nil
+}
+
+func (u *User) Reinstate() {
+	u.Status = UserStatusActive
+	u.StatusReason = ""
+	u.SuspendedUntil = nil
+}
+
+// setSuspendedUntil stores the scanned end of the suspension, reinstating the
+// user if it has already passed. The next Update saves the user as active
+func (u *User) setSuspendedUntil(until sql.NullTime) {
+	u.SuspendedUntil = nil
+
+	if until.Valid {
+		u.SuspendedUntil = &until.Time
+	}
+
+	if u.Status == UserStatusSuspended && u.SuspendedUntil != nil && !u.SuspendedUntil.After(time.Now()) {
+		u.Reinstate()
+	}
+}
+
+func ValidateStatusReason(v *validator.Validator, reason string) {
+	v.Check(reason != "", "reason", "must be provided")
+	v.Check(len(reason) <= 500, "reason", "must be no more than 500 bytes long")
+}
+
 type password struct {
 	plaintext *string
 	hash      []byte
@@ -81,6 +147,10 @@ func ValidatePasswordPlaintext(v *validator.Validator, password string) {
 	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
 }
 
+func ValidateRole(v *validator.Validator, role string) {
+	v.Check(validator.PermittedValue(role, Roles...), "role", "must be one of customer, courier or admin")
+}
+
 func ValidateUser(v *validator.Validator, user *User) {
 	v.Check(user.Name != "", "name", "must be provided")
 	v.Check(len(user.Name) <= 500, "name", "must be no more than 500 bytes long")
@@ -97,18 +167,26 @@ func ValidateUser(v *validator.Validator, user *User) {
 }
 
 type UserModel struct {
-	DB *sql.DB
+	DB           *sql.DB
+	QueryTimeout time.Duration
 }
 
-func (m UserModel) Insert(user *User) error {
+func (m UserModel) Insert(ctx context.Context, user *User) error {
+	if user.Status == "" {
+		user.Status = UserStatusActive
+	}
+
 	query := `
-		INSERT INTO users (photo, name, email, password_hash, activated, role)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO users (photo, name, email, password_hash, activated, role, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, version`
 
-	args := []any{user.Photo, user.Name, user.Email, user.Password.hash, user.Activated, user.Role}
+	args := []any{user.Photo, user.Name, user.Email, user.Password.hash, user.Activated, user.Role, user.Status}
+
+	ctx, span := startSpan(ctx, "UserModel.Insert")
+	defer span.End()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Id, &user.CreatedAt, &user.Version)
@@ -125,15 +203,19 @@ func (m UserModel) Insert(user *User) error {
 	return nil
 }
 
-func (m UserModel) GetByEmail(email string) (*User, error) {
+func (m UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, photo, created_at, name, email, password_hash, activated, version, role
+		SELECT id, photo, created_at, name, email, password_hash, activated, version, role, status, status_reason, suspended_until, pending_email
 		FROM users
-		WHERE email = $1`
+		WHERE email = $1 AND deleted_at IS NULL`
 
 	var user User
+	var suspendedUntil sql.NullTime
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, span := startSpan(ctx, "UserModel.GetByEmail")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, email).Scan(
@@ -146,6 +228,10 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.Activated,
 		&user.Version,
 		&user.Role,
+		&user.Status,
+		&user.StatusReason,
+		&suspendedUntil,
+		&user.PendingEmail,
 	)
 
 	if err != nil {
@@ -157,13 +243,74 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		}
 	}
 
+	user.setSuspendedUntil(suspendedUntil)
+
 	return &user, nil
 }
 
-func (m UserModel) Update(user *User) error {
+// Update saves the user. Demoting the only admin left returns ErrLastAdmin
+func (m UserModel) Update(ctx context.Context, user *User) error {
+	ctx, span := startSpan(ctx, "UserModel.Update")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.update(ctx, tx, user)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateRole saves the user, whose role has been changed, and grants them the
+// codes that come with it in the same transaction, so a failed grant doesn't
+// leave the role saved without its permissions. Codes the user already has are
+// skipped
+func (m UserModel) UpdateRole(ctx context.Context, user *User, codes ...string) error {
+	ctx, span := startSpan(ctx, "UserModel.UpdateRole")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.update(ctx, tx, user)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, addPermissionsQuery, user.Id, pq.Array(codes))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m UserModel) update(ctx context.Context, tx *sql.Tx, user *User) error {
+	if user.Role != "admin" {
+		err := m.checkNotLastAdmin(ctx, tx, user.Id)
+		if err != nil {
+			return err
+		}
+	}
+
 	query := `
 		UPDATE users
-		SET photo = $1, name = $2, email = $3, password_hash = $4, activated = $5, version = version + 1, role = $8
+		SET photo = $1, name = $2, email = $3, password_hash = $4, activated = $5, version = version + 1, role = $8, status = $9, status_reason = $10, suspended_until = $11, pending_email = $12
 		WHERE id = $6 AND version = $7
 		RETURNING version`
 
@@ -176,12 +323,13 @@ func (m UserModel) Update(user *User) error {
 		user.Id,
 		user.Version,
 		user.Role,
+		user.Status,
+		user.StatusReason,
+		user.SuspendedUntil,
+		user.PendingEmail,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&user.Version)
 	if err != nil {
 		var pqErr *pq.Error
 		switch {
@@ -197,11 +345,136 @@ func (m UserModel) Update(user *User) error {
 	return nil
 }
 
-func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+// checkNotLastAdmin returns ErrLastAdmin when the user is the only admin. The
+// admins are locked until the transaction ends, so two admins demoting each
+// other at the same time can't leave the application without one
+func (m UserModel) checkNotLastAdmin(ctx context.Context, tx *sql.Tx, id int64) error {
+	var isAdmin bool
+
+	err := tx.QueryRowContext(ctx, `SELECT role = 'admin' FROM users WHERE id = $1`, id).Scan(&isAdmin)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	if !isAdmin {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM users WHERE role = 'admin' FOR UPDATE`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	admins := 0
+	for rows.Next() {
+		admins++
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+
+	return nil
+}
+
+// Anonymize deletes the user's account. The user row is kept with its
+// personal data replaced, so restaurants keep the orders and reviews that
+// point to it, and everything else that belongs to the user is deleted. Users
+// with orders that are still being prepared or delivered can't be deleted,
+// which returns ErrOrdersInProgress
+func (m UserModel) Anonymize(ctx context.Context, user *User) error {
+	ctx, span := startSpan(ctx, "UserModel.Anonymize")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.checkNotLastAdmin(ctx, tx, user.Id)
+	if err != nil {
+		return err
+	}
+
+	var inProgress bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM orders
+			WHERE (user_id = $1 OR courier_id = $1)
+			AND status NOT IN ('delivered', 'picked_up', 'served', 'cancelled')
+		)`
+
+	err = tx.QueryRowContext(ctx, query, user.Id).Scan(&inProgress)
+	if err != nil {
+		return err
+	}
+
+	if inProgress {
+		return ErrOrdersInProgress
+	}
+
+	// the email stays unique, and an empty password hash never matches
+	query = `
+		UPDATE users
+		SET photo = '', name = 'Deleted user', email = 'deleted-' || id || '@deleted.invalid', pending_email = '', password_hash = '',
+			activated = false, role = 'customer', status = 'active', status_reason = '', suspended_until = NULL,
+			deleted_at = NOW(), version = version + 1
+		WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+		RETURNING version`
+
+	err = tx.QueryRowContext(ctx, query, user.Id, user.Version).Scan(&user.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	queries := []string{
+		`UPDATE orders
+		SET address = CASE WHEN address = '' THEN '' ELSE 'deleted' END, delivery_instructions = '', latitude = 0, longitude = 0, has_destination = FALSE
+		WHERE user_id = $1`,
+		`DELETE FROM tokens WHERE user_id = $1`,
+		`DELETE FROM users_permissions WHERE user_id = $1`,
+		`DELETE FROM user_addresses WHERE user_id = $1`,
+		`DELETE FROM favorite_restaurants WHERE user_id = $1`,
+		`DELETE FROM favorite_dishes WHERE user_id = $1`,
+		`DELETE FROM restaurant_staff WHERE user_id = $1`,
+		`DELETE FROM courier_locations WHERE courier_id = $1`,
+	}
+
+	for _, query := range queries {
+		_, err = tx.ExecContext(ctx, query, user.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m UserModel) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 
 	query := `
-		SELECT users.id, users.photo, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version, users.role
+		SELECT users.id, users.photo, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version, users.role, users.status, users.status_reason, users.suspended_until, users.pending_email
 		FROM users
 		INNER JOIN tokens
 		ON users.id = tokens.user_id
@@ -213,8 +486,12 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	args := []any{tokenHash[:], tokenScope, time.Now()}
 
 	var user User
+	var suspendedUntil sql.NullTime
+
+	ctx, span := startSpan(ctx, "UserModel.GetForToken")
+	defer span.End()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
@@ -227,6 +504,10 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.Activated,
 		&user.Version,
 		&user.Role,
+		&user.Status,
+		&user.StatusReason,
+		&suspendedUntil,
+		&user.PendingEmail,
 	)
 	if err != nil {
 		switch {
@@ -237,18 +518,24 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		}
 	}
 
+	user.setSuspendedUntil(suspendedUntil)
+
 	return &user, nil
 }
 
-func (m UserModel) Get(id int64) (*User, error) {
+func (m UserModel) Get(ctx context.Context, id int64) (*User, error) {
 	query := `
-		SELECT id, photo, created_at, name, email, password_hash, activated, version, role
+		SELECT id, photo, created_at, name, email, password_hash, activated, version, role, status, status_reason, suspended_until, pending_email
 		FROM users
-		WHERE id = $1`
+		WHERE id = $1 AND deleted_at IS NULL`
 
 	var user User
+	var suspendedUntil sql.NullTime
+
+	ctx, span := startSpan(ctx, "UserModel.Get")
+	defer span.End()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(
@@ -261,6 +548,10 @@ func (m UserModel) Get(id int64) (*User, error) {
 		&user.Activated,
 		&user.Version,
 		&user.Role,
+		&user.Status,
+		&user.StatusReason,
+		&suspendedUntil,
+		&user.PendingEmail,
 	)
 
 	if err != nil {
@@ -272,5 +563,92 @@ func (m UserModel) Get(id int64) (*User, error) {
 		}
 	}
 
+	user.setSuspendedUntil(suspendedUntil)
+
 	return &user, nil
 }
+
+// UserFilters restricts the user list. Search matches part of the name or
+// email, Status treats expired suspensions as active, and empty fields don't
+// filter
+type UserFilters struct {
+	Search string
+	Role   string
+	Status string
+}
+
+// GetAll lists users for admins
+func (m UserModel) GetAll(ctx context.Context, userFilters UserFilters, filters Filters) ([]*User, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), id, photo, created_at, name, email, activated, version, role, status, status_reason, suspended_until, pending_email
+		FROM users
+		WHERE deleted_at IS NULL
+		AND (name ILIKE $1 OR email ILIKE $1 OR $1 = '')
+		AND (role = $2 OR $2 = '')
+		AND (CASE WHEN status = 'suspended' AND suspended_until <= NOW() THEN 'active' ELSE status END = $3 OR $3 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+
+	search := ""
+	if userFilters.Search != "" {
+		search = "%" + escapeLike(userFilters.Search) + "%"
+	}
+
+	args := []any{search, userFilters.Role, userFilters.Status, filters.limit(), filters.offset()}
+
+	ctx, span := startSpan(ctx, "UserModel.GetAll")
+	defer span.End()
+
+	ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	users := []*User{}
+
+	for rows.Next() {
+		var user User
+		var suspendedUntil sql.NullTime
+
+		err := rows.Scan(
+			&totalRecords,
+			&user.Id,
+			&user.Photo,
+			&user.CreatedAt,
+			&user.Name,
+			&user.Email,
+			&user.Activated,
+			&user.Version,
+			&user.Role,
+			&user.Status,
+			&user.StatusReason,
+			&suspendedUntil,
+			&user.PendingEmail,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		user.setSuspendedUntil(suspendedUntil)
+
+		users = append(users, &user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return users, metadata, nil
+}
+
+// escapeLike makes the wildcards of a LIKE pattern match themselves
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -1,6 +1,8 @@
 package data
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -43,7 +45,7 @@ func insertTestUser(t *testing.T, model UserModel) *User {
 	t.Helper()
 
 	user := newTestUser(t)
-	if err := model.Insert(user); err != nil {
+	if err := model.Insert(t.Context(), user); err != nil {
 		t.Fatalf("failed to insert test user: %v", err)
 	}
 
@@ -58,7 +60,7 @@ func TestUserModel_Insert(t *testing.T) {
 	model := UserModel{DB: testDB}
 	user := newTestUser(t)
 
-	err := model.Insert(user)
+	err := model.Insert(t.Context(), user)
 	t.Cleanup(func() {
 		testDB.Exec(`DELETE FROM users WHERE id = $1`, user.Id)
 	})
@@ -84,7 +86,7 @@ func TestUserModel_Insert_DuplicateEmail(t *testing.T) {
 	duplicate := newTestUser(t)
 	duplicate.Email = user.Email
 
-	err := model.Insert(duplicate)
+	err := model.Insert(t.Context(), duplicate)
 	if err != ErrDuplicateEmail {
 		t.Errorf("Insert() error = %v, want ErrDuplicateEmail", err)
 	}
@@ -94,7 +96,7 @@ func TestUserModel_GetByEmail(t *testing.T) {
 	model := UserModel{DB: testDB}
 	user := insertTestUser(t, model)
 
-	fetched, err := model.GetByEmail(user.Email)
+	fetched, err := model.GetByEmail(t.Context(), user.Email)
 	if err != nil {
 		t.Fatalf("GetByEmail() error = %v", err)
 	}
@@ -117,7 +119,7 @@ func TestUserModel_GetByEmail(t *testing.T) {
 func TestUserModel_GetByEmail_NotFound(t *testing.T) {
 	model := UserModel{DB: testDB}
 
-	_, err := model.GetByEmail(uniqueTestEmail(t))
+	_, err := model.GetByEmail(t.Context(), uniqueTestEmail(t))
 	if err != ErrRecordNotFound {
 		t.Errorf("GetByEmail() error = %v, want ErrRecordNotFound", err)
 	}
@@ -127,7 +129,7 @@ func TestUserModel_Get(t *testing.T) {
 	model := UserModel{DB: testDB}
 	user := insertTestUser(t, model)
 
-	fetched, err := model.Get(user.Id)
+	fetched, err := model.Get(t.Context(), user.Id)
 	if err != nil {
 		t.Fatalf("Get() error = %v", err)
 	}
@@ -143,7 +145,7 @@ func TestUserModel_Get(t *testing.T) {
 func TestUserModel_Get_NotFound(t *testing.T) {
 	model := UserModel{DB: testDB}
 
-	_, err := model.Get(999999)
+	_, err := model.Get(t.Context(), 999999)
 	if err != ErrRecordNotFound {
 		t.Errorf("Get() error = %v, want ErrRecordNotFound", err)
 	}
@@ -161,11 +163,11 @@ func TestUserModel_Update(t *testing.T) {
 		t.Fatalf("failed to set password: %v", err)
 	}
 
-	if err := model.Update(user); err != nil {
+	if err := model.Update(t.Context(), user); err != nil {
 		t.Fatalf("Update() error = %v", err)
 	}
 
-	fetched, err := model.Get(user.Id)
+	fetched, err := model.Get(t.Context(), user.Id)
 	if err != nil {
 		t.Fatalf("Get() after Update() error = %v", err)
 	}
@@ -195,12 +197,12 @@ func TestUserModel_Update_EditConflict(t *testing.T) {
 	stale := *user
 
 	user.Name = "Current Version"
-	if err := model.Update(user); err != nil {
+	if err := model.Update(t.Context(), user); err != nil {
 		t.Fatalf("Update() current user error = %v", err)
 	}
 
 	stale.Name = "Stale Version"
-	err := model.Update(&stale)
+	err := model.Update(t.Context(), &stale)
 	if err != ErrEditConflict {
 		t.Errorf("Update() stale user error = %v, want ErrEditConflict", err)
 	}
@@ -212,8 +214,292 @@ func TestUserModel_Update_DuplicateEmail(t *testing.T) {
 	second := insertTestUser(t, model)
 
 	second.Email = first.Email
-	err := model.Update(second)
+	err := model.Update(t.Context(), second)
 	if err != ErrDuplicateEmail {
 		t.Errorf("Update() error = %v, want ErrDuplicateEmail", err)
 	}
 }
+
+func insertTestAdmin(t *testing.T, model UserModel) *User {
+	t.Helper()
+
+	user := newTestUser(t)
+	user.Role = "admin"
+	if err := model.Insert(t.Context(), user); err != nil {
+		t.Fatalf("failed to insert test admin: %v", err)
+	}
+
+	t.Cleanup(func() {
+		testDB.Exec(`DELETE FROM users WHERE id = $1`, user.Id)
+	})
+
+	return user
+}
+
+func TestUserModel_Update_DemoteAdmin(t *testing.T) {
+	model := UserModel{DB: testDB}
+	first := insertTestAdmin(t, model)
+	insertTestAdmin(t, model)
+
+	first.Role = "customer"
+	if err := model.Update(t.Context(), first); err != nil {
+		t.Fatalf("Update() demoting one of two admins error = %v", err)
+	}
+}
+
+func TestUserModel_Update_LastAdmin(t *testing.T) {
+	model := UserModel{DB: testDB}
+	admin := insertTestAdmin(t, model)
+
+	var admins int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM users WHERE role = 'admin'`).Scan(&admins); err != nil {
+		t.Fatalf("failed to count admins: %v", err)
+	}
+	if admins > 1 {
+		t.Skip("the test database has other admins")
+	}
+
+	admin.Role = "customer"
+	err := model.Update(t.Context(), admin)
+	if err != ErrLastAdmin {
+		t.Errorf("Update() demoting the last admin error = %v, want ErrLastAdmin", err)
+	}
+
+	// other changes to the last admin are still allowed
+	admin.Role = "admin"
+	admin.Name = "Still Admin"
+	if err := model.Update(t.Context(), admin); err != nil {
+		t.Errorf("Update() renaming the last admin error = %v", err)
+	}
+}
+
+func TestUserModel_GetAll(t *testing.T) {
+	model := UserModel{DB: testDB}
+	user := insertTestUser(t, model)
+	insertTestUser(t, model)
+
+	filters := Filters{Page: 1, PageSize: 10, Sort: "id", SortSafelist: []string{"id"}}
+
+	users, metadata, err := model.GetAll(t.Context(), UserFilters{Search: user.Email}, filters)
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if metadata.TotalRecords != 1 || users[0].Id != user.Id {
+		t.Fatalf("GetAll() by email returned %d users, want only user %d", metadata.TotalRecords, user.Id)
+	}
+	if users[0].Status != UserStatusActive {
+		t.Errorf("Status = %q, want %q", users[0].Status, UserStatusActive)
+	}
+
+	_, metadata, err = model.GetAll(t.Context(), UserFilters{Search: user.Email, Status: UserStatusBanned}, filters)
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if metadata.TotalRecords != 0 {
+		t.Errorf("GetAll() banned users returned %d users, want 0", metadata.TotalRecords)
+	}
+
+	// wildcards in the search match themselves
+	_, metadata, err = model.GetAll(t.Context(), UserFilters{Search: "%_%@example.com"}, filters)
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if metadata.TotalRecords != 0 {
+		t.Errorf("GetAll() with a wildcard search returned %d users, want 0", metadata.TotalRecords)
+	}
+}
+
+func TestUser_setSuspendedUntil(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		status     string
+		until      sql.NullTime
+		wantStatus string
+	}{
+		{"suspended indefinitely", UserStatusSuspended, sql.NullTime{}, UserStatusSuspended},
+		{"suspension not over", UserStatusSuspended, sql.NullTime{Time: future, Valid: true}, UserStatusSuspended},
+		{"suspension over", UserStatusSuspended, sql.NullTime{Time: past, Valid: true}, UserStatusActive},
+		{"banned", UserStatusBanned, sql.NullTime{}, UserStatusBanned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &User{Status: tt.status, StatusReason: "spam"}
+			user.setSuspendedUntil(tt.until)
+
+			if user.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", user.Status, tt.wantStatus)
+			}
+			if tt.wantStatus == UserStatusActive && (user.StatusReason != "" || user.SuspendedUntil != nil) {
+				t.Error("an expired suspension should clear the reason and end date")
+			}
+		})
+	}
+}
+
+func TestUserModel_Update_Suspend(t *testing.T) {
+	model := UserModel{DB: testDB}
+	user := insertTestUser(t, model)
+
+	until := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+	user.Suspend("chargebacks", &until)
+
+	if err := model.Update(t.Context(), user); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	fetched, err := model.Get(t.Context(), user.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if fetched.Status != UserStatusSuspended || fetched.StatusReason != "chargebacks" {
+		t.Errorf("Get() status = %q (%q), want suspended (chargebacks)", fetched.Status, fetched.StatusReason)
+	}
+	if fetched.SuspendedUntil == nil || !fetched.SuspendedUntil.Equal(until) {
+		t.Errorf("SuspendedUntil = %v, want %v", fetched.SuspendedUntil, until)
+	}
+
+	// end the suspension without going through the model, as if time had passed
+	if _, err := testDB.Exec(`UPDATE users SET suspended_until = NOW() - INTERVAL '1 minute' WHERE id = $1`, user.Id); err != nil {
+		t.Fatalf("failed to end suspension: %v", err)
+	}
+
+	fetched, err = model.Get(t.Context(), user.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if fetched.Status != UserStatusActive {
+		t.Errorf("Get() after the suspension ended status = %q, want active", fetched.Status)
+	}
+
+	_, metadata, err := model.GetAll(t.Context(), UserFilters{Search: user.Email, Status: UserStatusSuspended}, Filters{Page: 1, PageSize: 10, Sort: "id", SortSafelist: []string{"id"}})
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if metadata.TotalRecords != 0 {
+		t.Errorf("GetAll() suspended users included an expired suspension")
+	}
+}
+
+func TestUserModel_Anonymize(t *testing.T) {
+	model := UserModel{DB: testDB}
+	orderModel := OrderModel{DB: testDB}
+	restaurantID := seedRestaurant(t)
+	user := insertTestUser(t, model)
+	insertTestAddress(t, AddressModel{DB: testDB}, user.Id, true)
+
+	order := insertTestOrder(t, orderModel, user.Id, restaurantID)
+	order.Status = "cancelled"
+	if err := orderModel.Update(t.Context(), order); err != nil {
+		t.Fatalf("failed to cancel test order: %v", err)
+	}
+
+	if err := model.Anonymize(t.Context(), user); err != nil {
+		t.Fatalf("Anonymize() error = %v", err)
+	}
+
+	if _, err := model.Get(t.Context(), user.Id); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("Get() after Anonymize() error = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := model.GetByEmail(t.Context(), user.Email); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("GetByEmail() after Anonymize() error = %v, want ErrRecordNotFound", err)
+	}
+
+	kept, err := orderModel.GetForRestaurant(t.Context(), order.ID, restaurantID)
+	if err != nil {
+		t.Fatalf("GetForRestaurant() after Anonymize() error = %v", err)
+	}
+	if kept.Address == order.Address {
+		t.Error("Anonymize() kept the delivery address of the order")
+	}
+
+	addresses, err := (AddressModel{DB: testDB}).GetAllForUser(t.Context(), user.Id)
+	if err != nil {
+		t.Fatalf("GetAllForUser() error = %v", err)
+	}
+	if len(addresses) != 0 {
+		t.Errorf("Anonymize() kept %d addresses, want 0", len(addresses))
+	}
+}
+
+func TestUserModel_Anonymize_OrdersInProgress(t *testing.T) {
+	model := UserModel{DB: testDB}
+	user := insertTestUser(t, model)
+	insertTestOrder(t, OrderModel{DB: testDB}, user.Id, seedRestaurant(t))
+
+	err := model.Anonymize(t.Context(), user)
+	if !errors.Is(err, ErrOrdersInProgress) {
+		t.Errorf("Anonymize() with a pending order error = %v, want ErrOrdersInProgress", err)
+	}
+}
+
+func TestUserModel_DeleteKeepsOrders(t *testing.T) {
+	user := insertTestUser(t, UserModel{DB: testDB})
+	insertTestOrder(t, OrderModel{DB: testDB}, user.Id, seedRestaurant(t))
+
+	if _, err := testDB.Exec(`DELETE FROM users WHERE id = $1`, user.Id); err == nil {
+		t.Error("deleting a user with orders should fail instead of deleting the orders")
+	}
+}
+
+func TestUserModel_UpdateRole(t *testing.T) {
+	model := UserModel{DB: testDB}
+	permissions := PermissionModel{DB: testDB}
+	user := insertTestUser(t, model)
+
+	// codes the user already has don't fail the grant
+	if err := permissions.AddForUser(t.Context(), user.Id, "orders:read"); err != nil {
+		t.Fatalf("AddForUser() error = %v", err)
+	}
+
+	user.Role = "courier"
+	if err := model.UpdateRole(t.Context(), user, "orders:read", "orders:write"); err != nil {
+		t.Fatalf("UpdateRole() error = %v", err)
+	}
+
+	fetched, err := model.Get(t.Context(), user.Id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if fetched.Role != "courier" {
+		t.Errorf("UpdateRole() Role = %q, want courier", fetched.Role)
+	}
+
+	granted, err := permissions.GetAllForUser(t.Context(), user.Id)
+	if err != nil {
+		t.Fatalf("GetAllForUser() error = %v", err)
+	}
+	if !granted.Include("orders:read") || !granted.Include("orders:write") {
+		t.Errorf("UpdateRole() permissions = %v, want orders:read and orders:write", granted)
+	}
+}
+
+func TestUserModel_UpdateRole_EditConflict(t *testing.T) {
+	model := UserModel{DB: testDB}
+	permissions := PermissionModel{DB: testDB}
+	user := insertTestUser(t, model)
+
+	stale := *user
+
+	user.Name = "Current Version"
+	if err := model.Update(t.Context(), user); err != nil {
+		t.Fatalf("Update() current user error = %v", err)
+	}
+
+	stale.Role = "courier"
+	if err := model.UpdateRole(t.Context(), &stale, "orders:write"); err != ErrEditConflict {
+		t.Fatalf("UpdateRole() stale user error = %v, want ErrEditConflict", err)
+	}
+
+	// the grant is rolled back with the role change
+	granted, err := permissions.GetAllForUser(t.Context(), user.Id)
+	if err != nil {
+		t.Fatalf("GetAllForUser() error = %v", err)
+	}
+	if granted.Include("orders:write") {
+		t.Error("UpdateRole() granted orders:write although the update failed")
+	}
+}
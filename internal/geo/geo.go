@@ -0,0 +1,23 @@
+package geo
+
+import "math"
+
+// mean radius of the Earth in metres
+const earthRadius = 6_371_000
+
+// Distance returns the great-circle distance in metres between two points
+// given in decimal degrees, using the haversine formula
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := radians(lat1)
+	phi2 := radians(lat2)
+	dPhi := radians(lat2 - lat1)
+	dLambda := radians(lon2 - lon1)
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
@@ -0,0 +1,28 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", -34.603722, -58.381592, -34.603722, -58.381592, 0},
+		{"one degree of latitude", 0, 0, 1, 0, 111_195},
+		{"Buenos Aires to Montevideo", -34.603722, -58.381592, -34.901112, -56.164532, 204_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Distance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			// allow 1% error for the spherical approximation
+			if math.Abs(got-tt.want) > tt.want*0.01+1 {
+				t.Errorf("Distance() = %.0f, want %.0f", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package geo
+
+// Point is a position in decimal degrees
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// InPolygon reports whether p lies inside the polygon described by its vertices,
+// using the even-odd ray casting rule. The polygon doesn't need to be closed (the
+// last vertex connects back to the first) and may be concave. Coordinates are treated
+// as planar, which is accurate enough for city-sized areas that don't cross the
+// antimeridian. Points exactly on an edge may fall on either side
+func InPolygon(polygon []Point, p Point) bool {
+	if len(polygon) < 3 {
+		return false
+	}
+
+	inside := false
+
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+
+		// only edges that straddle the point's latitude can cross the ray
+		if (a.Latitude > p.Latitude) == (b.Latitude > p.Latitude) {
+			continue
+		}
+
+		// longitude at which the edge crosses the point's latitude
+		crossing := a.Longitude + (p.Latitude-a.Latitude)*(b.Longitude-a.Longitude)/(b.Latitude-a.Latitude)
+		if p.Longitude < crossing {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// InCircle reports whether p lies within radius metres of center
+func InCircle(center Point, radius float64, p Point) bool {
+	return Distance(center.Latitude, center.Longitude, p.Latitude, p.Longitude) <= radius
+}
@@ -0,0 +1,98 @@
+package geo
+
+import "testing"
+
+func TestInPolygon(t *testing.T) {
+	square := []Point{
+		{Latitude: 0, Longitude: 0},
+		{Latitude: 0, Longitude: 10},
+		{Latitude: 10, Longitude: 10},
+		{Latitude: 10, Longitude: 0},
+	}
+
+	// a "U" shape, open towards the north, to exercise concave polygons
+	concave := []Point{
+		{Latitude: 0, Longitude: 0},
+		{Latitude: 0, Longitude: 9},
+		{Latitude: 9, Longitude: 9},
+		{Latitude: 9, Longitude: 6},
+		{Latitude: 3, Longitude: 6},
+		{Latitude: 3, Longitude: 3},
+		{Latitude: 9, Longitude: 3},
+		{Latitude: 9, Longitude: 0},
+	}
+
+	// a neighbourhood of Buenos Aires, in real coordinates
+	palermo := []Point{
+		{Latitude: -34.5700, Longitude: -58.4400},
+		{Latitude: -34.5700, Longitude: -58.4000},
+		{Latitude: -34.6000, Longitude: -58.4000},
+		{Latitude: -34.6000, Longitude: -58.4400},
+	}
+
+	tests := []struct {
+		name    string
+		polygon []Point
+		point   Point
+		want    bool
+	}{
+		{"inside square", square, Point{5, 5}, true},
+		{"outside square to the east", square, Point{5, 15}, false},
+		{"outside square to the south", square, Point{-1, 5}, false},
+		{"level with a vertex but outside", square, Point{10, 20}, false},
+		{"inside left arm of concave", concave, Point{6, 1.5}, true},
+		{"inside right arm of concave", concave, Point{6, 7.5}, true},
+		{"inside the notch of concave", concave, Point{6, 4.5}, false},
+		{"inside the base of concave", concave, Point{1.5, 4.5}, true},
+		{"inside real neighbourhood", palermo, Point{-34.5850, -58.4200}, true},
+		{"outside real neighbourhood", palermo, Point{-34.6037, -58.3816}, false},
+		{"degenerate polygon", square[:2], Point{0, 5}, false},
+		{"empty polygon", nil, Point{0, 0}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InPolygon(tt.polygon, tt.point); got != tt.want {
+				t.Errorf("InPolygon() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInPolygon_WindingOrder(t *testing.T) {
+	clockwise := []Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	counterClockwise := []Point{{0, 10}, {10, 10}, {10, 0}, {0, 0}}
+	point := Point{5, 5}
+
+	if !InPolygon(clockwise, point) {
+		t.Error("InPolygon() = false for clockwise polygon, want true")
+	}
+	if !InPolygon(counterClockwise, point) {
+		t.Error("InPolygon() = false for counter-clockwise polygon, want true")
+	}
+}
+
+func TestInCircle(t *testing.T) {
+	center := Point{Latitude: -34.603722, Longitude: -58.381592}
+
+	tests := []struct {
+		name   string
+		radius float64
+		point  Point
+		want   bool
+	}{
+		{"center", 1000, center, true},
+		// one thousandth of a degree of latitude is about 111 metres
+		{"just inside", 120, Point{-34.602722, -58.381592}, true},
+		{"just outside", 100, Point{-34.602722, -58.381592}, false},
+		{"another city", 50_000, Point{-34.901112, -56.164532}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InCircle(center, tt.radius, tt.point); got != tt.want {
+				t.Errorf("InCircle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
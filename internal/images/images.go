@@ -0,0 +1,154 @@
+// Package images validates uploaded photos and re-encodes them into a fixed set
+// of resized variants. Re-encoding also drops any metadata, such as EXIF, that
+// came with the upload.
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"path"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	SizeThumbnail = "thumbnail"
+	SizeMedium    = "medium"
+	SizeLarge     = "large"
+)
+
+// Sizes lists the variants stored for every upload, largest first
+var Sizes = []string{SizeLarge, SizeMedium, SizeThumbnail}
+
+// each variant fits in a square of this many pixels, keeping the aspect ratio.
+// Images smaller than the box are never upscaled
+var sizeBox = map[string]int{
+	SizeLarge:     1600,
+	SizeMedium:    800,
+	SizeThumbnail: 200,
+}
+
+const (
+	MinDimension = 32
+	MaxDimension = 8000
+	// caps the memory needed to decode images that are long and thin
+	maxPixels = 40_000_000
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("must be a JPEG, PNG or WebP image")
+	ErrTooSmall          = fmt.Errorf("must be at least %dx%d pixels", MinDimension, MinDimension)
+	ErrTooLarge          = fmt.Errorf("must be no larger than %dx%d pixels", MaxDimension, MaxDimension)
+)
+
+var supportedFormats = map[string]bool{"jpeg": true, "png": true, "webp": true}
+
+type Variant struct {
+	Size   string
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Photo is a processed upload. Opaque images are encoded as JPEG and images
+// with transparency as PNG
+type Photo struct {
+	Ext         string
+	ContentType string
+	Variants    []Variant
+}
+
+// Process decodes an uploaded image, checks its format and dimensions, applies
+// the EXIF orientation of JPEGs and encodes each variant in Sizes
+func Process(data []byte) (*Photo, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !supportedFormats[format] {
+		return nil, ErrUnsupportedFormat
+	}
+
+	// checked before decoding, so oversized images are never held in memory
+	switch {
+	case config.Width < MinDimension || config.Height < MinDimension:
+		return nil, ErrTooSmall
+	case config.Width > MaxDimension || config.Height > MaxDimension || config.Width*config.Height > maxPixels:
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	// the boxes are square, so the variants can be scaled before they are rotated
+	large := orient(fit(src, sizeBox[SizeLarge]), orientation)
+
+	photo := &Photo{Ext: ".jpg", ContentType: "image/jpeg"}
+	if !large.Opaque() {
+		photo.Ext, photo.ContentType = ".png", "image/png"
+	}
+
+	for _, size := range Sizes {
+		img := large
+		if size != SizeLarge {
+			img = fit(large, sizeBox[size])
+		}
+
+		var buf bytes.Buffer
+
+		if photo.ContentType == "image/png" {
+			err = png.Encode(&buf, img)
+		} else {
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		bounds := img.Bounds()
+		photo.Variants = append(photo.Variants, Variant{Size: size, Width: bounds.Dx(), Height: bounds.Dy(), Data: buf.Bytes()})
+	}
+
+	return photo, nil
+}
+
+// VariantKey returns the storage key of one size of a photo, e.g. "dishes/1.jpg"
+// becomes "dishes/1_thumbnail.jpg"
+func VariantKey(key, size string) string {
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + "_" + size + ext
+}
+
+// fit scales img down to fit in a box x box square, or copies it when it already fits
+func fit(img image.Image, box int) *image.NRGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width > box || height > box {
+		if width >= height {
+			width, height = box, max(1, height*box/width)
+		} else {
+			width, height = max(1, width*box/height), box
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	if width == bounds.Dx() && height == bounds.Dy() {
+		draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	} else {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	}
+
+	return dst
+}
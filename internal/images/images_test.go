@@ -0,0 +1,229 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func newTestImage(width, height int, fill color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, fill)
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF APP1 segment carrying the orientation tag right after the SOI marker
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	ifd := make([]byte, 2+12+4)
+	binary.BigEndian.PutUint16(ifd[0:], 1)
+	binary.BigEndian.PutUint16(ifd[2:], 0x0112)
+	binary.BigEndian.PutUint16(ifd[4:], 3) // SHORT
+	binary.BigEndian.PutUint32(ifd[6:], 1)
+	binary.BigEndian.PutUint16(ifd[10:], orientation)
+
+	payload := append([]byte("Exif\x00\x00"), append(tiff, ifd...)...)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func variant(t *testing.T, photo *Photo, size string) Variant {
+	t.Helper()
+	for _, v := range photo.Variants {
+		if v.Size == size {
+			return v
+		}
+	}
+	t.Fatalf("photo has no %s variant", size)
+	return Variant{}
+}
+
+func TestProcess_Variants(t *testing.T) {
+	data := encodeJPEG(t, newTestImage(3200, 1600, color.NRGBA{200, 30, 30, 255}))
+
+	photo, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if photo.Ext != ".jpg" || photo.ContentType != "image/jpeg" {
+		t.Errorf("Process() = %s (%s), want .jpg (image/jpeg)", photo.Ext, photo.ContentType)
+	}
+
+	want := map[string][2]int{
+		SizeLarge:     {1600, 800},
+		SizeMedium:    {800, 400},
+		SizeThumbnail: {200, 100},
+	}
+
+	for size, dims := range want {
+		v := variant(t, photo, size)
+		if v.Width != dims[0] || v.Height != dims[1] {
+			t.Errorf("%s variant = %dx%d, want %dx%d", size, v.Width, v.Height, dims[0], dims[1])
+		}
+
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(v.Data))
+		if err != nil || format != "jpeg" || cfg.Width != dims[0] {
+			t.Errorf("%s variant does not decode as a %dpx wide JPEG: %v", size, dims[0], err)
+		}
+	}
+}
+
+func TestProcess_NoUpscaling(t *testing.T) {
+	photo, err := Process(encodePNG(t, newTestImage(100, 60, color.NRGBA{0, 0, 255, 255})))
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if v := variant(t, photo, SizeLarge); v.Width != 100 || v.Height != 60 {
+		t.Errorf("large variant = %dx%d, want the original 100x60", v.Width, v.Height)
+	}
+
+	// an opaque PNG is converted to JPEG
+	if photo.ContentType != "image/jpeg" {
+		t.Errorf("ContentType = %q, want image/jpeg", photo.ContentType)
+	}
+}
+
+func TestProcess_KeepsTransparency(t *testing.T) {
+	photo, err := Process(encodePNG(t, newTestImage(64, 64, color.NRGBA{0, 0, 0, 0})))
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if photo.Ext != ".png" || photo.ContentType != "image/png" {
+		t.Errorf("Process() = %s (%s), want .png (image/png)", photo.Ext, photo.ContentType)
+	}
+}
+
+func TestProcess_Rejects(t *testing.T) {
+	gif := []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;")
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{name: "not an image", data: []byte("#!/bin/sh\necho hello"), want: ErrUnsupportedFormat},
+		{name: "empty", data: nil, want: ErrUnsupportedFormat},
+		{name: "gif", data: gif, want: ErrUnsupportedFormat},
+		{name: "too small", data: encodePNG(t, newTestImage(16, 16, color.White)), want: ErrTooSmall},
+		{name: "too wide", data: encodePNG(t, image.NewGray(image.Rect(0, 0, MaxDimension+1, 40))), want: ErrTooLarge},
+		{name: "truncated", data: encodeJPEG(t, newTestImage(64, 64, color.White))[:200], want: ErrUnsupportedFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Process(tt.data)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Process() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcess_StripsEXIFAndAppliesOrientation(t *testing.T) {
+	// a 80x40 image stored sideways, to be turned 90° clockwise for display
+	src := newTestImage(80, 40, color.NRGBA{255, 255, 255, 255})
+	src.Set(0, 0, color.NRGBA{0, 0, 0, 255})
+	data := withOrientation(encodeJPEG(t, src), 6)
+
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("jpegOrientation() = %d, want 6", got)
+	}
+
+	photo, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	large := variant(t, photo, SizeLarge)
+	if large.Width != 40 || large.Height != 80 {
+		t.Errorf("large variant = %dx%d, want 40x80", large.Width, large.Height)
+	}
+	if bytes.Contains(large.Data, []byte("Exif")) {
+		t.Error("large variant still contains EXIF data")
+	}
+	if got := jpegOrientation(large.Data); got != 1 {
+		t.Errorf("jpegOrientation() of the output = %d, want 1", got)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// 2x1 image: red on the left, blue on the right
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation   int
+		width, height int
+		redAt         image.Point
+	}{
+		{1, 2, 1, image.Pt(0, 0)},
+		{2, 2, 1, image.Pt(1, 0)},
+		{3, 2, 1, image.Pt(1, 0)},
+		{4, 2, 1, image.Pt(0, 0)},
+		{5, 1, 2, image.Pt(0, 0)},
+		{6, 1, 2, image.Pt(0, 0)},
+		{7, 1, 2, image.Pt(0, 1)},
+		{8, 1, 2, image.Pt(0, 1)},
+	}
+
+	for _, tt := range tests {
+		dst := orient(src, tt.orientation)
+
+		if dst.Bounds().Dx() != tt.width || dst.Bounds().Dy() != tt.height {
+			t.Errorf("orient(%d) size = %v, want %dx%d", tt.orientation, dst.Bounds().Size(), tt.width, tt.height)
+			continue
+		}
+		if dst.NRGBAAt(tt.redAt.X, tt.redAt.Y) != red {
+			t.Errorf("orient(%d) red pixel not at %v", tt.orientation, tt.redAt)
+		}
+	}
+}
+
+func TestVariantKey(t *testing.T) {
+	if got := VariantKey("dishes/1.jpg", SizeThumbnail); got != "dishes/1_thumbnail.jpg" {
+		t.Errorf("VariantKey() = %q, want dishes/1_thumbnail.jpg", got)
+	}
+	if got := VariantKey("users/7.png", SizeLarge); got != "users/7_large.png" {
+		t.Errorf("VariantKey() = %q, want users/7_large.png", got)
+	}
+}
@@ -2,9 +2,13 @@ package jsonlog
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"maps"
 	"os"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -15,40 +19,106 @@ type Level int8
 // consts that represent a specific security level.
 // use iota as a shortcut to assign successive integer values
 const (
-	LevelInfo  Level = iota // 0
-	LevelError              // 1
-	LevelFatal              // 2
-	LevelOff                // 3
+	LevelDebug Level = iota // 0
+	LevelInfo               // 1
+	LevelError              // 2
+	LevelFatal              // 3
+	LevelOff                // 4
 )
 
 func (l Level) String() string {
 	switch l {
+	case LevelDebug:
+		return "DEBUG"
 	case LevelInfo:
 		return "INFO"
 	case LevelError:
 		return "ERROR"
 	case LevelFatal:
 		return "FATAL"
+	case LevelOff:
+		return "OFF"
 	default:
 		return ""
 	}
 }
 
+// ParseLevel returns the level with the given name, such as "debug" or "ERROR"
+func ParseLevel(name string) (Level, error) {
+	for level := LevelDebug; level <= LevelOff; level++ {
+		if strings.EqualFold(name, level.String()) {
+			return level, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// Sink is a destination for log entries, such as stdout or a RotatingFile.
+// Entries below its MinLevel are not written to it
+type Sink struct {
+	Out      io.Writer
+	MinLevel Level
+}
+
 // custom logger type
 type Logger struct {
-	out      io.Writer // output destination
-	minLevel Level     // minimum level that log entries will be written for
+	*core
+	properties map[string]string // added to every entry, set on child loggers
+}
+
+// core is the state a logger shares with its child loggers
+type core struct {
+	sinks    []Sink
+	minLevel Level // lowest MinLevel of the sinks, to skip entries no sink wants
+	sampler  *sampler
 	mu       sync.Mutex
 }
 
 // new logger instance that writes log entries at or above a minimum severity level to a specific destination
 func New(out io.Writer, minLevel Level) *Logger {
+	return NewWithSinks(Sampling{}, Sink{Out: out, MinLevel: minLevel})
+}
+
+// NewWithSinks returns a logger that writes each entry to every sink whose
+// minimum level it meets. Repeated ERROR entries are limited by sampling
+func NewWithSinks(sampling Sampling, sinks ...Sink) *Logger {
+	c := &core{
+		sinks:    sinks,
+		minLevel: LevelOff,
+		sampler:  newSampler(sampling),
+	}
+
+	for _, sink := range sinks {
+		c.minLevel = min(c.minLevel, sink.MinLevel)
+	}
+
+	return &Logger{core: c}
+}
+
+// With returns a child logger that adds properties to every entry it writes,
+// such as the ID of the request being served. Properties passed to a single
+// entry take precedence
+func (l *Logger) With(properties map[string]string) *Logger {
+	merged := make(map[string]string, len(l.properties)+len(properties))
+	maps.Copy(merged, l.properties)
+	maps.Copy(merged, properties)
+
 	return &Logger{
-		out:      out,
-		minLevel: minLevel,
+		core:       l.core,
+		properties: merged,
 	}
 }
 
+// Enabled reports whether entries of the level are written to any sink
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.minLevel && level < LevelOff
+}
+
+func (l *Logger) PrintDebug(message string, properties map[string]string) {
+	l.print(LevelDebug, message, properties)
+}
+
 func (l *Logger) PrintInfo(message string, properties map[string]string) {
 	l.print(LevelInfo, message, properties)
 }
@@ -63,10 +133,33 @@ func (l *Logger) PrintFatal(err error, properties map[string]string) {
 }
 
 func (l *Logger) print(level Level, message string, properties map[string]string) (int, error) {
-	if level < l.minLevel {
+	if !l.Enabled(level) {
 		return 0, nil
 	}
 
+	// FATAL entries are always written, since the program exits right after
+	var suppressed int
+	if level == LevelError {
+		var write bool
+		if write, suppressed = l.sampler.allow(message, time.Now()); !write {
+			return 0, nil
+		}
+	}
+
+	if len(l.properties) > 0 || suppressed > 0 {
+		merged := maps.Clone(l.properties)
+		if merged == nil {
+			merged = make(map[string]string, len(properties)+1)
+		}
+		maps.Copy(merged, properties)
+		properties = merged
+	}
+
+	// how many identical entries were dropped since this message was last written
+	if suppressed > 0 {
+		properties["suppressed"] = strconv.Itoa(suppressed)
+	}
+
 	aux := struct {
 		Level      string            `json:"level"`
 		Time       string            `json:"time"`
@@ -80,7 +173,9 @@ func (l *Logger) print(level Level, message string, properties map[string]string
 		Properties: properties,
 	}
 
-	// include a stack trace for entries in the ERROR and FATAL levels
+	// include a stack trace for entries in the ERROR and FATAL levels. It's only
+	// captured for entries that are written, since sampled out errors are the
+	// ones that come in floods
 	if level >= LevelError {
 		aux.Trace = string(debug.Stack())
 	}
@@ -92,12 +187,30 @@ func (l *Logger) print(level Level, message string, properties map[string]string
 		line = []byte(LevelError.String() + ": unable to marshal log message: " + err.Error())
 	}
 
-	// lock the mutex so that no two writes to the output destination can happen concurrently
+	line = append(line, '\n')
+
+	// lock the mutex so that no two writes to the output destinations can happen concurrently
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	// write the log entry
-	return l.out.Write(append(line, '\n'))
+	// write the log entry to every sink that takes its level, reporting the
+	// first failure without skipping the remaining sinks
+	var firstErr error
+	for _, sink := range l.sinks {
+		if level < sink.MinLevel {
+			continue
+		}
+
+		if _, err := sink.Out.Write(line); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if firstErr != nil {
+		return 0, firstErr
+	}
+
+	return len(line), nil
 }
 
 func (l *Logger) Write(message []byte) (n int, err error) {
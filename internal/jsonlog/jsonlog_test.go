@@ -0,0 +1,153 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type entry struct {
+	Level      string            `json:"level"`
+	Message    string            `json:"message"`
+	Properties map[string]string `json:"properties"`
+	Trace      string            `json:"trace"`
+}
+
+func readEntries(t *testing.T, buf *bytes.Buffer) []entry {
+	t.Helper()
+
+	var entries []entry
+	for line := range strings.Lines(buf.String()) {
+		var e entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestLogger_MinLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, LevelInfo)
+
+	logger.PrintDebug("cache miss", nil)
+	logger.PrintInfo("starting server", nil)
+	logger.PrintError(errors.New("connection refused"), nil)
+
+	entries := readEntries(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("wrote %d entries, want 2", len(entries))
+	}
+	if entries[0].Level != "INFO" || entries[0].Message != "starting server" {
+		t.Errorf("first entry = %s %q, want INFO %q", entries[0].Level, entries[0].Message, "starting server")
+	}
+	if entries[0].Trace != "" {
+		t.Error("INFO entry should not include a stack trace")
+	}
+	if entries[1].Level != "ERROR" || entries[1].Trace == "" {
+		t.Errorf("second entry = %s with trace %t, want ERROR with a trace", entries[1].Level, entries[1].Trace != "")
+	}
+}
+
+func TestLogger_With(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, LevelDebug)
+
+	child := logger.With(map[string]string{"request_id": "abc", "user_id": "1"})
+	grandchild := child.With(map[string]string{"user_id": "2"})
+
+	child.PrintDebug("loading dishes", map[string]string{"restaurant_id": "5"})
+	grandchild.PrintInfo("request completed", nil)
+	logger.PrintInfo("stopped server", nil)
+
+	entries := readEntries(t, &buf)
+	if len(entries) != 3 {
+		t.Fatalf("wrote %d entries, want 3", len(entries))
+	}
+
+	if got := entries[0].Properties; got["request_id"] != "abc" || got["restaurant_id"] != "5" {
+		t.Errorf("child entry properties = %v, want request_id and restaurant_id", got)
+	}
+	if got := entries[1].Properties; got["request_id"] != "abc" || got["user_id"] != "2" {
+		t.Errorf("grandchild entry properties = %v, want request_id=abc user_id=2", got)
+	}
+	if got := entries[2].Properties; len(got) != 0 {
+		t.Errorf("parent entry properties = %v, want none", got)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Level
+		wantErr bool
+	}{
+		{"debug", LevelDebug, false},
+		{"INFO", LevelInfo, false},
+		{"Error", LevelError, false},
+		{"off", LevelOff, false},
+		{"verbose", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel(%q) error = %v, wantErr = %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ParseLevel(%q) = %s, want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogger_Sinks(t *testing.T) {
+	var stdout, file bytes.Buffer
+	logger := NewWithSinks(Sampling{}, Sink{Out: &stdout, MinLevel: LevelError}, Sink{Out: &file, MinLevel: LevelDebug})
+
+	logger.PrintDebug("cache miss", nil)
+	logger.PrintInfo("request completed", nil)
+	logger.PrintError(errors.New("connection refused"), nil)
+
+	if got := len(readEntries(t, &stdout)); got != 1 {
+		t.Errorf("ERROR sink got %d entries, want 1", got)
+	}
+	if got := len(readEntries(t, &file)); got != 3 {
+		t.Errorf("DEBUG sink got %d entries, want 3", got)
+	}
+}
+
+func TestLogger_Sampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithSinks(Sampling{Window: time.Hour, Burst: 2}, Sink{Out: &buf, MinLevel: LevelInfo})
+
+	for range 5 {
+		logger.PrintError(errors.New("connection refused"), nil)
+		logger.PrintInfo("request completed", nil)
+	}
+	logger.PrintError(errors.New("disk full"), nil)
+
+	var errorsWritten, infoWritten int
+	for _, e := range readEntries(t, &buf) {
+		switch e.Level {
+		case "ERROR":
+			errorsWritten++
+		case "INFO":
+			infoWritten++
+		}
+	}
+
+	// 2 of the repeated error, plus the different one
+	if errorsWritten != 3 {
+		t.Errorf("wrote %d ERROR entries, want 3", errorsWritten)
+	}
+	if infoWritten != 5 {
+		t.Errorf("wrote %d INFO entries, want 5 (INFO is not sampled)", infoWritten)
+	}
+}
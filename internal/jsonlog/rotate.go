@@ -0,0 +1,171 @@
+package jsonlog
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat names rotated files so they sort by age
+const backupTimeFormat = "20060102T150405.000000000"
+
+// RotateOptions control when a RotatingFile starts a new file. A zero MaxSize
+// or MaxAge disables that kind of rotation, and a zero MaxBackups keeps every
+// rotated file
+type RotateOptions struct {
+	MaxSize    int64         // bytes written before rotating
+	MaxAge     time.Duration // time since the file was opened before rotating
+	MaxBackups int           // rotated files kept, the oldest are removed first
+}
+
+// RotatingFile is a log file that is renamed and replaced by a new one when it
+// grows past its maximum size or age. Rotated files keep the name of the file
+// with the rotation time added, e.g. api-20260606T120000.000000000.log
+type RotatingFile struct {
+	path string
+	opts RotateOptions
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	now      func() time.Time
+}
+
+// OpenRotatingFile opens the log file at path for appending, creating it and
+// its directory if needed
+func OpenRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path: path,
+		opts: opts,
+		now:  time.Now,
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	err = f.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.shouldRotate(len(p)) {
+		err := f.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	return n, err
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+
+	return nil
+}
+
+// shouldRotate reports whether writing n more bytes calls for a new file. An
+// empty file is never rotated, so entries larger than MaxSize are still written
+func (f *RotatingFile) shouldRotate(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+
+	if f.opts.MaxSize > 0 && f.size+int64(n) > f.opts.MaxSize {
+		return true
+	}
+
+	return f.opts.MaxAge > 0 && f.now().Sub(f.openedAt) >= f.opts.MaxAge
+}
+
+func (f *RotatingFile) rotate() error {
+	err := f.file.Close()
+	if err != nil {
+		return err
+	}
+
+	prefix, ext := f.backupPattern()
+
+	err = os.Rename(f.path, prefix+f.now().UTC().Format(backupTimeFormat)+ext)
+	if err != nil {
+		return err
+	}
+
+	err = f.open()
+	if err != nil {
+		return err
+	}
+
+	return f.removeOldBackups()
+}
+
+// removeOldBackups deletes the oldest rotated files beyond MaxBackups
+func (f *RotatingFile) removeOldBackups() error {
+	if f.opts.MaxBackups <= 0 {
+		return nil
+	}
+
+	prefix, ext := f.backupPattern()
+
+	backups, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return err
+	}
+
+	// the timestamps in the names sort oldest first
+	slices.Sort(backups)
+
+	for len(backups) > f.opts.MaxBackups {
+		err = os.Remove(backups[0])
+		if err != nil {
+			return err
+		}
+
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// backupPattern splits the name of rotated files around their timestamp, so
+// logs/api.log is rotated to logs/api-<time>.log
+func (f *RotatingFile) backupPattern() (prefix, ext string) {
+	ext = filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext) + "-", ext
+}
@@ -0,0 +1,121 @@
+package jsonlog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func backups(t *testing.T, dir string) []string {
+	t.Helper()
+
+	names, err := filepath.Glob(filepath.Join(dir, "api-*.log"))
+	if err != nil {
+		t.Fatalf("Glob() error = %v", err)
+	}
+	return names
+}
+
+func TestRotatingFile_MaxSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "api.log")
+
+	f, err := OpenRotatingFile(path, RotateOptions{MaxSize: 20, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile() error = %v", err)
+	}
+	defer f.Close()
+
+	now := time.Date(2026, 6, 6, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	// every write after the first one overflows the file
+	for _, line := range []string{"first entry 1\n", "second entry\n", "third entry\n", "fourth entry\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(content) != "fourth entry\n" {
+		t.Errorf("current file = %q, want only the last entry", content)
+	}
+
+	rotated := backups(t, filepath.Join(dir, "logs"))
+	if len(rotated) != 2 {
+		t.Fatalf("kept %d rotated files, want 2", len(rotated))
+	}
+
+	oldest, _ := os.ReadFile(rotated[0])
+	if string(oldest) != "second entry\n" {
+		t.Errorf("oldest kept file = %q, want the second entry", oldest)
+	}
+}
+
+func TestRotatingFile_MaxAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.log")
+
+	f, err := OpenRotatingFile(path, RotateOptions{MaxAge: time.Hour})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile() error = %v", err)
+	}
+	defer f.Close()
+
+	now := time.Date(2026, 6, 6, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+	f.openedAt = now
+
+	f.Write([]byte("morning\n"))
+	now = now.Add(30 * time.Minute)
+	f.Write([]byte("still morning\n"))
+
+	if got := len(backups(t, dir)); got != 0 {
+		t.Fatalf("rotated %d files before MaxAge, want 0", got)
+	}
+
+	now = now.Add(30 * time.Minute)
+	f.Write([]byte("afternoon\n"))
+
+	rotated := backups(t, dir)
+	if len(rotated) != 1 {
+		t.Fatalf("rotated %d files after MaxAge, want 1", len(rotated))
+	}
+	if !strings.HasSuffix(rotated[0], "api-20260606T130000.000000000.log") {
+		t.Errorf("rotated file name = %q, want the rotation time", filepath.Base(rotated[0]))
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "afternoon\n" {
+		t.Errorf("current file = %q, want %q", content, "afternoon\n")
+	}
+}
+
+func TestRotatingFile_AppendsToExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.log")
+
+	if err := os.WriteFile(path, []byte("before restart\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenRotatingFile(path, RotateOptions{MaxSize: 1 << 20})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile() error = %v", err)
+	}
+
+	f.Write([]byte("after restart\n"))
+	f.Close()
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "before restart\nafter restart\n" {
+		t.Errorf("file = %q, want both entries", content)
+	}
+}
@@ -0,0 +1,94 @@
+package jsonlog
+
+import (
+	"sync"
+	"time"
+)
+
+// Sampling limits repeated ERROR entries, so a failing dependency such as the
+// database doesn't flood the logs. Within each Window, only the first Burst
+// entries with the same message are written. The next entry written after
+// the window ends reports how many were dropped. The zero value writes every
+// entry
+type Sampling struct {
+	Window time.Duration
+	Burst  int
+}
+
+// maxSampledMessages bounds the number of distinct messages tracked at once
+const maxSampledMessages = 1000
+
+type sampleCount struct {
+	windowStart time.Time
+	written     int
+	suppressed  int
+}
+
+type sampler struct {
+	Sampling
+	mu     sync.Mutex
+	counts map[string]*sampleCount
+}
+
+func newSampler(sampling Sampling) *sampler {
+	if sampling.Window <= 0 || sampling.Burst <= 0 {
+		return nil
+	}
+
+	return &sampler{
+		Sampling: sampling,
+		counts:   make(map[string]*sampleCount),
+	}
+}
+
+// allow reports whether an entry with the message should be written at now,
+// and how many entries with the same message were dropped before it
+func (s *sampler) allow(message string, now time.Time) (bool, int) {
+	if s == nil {
+		return true, 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count, ok := s.counts[message]
+	if !ok {
+		if len(s.counts) >= maxSampledMessages {
+			s.prune(now)
+		}
+
+		count = &sampleCount{windowStart: now}
+		s.counts[message] = count
+	}
+
+	var suppressed int
+
+	if now.Sub(count.windowStart) >= s.Window {
+		suppressed = count.suppressed
+		*count = sampleCount{windowStart: now}
+	}
+
+	if count.written >= s.Burst {
+		count.suppressed++
+		return false, 0
+	}
+
+	count.written++
+
+	return true, suppressed
+}
+
+// prune forgets the messages whose window has ended, along with their counts
+// of dropped entries. When every message is still within its window, they
+// are all forgotten, which at worst lets a few more entries through
+func (s *sampler) prune(now time.Time) {
+	for message, count := range s.counts {
+		if now.Sub(count.windowStart) >= s.Window {
+			delete(s.counts, message)
+		}
+	}
+
+	if len(s.counts) >= maxSampledMessages {
+		clear(s.counts)
+	}
+}
@@ -0,0 +1,60 @@
+package jsonlog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSampler_Allow(t *testing.T) {
+	s := newSampler(Sampling{Window: time.Minute, Burst: 2})
+	start := time.Date(2026, 6, 6, 12, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		at             time.Duration
+		message        string
+		wantWrite      bool
+		wantSuppressed int
+	}{
+		{0, "connection refused", true, 0},
+		{time.Second, "connection refused", true, 0},
+		{2 * time.Second, "connection refused", false, 0},
+		{3 * time.Second, "connection refused", false, 0},
+		{4 * time.Second, "disk full", true, 0},
+		{time.Minute, "connection refused", true, 2},
+		{time.Minute + time.Second, "connection refused", true, 0},
+	}
+
+	for _, step := range steps {
+		write, suppressed := s.allow(step.message, start.Add(step.at))
+		if write != step.wantWrite || suppressed != step.wantSuppressed {
+			t.Errorf("allow(%q) at %s = (%t, %d), want (%t, %d)",
+				step.message, step.at, write, suppressed, step.wantWrite, step.wantSuppressed)
+		}
+	}
+}
+
+func TestSampler_Disabled(t *testing.T) {
+	s := newSampler(Sampling{})
+
+	for range 100 {
+		if write, _ := s.allow("connection refused", time.Now()); !write {
+			t.Fatal("allow() = false with sampling disabled")
+		}
+	}
+}
+
+func TestSampler_Prune(t *testing.T) {
+	s := newSampler(Sampling{Window: time.Minute, Burst: 1})
+	start := time.Date(2026, 6, 6, 12, 0, 0, 0, time.UTC)
+
+	for i := range maxSampledMessages {
+		s.allow(fmt.Sprintf("error %d", i), start)
+	}
+
+	s.allow("new message", start.Add(time.Minute))
+
+	if len(s.counts) != 1 {
+		t.Errorf("tracking %d messages after their windows ended, want 1", len(s.counts))
+	}
+}
@@ -0,0 +1,95 @@
+package jsonlog
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+)
+
+// Handler adapts a Logger to the log/slog API, so libraries that log through
+// slog write the same JSON entries as the rest of the application. slog's
+// DEBUG and INFO map to the levels of the same name, WARN is written as INFO
+// and ERROR as ERROR. Attributes become properties, with groups joined to the
+// attribute key by a dot, e.g. "db.query"
+type Handler struct {
+	logger *Logger
+	attrs  map[string]string
+	group  string // prefix of the keys of attributes added from here on
+}
+
+// NewHandler returns a slog handler that writes to logger
+func NewHandler(logger *Logger) *Handler {
+	return &Handler{logger: logger}
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.Enabled(fromSlogLevel(level))
+}
+
+func (h *Handler) Handle(_ context.Context, record slog.Record) error {
+	properties := maps.Clone(h.attrs)
+	if properties == nil {
+		properties = make(map[string]string, record.NumAttrs())
+	}
+
+	record.Attrs(func(attr slog.Attr) bool {
+		addAttr(properties, h.group, attr)
+		return true
+	})
+
+	_, err := h.logger.print(fromSlogLevel(record.Level), record.Message, properties)
+	return err
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	properties := make(map[string]string, len(h.attrs)+len(attrs))
+	maps.Copy(properties, h.attrs)
+
+	for _, attr := range attrs {
+		addAttr(properties, h.group, attr)
+	}
+
+	return &Handler{logger: h.logger, attrs: properties, group: h.group}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &Handler{logger: h.logger, attrs: h.attrs, group: h.group + name + "."}
+}
+
+// addAttr flattens attr into properties, prefixing its key with the group
+func addAttr(properties map[string]string, group string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		// attributes of an unnamed group are inlined into the current one
+		if attr.Key != "" {
+			group += attr.Key + "."
+		}
+
+		for _, member := range attr.Value.Group() {
+			addAttr(properties, group, member)
+		}
+		return
+	}
+
+	properties[group+attr.Key] = attr.Value.String()
+}
+
+func fromSlogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
+		return LevelDebug
+	case level < slog.LevelError:
+		return LevelInfo
+	default:
+		return LevelError
+	}
+}
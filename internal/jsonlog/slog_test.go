@@ -0,0 +1,41 @@
+package jsonlog
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(New(&buf, LevelInfo)))
+
+	logger.Debug("not written")
+	logger.With("component", "storage").WithGroup("s3").Info("bucket ready", "bucket", "food-backend", slog.Group("retry", "attempts", 2))
+	logger.Warn("slow upload", "duration", "2.5s")
+	logger.Error("upload failed", "err", errors.New("timeout"))
+
+	entries := readEntries(t, &buf)
+	if len(entries) != 3 {
+		t.Fatalf("wrote %d entries, want 3", len(entries))
+	}
+
+	want := map[string]string{
+		"component":         "storage",
+		"s3.bucket":         "food-backend",
+		"s3.retry.attempts": "2",
+	}
+	for key, value := range want {
+		if got := entries[0].Properties[key]; got != value {
+			t.Errorf("property %q = %q, want %q", key, got, value)
+		}
+	}
+
+	if entries[1].Level != "INFO" {
+		t.Errorf("WARN entry level = %s, want INFO", entries[1].Level)
+	}
+	if entries[2].Level != "ERROR" || entries[2].Properties["err"] != "timeout" {
+		t.Errorf("ERROR entry = %s %v, want ERROR with err=timeout", entries[2].Level, entries[2].Properties)
+	}
+}
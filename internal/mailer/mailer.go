@@ -0,0 +1,136 @@
+// Package mailer renders the application's emails from templates and hands
+// them to a Sender, which delivers them over SMTP or, where there's no mail
+// server, writes them to the log.
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/xtommas/food-backend/internal/jsonlog"
+)
+
+//go:embed "templates"
+var templateFS embed.FS
+
+// Message is a rendered plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers rendered messages
+type Sender interface {
+	Send(msg *Message) error
+}
+
+type Mailer struct {
+	sender Sender
+}
+
+func New(sender Sender) *Mailer {
+	return &Mailer{sender: sender}
+}
+
+// Send renders templateFile with data and sends it to recipient. Each
+// template defines a "subject" and a "body" block
+func (m *Mailer) Send(recipient, templateFile string, data any) error {
+	msg, err := Render(recipient, templateFile, data)
+	if err != nil {
+		return err
+	}
+
+	return m.sender.Send(msg)
+}
+
+func Render(recipient, templateFile string, data any) (*Message, error) {
+	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var subject, body bytes.Buffer
+
+	err = tmpl.ExecuteTemplate(&subject, "subject", data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tmpl.ExecuteTemplate(&body, "body", data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{To: recipient, Subject: strings.TrimSpace(subject.String()), Body: body.String()}, nil
+}
+
+// SMTP sends messages through an SMTP server, upgrading to TLS when the
+// server supports it
+type SMTP struct {
+	addr   string
+	auth   smtp.Auth
+	sender string
+}
+
+// NewSMTP sends from sender, e.g. "Food Backend <no-reply@example.com>". The
+// username and password can be empty for servers that don't need them
+func NewSMTP(host string, port int, username, password, sender string) (*SMTP, error) {
+	if _, err := mail.ParseAddress(sender); err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", sender, err)
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTP{addr: host + ":" + strconv.Itoa(port), auth: auth, sender: sender}, nil
+}
+
+func (s *SMTP) Send(msg *Message) error {
+	from, err := mail.ParseAddress(s.sender)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return smtp.SendMail(s.addr, s.auth, from.Address, []string{msg.To}, buf.Bytes())
+}
+
+// Log writes messages to the log instead of sending them, for development
+type Log struct {
+	logger *jsonlog.Logger
+}
+
+func NewLog(logger *jsonlog.Logger) *Log {
+	return &Log{logger: logger}
+}
+
+func (l *Log) Send(msg *Message) error {
+	l.logger.PrintInfo("email not sent, no SMTP server configured", map[string]string{
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	})
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/xtommas/food-backend/internal/jsonlog"
+)
+
+func TestRender(t *testing.T) {
+	msg, err := Render("new@example.com", "email_change_confirm.tmpl", map[string]any{
+		"Name":   "Tomas",
+		"Token":  "Y3QMGX3PJ3WLRL2YRTQGQ6KRHU",
+		"Expiry": "2026-06-07T12:00:00Z",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	if msg.To != "new@example.com" {
+		t.Errorf("To = %q, want new@example.com", msg.To)
+	}
+	if msg.Subject != "Confirm your new email address" {
+		t.Errorf("Subject = %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Body, "Y3QMGX3PJ3WLRL2YRTQGQ6KRHU") {
+		t.Errorf("Body does not include the token:\n%s", msg.Body)
+	}
+}
+
+func TestRender_MissingTemplate(t *testing.T) {
+	if _, err := Render("new@example.com", "missing.tmpl", nil); err == nil {
+		t.Error("Render() with a missing template should fail")
+	}
+}
+
+func TestNewSMTP_InvalidSender(t *testing.T) {
+	if _, err := NewSMTP("localhost", 25, "", "", "not an address"); err == nil {
+		t.Error("NewSMTP() with an invalid sender should fail")
+	}
+}
+
+func TestMailer_Log(t *testing.T) {
+	var buf bytes.Buffer
+	m := New(NewLog(jsonlog.New(&buf, jsonlog.LevelInfo)))
+
+	err := m.Send("old@example.com", "email_change_notice.tmpl", map[string]any{"Name": "Tomas", "NewEmail": "new@example.com"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	var entry struct {
+		Properties map[string]string `json:"properties"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid log entry %q: %v", buf.String(), err)
+	}
+
+	if entry.Properties["to"] != "old@example.com" {
+		t.Errorf("logged recipient = %q, want old@example.com", entry.Properties["to"])
+	}
+	if !strings.Contains(entry.Properties["body"], "new@example.com") {
+		t.Errorf("logged body does not mention the new address:\n%s", entry.Properties["body"])
+	}
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateKey(t *testing.T) {
+	valid := []string{"dishes/1.jpg", "users/42.png", "a.txt"}
+	invalid := []string{"", "/etc/passwd", "../secret", "..", ".", "dishes/../../x", "dishes//1.jpg", `dishes\1.jpg`, "dishes/"}
+
+	for _, key := range valid {
+		if err := ValidateKey(key); err != nil {
+			t.Errorf("ValidateKey(%q) = %v, want nil", key, err)
+		}
+	}
+	for _, key := range invalid {
+		if err := ValidateKey(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("ValidateKey(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestLocal_PutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocal(t.TempDir(), "http://localhost:4000", []byte("secret"))
+
+	err := store.Put(ctx, "dishes/1.jpg", strings.NewReader("first"), 5, "image/jpeg")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	// a second put replaces the object
+	err = store.Put(ctx, "dishes/1.jpg", strings.NewReader("second"), 6, "image/jpeg")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	object, err := store.Get(ctx, "dishes/1.jpg")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	body, _ := io.ReadAll(object.Body)
+	object.Body.Close()
+
+	if string(body) != "second" {
+		t.Errorf("Get() body = %q, want %q", body, "second")
+	}
+	if object.Size != 6 {
+		t.Errorf("Get() Size = %d, want 6", object.Size)
+	}
+	if object.ContentType != "image/jpeg" {
+		t.Errorf("Get() ContentType = %q, want image/jpeg", object.ContentType)
+	}
+
+	if err := store.Delete(ctx, "dishes/1.jpg"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, "dishes/1.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "dishes/1.jpg"); err != nil {
+		t.Errorf("Delete() of a missing object error = %v, want nil", err)
+	}
+}
+
+func TestLocal_InvalidKey(t *testing.T) {
+	store := NewLocal(t.TempDir(), "http://localhost:4000", []byte("secret"))
+
+	err := store.Put(context.Background(), "../escape.txt", strings.NewReader("x"), 1, "text/plain")
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Put() error = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestLocal_PresignedURL(t *testing.T) {
+	store := NewLocal(t.TempDir(), "http://localhost:4000", []byte("secret"))
+
+	raw, err := store.PresignedURL(context.Background(), "dishes/1.jpg", time.Minute)
+	if err != nil {
+		t.Fatalf("PresignedURL() error = %v", err)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("PresignedURL() returned an invalid URL: %v", err)
+	}
+	if u.Host != "localhost:4000" || u.Path != "/storage/dishes/1.jpg" {
+		t.Errorf("PresignedURL() = %q, want a /storage/ URL on the API", raw)
+	}
+
+	expires, signature := u.Query().Get("expires"), u.Query().Get("signature")
+
+	if !store.Verify("dishes/1.jpg", expires, signature) {
+		t.Error("Verify() rejected a fresh presigned URL")
+	}
+	if store.Verify("dishes/2.jpg", expires, signature) {
+		t.Error("Verify() accepted the signature for another key")
+	}
+	if store.Verify("dishes/1.jpg", expires, strings.Repeat("0", len(signature))) {
+		t.Error("Verify() accepted a forged signature")
+	}
+
+	other := NewLocal(t.TempDir(), "http://localhost:4000", []byte("other"))
+	if other.Verify("dishes/1.jpg", expires, signature) {
+		t.Error("Verify() accepted a signature made with another secret")
+	}
+
+	expired, err := store.PresignedURL(context.Background(), "dishes/1.jpg", -time.Minute)
+	if err != nil {
+		t.Fatalf("PresignedURL() error = %v", err)
+	}
+	u, _ = url.Parse(expired)
+	if store.Verify("dishes/1.jpg", u.Query().Get("expires"), u.Query().Get("signature")) {
+		t.Error("Verify() accepted an expired URL")
+	}
+}
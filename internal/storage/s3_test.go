@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestS3 connects to the bucket in TEST_S3_ENDPOINT, e.g. the MinIO service
+// from docker-compose. The tests are skipped when it isn't set
+func newTestS3(t *testing.T) *S3 {
+	t.Helper()
+
+	endpoint := os.Getenv("TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("TEST_S3_ENDPOINT not set")
+	}
+
+	store, err := NewS3(S3Config{
+		Endpoint:  endpoint,
+		AccessKey: os.Getenv("TEST_S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("TEST_S3_SECRET_KEY"),
+		Bucket:    "food-backend-test",
+		Region:    "us-east-1",
+	})
+	if err != nil {
+		t.Fatalf("NewS3() error = %v", err)
+	}
+
+	if err := store.EnsureBucket(context.Background()); err != nil {
+		t.Fatalf("EnsureBucket() error = %v", err)
+	}
+
+	return store
+}
+
+func TestS3_PutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	store := newTestS3(t)
+
+	err := store.Put(ctx, "dishes/1.jpg", strings.NewReader("photo"), 5, "image/jpeg")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	t.Cleanup(func() { store.Delete(ctx, "dishes/1.jpg") })
+
+	object, err := store.Get(ctx, "dishes/1.jpg")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	body, _ := io.ReadAll(object.Body)
+	object.Body.Close()
+
+	if string(body) != "photo" || object.ContentType != "image/jpeg" {
+		t.Errorf("Get() = %q (%s), want %q (image/jpeg)", body, object.ContentType, "photo")
+	}
+
+	if err := store.Delete(ctx, "dishes/1.jpg"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, "dishes/1.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3_PresignedURL(t *testing.T) {
+	ctx := context.Background()
+	store := newTestS3(t)
+
+	err := store.Put(ctx, "dishes/2.jpg", strings.NewReader("photo"), 5, "image/jpeg")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	t.Cleanup(func() { store.Delete(ctx, "dishes/2.jpg") })
+
+	url, err := store.PresignedURL(ctx, "dishes/2.jpg", time.Minute)
+	if err != nil {
+		t.Fatalf("PresignedURL() error = %v", err)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET presigned URL error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET presigned URL status = %d, want 200", resp.StatusCode)
+	}
+}
@@ -0,0 +1,50 @@
+// Package storage keeps uploaded files, such as dish and user photos, behind an
+// interface so they can live on the local disk or in an S3-compatible bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("storage: object not found")
+	ErrInvalidKey = errors.New("storage: invalid key")
+)
+
+// Object is a stored file opened for reading. The caller must close Body
+type Object struct {
+	Body        io.ReadSeekCloser
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Storage stores files under slash-separated keys such as "dishes/1.jpg"
+type Storage interface {
+	// Put stores the contents of r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key, or returns ErrNotFound
+	Get(ctx context.Context, key string) (*Object, error)
+	// Delete removes the object stored under key. Deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// PresignedURL returns a URL that can download the object without credentials until it expires
+	PresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// ValidateKey rejects keys that are empty, absolute or try to leave the storage root
+func ValidateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+
+	if path.Clean(key) != key || key == "." || strings.HasPrefix(key, "../") || key == ".." {
+		return ErrInvalidKey
+	}
+
+	return nil
+}
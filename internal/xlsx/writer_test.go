@@ -0,0 +1,118 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestColumnName(t *testing.T) {
+	tests := map[int]string{0: "A", 1: "B", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"}
+
+	for i, want := range tests {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestFormatCents(t *testing.T) {
+	tests := map[int64]string{0: "0.00", 5: "0.05", 1299: "12.99", 100000: "1000.00", -250: "-2.50"}
+
+	for cents, want := range tests {
+		if got := FormatCents(cents); got != want {
+			t.Errorf("FormatCents(%d) = %q, want %q", cents, got, want)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, "Orders & Items")
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	rows := [][]any{
+		{"order_id", "dish", "total"},
+		{int64(1), "Fish <&> Chips", Money(1299)},
+		{2, nil, 3.5},
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("output is not a zip archive: %v", err)
+	}
+
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+
+		parts[f.Name] = string(body)
+
+		// every part must be well-formed XML
+		dec := xml.NewDecoder(bytes.NewReader(body))
+		for {
+			_, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s is not well-formed: %v", f.Name, err)
+			}
+		}
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("archive is missing %s", name)
+		}
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">order_id</t></is></c>`,
+		`<c r="A2"><v>1</v></c>`,
+		`Fish &lt;&amp;&gt; Chips`,
+		`<c r="C2" s="1"><v>12.99</v></c>`,
+		`<c r="C3"><v>3.5</v></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet does not contain %s", want)
+		}
+	}
+	if strings.Contains(sheet, `r="B3"`) {
+		t.Error("nil cell should be left out")
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `name="Orders &amp; Items"`) {
+		t.Error("sheet name is not escaped in the workbook")
+	}
+}
+
+func TestWriter_UnsupportedType(t *testing.T) {
+	w, err := NewWriter(io.Discard, "Sheet1")
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	if err := w.Write([]any{struct{}{}}); err == nil {
+		t.Error("Write() with a struct cell should fail")
+	}
+}
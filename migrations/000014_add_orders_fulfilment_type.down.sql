@@ -0,0 +1,18 @@
+DROP INDEX IF EXISTS orders_restaurant_id_fulfilment_type_idx;
+
+UPDATE orders SET status = 'delivered' WHERE status IN ('picked_up', 'served');
+
+ALTER TABLE orders
+    DROP CONSTRAINT IF EXISTS orders_status_check;
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_status_check
+        CHECK (status IN ('pending', 'confirmed', 'preparing', 'ready', 'delivered', 'cancelled'));
+
+ALTER TABLE orders
+    DROP CONSTRAINT IF EXISTS orders_fulfilment_details_check,
+    DROP CONSTRAINT IF EXISTS orders_fulfilment_type_check;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS table_number,
+    DROP COLUMN IF EXISTS fulfilment_type;
@@ -0,0 +1,31 @@
+-- =============================================================================
+-- Fulfilment type: delivery (default for existing rows), pickup or dine_in
+-- =============================================================================
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS fulfilment_type TEXT NOT NULL DEFAULT 'delivery',
+    ADD COLUMN IF NOT EXISTS table_number INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_fulfilment_type_check
+        CHECK (fulfilment_type IN ('delivery', 'pickup', 'dine_in'));
+
+-- Delivery orders need an address, dine-in orders need a table
+ALTER TABLE orders
+    ADD CONSTRAINT orders_fulfilment_details_check
+        CHECK (
+            (fulfilment_type <> 'delivery' OR address <> '')
+            AND (fulfilment_type <> 'dine_in' OR table_number > 0)
+        );
+
+-- =============================================================================
+-- Terminal statuses for pickup (picked_up) and dine-in (served) orders
+-- =============================================================================
+ALTER TABLE orders
+    DROP CONSTRAINT IF EXISTS orders_status_check;
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_status_check
+        CHECK (status IN ('pending', 'confirmed', 'preparing', 'ready', 'delivered', 'picked_up', 'served', 'cancelled'));
+
+-- Restaurant order lists filtered by fulfilment type
+CREATE INDEX IF NOT EXISTS orders_restaurant_id_fulfilment_type_idx ON orders (restaurant_id, fulfilment_type);
@@ -0,0 +1,22 @@
+DROP INDEX IF EXISTS orders_unclaimed_delivery_idx;
+DROP INDEX IF EXISTS orders_courier_id_idx;
+
+UPDATE orders SET status = 'ready' WHERE status = 'out_for_delivery';
+
+ALTER TABLE orders
+    DROP CONSTRAINT IF EXISTS orders_status_check;
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_status_check
+        CHECK (status IN ('pending', 'confirmed', 'preparing', 'ready', 'delivered', 'picked_up', 'served', 'cancelled'));
+
+ALTER TABLE orders DROP COLUMN IF EXISTS courier_id;
+
+UPDATE users SET role = 'customer' WHERE role = 'courier';
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_role_check;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_role_check
+        CHECK (role IN ('customer', 'admin'));
@@ -0,0 +1,29 @@
+-- =============================================================================
+-- Courier role
+-- =============================================================================
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_role_check;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_role_check
+        CHECK (role IN ('customer', 'admin', 'courier'));
+
+-- =============================================================================
+-- Courier assignment on delivery orders
+-- =============================================================================
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS courier_id BIGINT REFERENCES users ON DELETE SET NULL;
+
+ALTER TABLE orders
+    DROP CONSTRAINT IF EXISTS orders_status_check;
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_status_check
+        CHECK (status IN ('pending', 'confirmed', 'preparing', 'ready', 'out_for_delivery', 'delivered', 'picked_up', 'served', 'cancelled'));
+
+-- Orders assigned to a courier
+CREATE INDEX IF NOT EXISTS orders_courier_id_idx ON orders (courier_id);
+
+-- Queue of ready delivery orders waiting for a courier
+CREATE INDEX IF NOT EXISTS orders_unclaimed_delivery_idx ON orders (id)
+    WHERE status = 'ready' AND fulfilment_type = 'delivery' AND courier_id IS NULL;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS courier_locations;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS latitude;
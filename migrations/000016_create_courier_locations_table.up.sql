@@ -0,0 +1,24 @@
+-- =============================================================================
+-- Delivery destination coordinates, used to estimate courier arrival times
+-- =============================================================================
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS latitude  NUMERIC(9, 6) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS longitude NUMERIC(9, 6) NOT NULL DEFAULT 0;
+
+-- =============================================================================
+-- GPS pings posted by the courier assigned to an order
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS courier_locations (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders ON DELETE CASCADE,
+    courier_id BIGINT NOT NULL REFERENCES users ON DELETE CASCADE,
+    latitude NUMERIC(9, 6) NOT NULL,
+    longitude NUMERIC(9, 6) NOT NULL,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Latest ping for an order
+CREATE INDEX IF NOT EXISTS courier_locations_order_id_recorded_at_idx ON courier_locations (order_id, recorded_at DESC);
+
+-- Retention pruning
+CREATE INDEX IF NOT EXISTS courier_locations_recorded_at_idx ON courier_locations (recorded_at);
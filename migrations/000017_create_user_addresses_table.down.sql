@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS delivery_instructions;
+
+DROP TABLE IF EXISTS user_addresses;
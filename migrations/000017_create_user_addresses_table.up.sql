@@ -0,0 +1,28 @@
+-- =============================================================================
+-- Customer address book
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS user_addresses (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users ON DELETE CASCADE,
+    label TEXT NOT NULL,
+    street TEXT NOT NULL,
+    city TEXT NOT NULL,
+    postal_code TEXT NOT NULL DEFAULT '',
+    latitude NUMERIC(9, 6) NOT NULL DEFAULT 0,
+    longitude NUMERIC(9, 6) NOT NULL DEFAULT 0,
+    instructions TEXT NOT NULL DEFAULT '',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS user_addresses_user_id_idx ON user_addresses (user_id);
+
+-- At most one default address per user
+CREATE UNIQUE INDEX IF NOT EXISTS user_addresses_one_default_idx ON user_addresses (user_id) WHERE is_default;
+
+-- =============================================================================
+-- Delivery instructions snapshotted into orders alongside the address
+-- =============================================================================
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS delivery_instructions TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS minimum_order,
+    DROP COLUMN IF EXISTS delivery_fee;
+
+DROP TABLE IF EXISTS delivery_zones;
@@ -0,0 +1,33 @@
+-- =============================================================================
+-- Areas a restaurant delivers to, either a polygon or a circle around a centre
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS delivery_zones (
+    id BIGSERIAL PRIMARY KEY,
+    restaurant_id BIGINT NOT NULL REFERENCES restaurants ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    polygon JSONB NOT NULL DEFAULT '[]',
+    center_latitude NUMERIC(9, 6) NOT NULL DEFAULT 0,
+    center_longitude NUMERIC(9, 6) NOT NULL DEFAULT 0,
+    radius_meters INTEGER NOT NULL DEFAULT 0,
+    fee BIGINT NOT NULL DEFAULT 0,
+    minimum_order BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1,
+    CONSTRAINT delivery_zones_kind_check CHECK (kind IN ('polygon', 'circle')),
+    CONSTRAINT delivery_zones_shape_check CHECK (
+        (kind = 'polygon' AND jsonb_array_length(polygon) >= 3)
+        OR (kind = 'circle' AND radius_meters > 0)
+    ),
+    CONSTRAINT delivery_zones_fee_check CHECK (fee >= 0),
+    CONSTRAINT delivery_zones_minimum_order_check CHECK (minimum_order >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS delivery_zones_restaurant_id_idx ON delivery_zones (restaurant_id);
+
+-- =============================================================================
+-- Fee and minimum order of the matched zone, snapshotted into delivery orders
+-- =============================================================================
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS delivery_fee BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS minimum_order BIGINT NOT NULL DEFAULT 0;
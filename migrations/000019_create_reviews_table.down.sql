@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS dish_reviews;
+
+DROP TABLE IF EXISTS reviews;
@@ -0,0 +1,34 @@
+-- =============================================================================
+-- Customer reviews, one per completed order
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS reviews (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL UNIQUE REFERENCES orders ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users ON DELETE CASCADE,
+    restaurant_id BIGINT NOT NULL REFERENCES restaurants ON DELETE CASCADE,
+    rating SMALLINT NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    reply TEXT NOT NULL DEFAULT '',
+    replied_at TIMESTAMP(0) WITH TIME ZONE,
+    hidden BOOLEAN NOT NULL DEFAULT FALSE,
+    hidden_reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1,
+    CONSTRAINT reviews_rating_check CHECK (rating BETWEEN 1 AND 5)
+);
+
+-- Public listings and rating aggregates only look at visible reviews
+CREATE INDEX IF NOT EXISTS reviews_restaurant_id_rating_idx ON reviews (restaurant_id, rating) WHERE NOT hidden;
+
+-- =============================================================================
+-- Optional per-dish ratings attached to a review
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS dish_reviews (
+    review_id BIGINT NOT NULL REFERENCES reviews ON DELETE CASCADE,
+    dish_id BIGINT NOT NULL REFERENCES dishes ON DELETE CASCADE,
+    rating SMALLINT NOT NULL,
+    PRIMARY KEY (review_id, dish_id),
+    CONSTRAINT dish_reviews_rating_check CHECK (rating BETWEEN 1 AND 5)
+);
+
+CREATE INDEX IF NOT EXISTS dish_reviews_dish_id_idx ON dish_reviews (dish_id, review_id, rating);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS favorite_dishes;
+
+DROP TABLE IF EXISTS favorite_restaurants;
@@ -0,0 +1,20 @@
+-- =============================================================================
+-- Restaurants and dishes saved by customers
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS favorite_restaurants (
+    user_id BIGINT NOT NULL REFERENCES users ON DELETE CASCADE,
+    restaurant_id BIGINT NOT NULL REFERENCES restaurants ON DELETE CASCADE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, restaurant_id)
+);
+
+CREATE TABLE IF NOT EXISTS favorite_dishes (
+    user_id BIGINT NOT NULL REFERENCES users ON DELETE CASCADE,
+    dish_id BIGINT NOT NULL REFERENCES dishes ON DELETE CASCADE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, dish_id)
+);
+
+-- Cascading deletes of restaurants and dishes
+CREATE INDEX IF NOT EXISTS favorite_restaurants_restaurant_id_idx ON favorite_restaurants (restaurant_id);
+CREATE INDEX IF NOT EXISTS favorite_dishes_dish_id_idx ON favorite_dishes (dish_id);
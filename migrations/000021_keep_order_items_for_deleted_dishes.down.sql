@@ -0,0 +1,12 @@
+CREATE INDEX IF NOT EXISTS order_items_order_id_idx ON order_items (order_id);
+DROP INDEX IF EXISTS order_items_order_id_id_idx;
+
+DELETE FROM order_items WHERE dish_id IS NULL;
+
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_dish_id_fkey;
+
+ALTER TABLE order_items
+    ADD CONSTRAINT order_items_dish_id_fkey
+        FOREIGN KEY (dish_id) REFERENCES dishes ON DELETE CASCADE;
+
+ALTER TABLE order_items ALTER COLUMN dish_id SET NOT NULL;
@@ -0,0 +1,15 @@
+-- =============================================================================
+-- Keep order history when a dish is deleted. Items already snapshot the dish
+-- name and unit price, so they only lose the link to the dish
+-- =============================================================================
+ALTER TABLE order_items ALTER COLUMN dish_id DROP NOT NULL;
+
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_dish_id_fkey;
+
+ALTER TABLE order_items
+    ADD CONSTRAINT order_items_dish_id_fkey
+        FOREIGN KEY (dish_id) REFERENCES dishes ON DELETE SET NULL;
+
+-- Batched item loading for a page of orders
+CREATE INDEX IF NOT EXISTS order_items_order_id_id_idx ON order_items (order_id, id);
+DROP INDEX IF EXISTS order_items_order_id_idx;
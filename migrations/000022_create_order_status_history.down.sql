@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS orders_restaurant_id_created_at_idx;
+
+DROP TRIGGER IF EXISTS orders_record_status ON orders;
+DROP FUNCTION IF EXISTS record_order_status();
+
+DROP TABLE IF EXISTS order_status_history;
@@ -0,0 +1,38 @@
+-- =============================================================================
+-- Order status history: one row every time an order enters a status, written
+-- by a trigger so every code path that changes the status is covered
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id         BIGSERIAL PRIMARY KEY,
+    order_id   BIGINT                   NOT NULL REFERENCES orders ON DELETE CASCADE,
+    status     TEXT                     NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS order_status_history_order_id_changed_at_idx
+    ON order_status_history (order_id, changed_at);
+
+CREATE OR REPLACE FUNCTION record_order_status()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.status IS DISTINCT FROM OLD.status THEN
+        INSERT INTO order_status_history (order_id, status, changed_at)
+        VALUES (NEW.id, NEW.status, NOW());
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER orders_record_status
+    AFTER INSERT OR UPDATE OF status ON orders
+    FOR EACH ROW EXECUTE FUNCTION record_order_status();
+
+-- Existing orders only know their current status
+INSERT INTO order_status_history (order_id, status, changed_at)
+SELECT id, status, updated_at
+FROM orders;
+
+-- =============================================================================
+-- Analytics scan a restaurant's orders by creation date
+-- =============================================================================
+CREATE INDEX IF NOT EXISTS orders_restaurant_id_created_at_idx ON orders (restaurant_id, created_at);
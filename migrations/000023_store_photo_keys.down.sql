@@ -0,0 +1,2 @@
+UPDATE dishes SET photo = 'images/' || photo WHERE photo <> '' AND photo NOT LIKE 'images/%';
+UPDATE users SET photo = 'images/' || photo WHERE photo <> '' AND photo NOT LIKE 'images/%';
@@ -0,0 +1,6 @@
+-- =============================================================================
+-- Photos are addressed by storage key ("dishes/1.jpg") instead of a path
+-- under the local images directory ("images/dishes/1.jpg")
+-- =============================================================================
+UPDATE dishes SET photo = substr(photo, 8) WHERE photo LIKE 'images/%';
+UPDATE users SET photo = substr(photo, 8) WHERE photo LIKE 'images/%';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS restaurant_images;
+
+DROP TABLE IF EXISTS dish_images;
@@ -0,0 +1,29 @@
+-- =============================================================================
+-- Ordered photo galleries of dishes and restaurants
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS dish_images (
+    id BIGSERIAL PRIMARY KEY,
+    dish_id BIGINT NOT NULL REFERENCES dishes ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    caption TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    -- deferred, so a reorder can swap positions within a transaction
+    CONSTRAINT dish_images_dish_id_position_key UNIQUE (dish_id, position) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE TABLE IF NOT EXISTS restaurant_images (
+    id BIGSERIAL PRIMARY KEY,
+    restaurant_id BIGINT NOT NULL REFERENCES restaurants ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    caption TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT restaurant_images_restaurant_id_position_key UNIQUE (restaurant_id, position) DEFERRABLE INITIALLY DEFERRED
+);
+
+-- At most one primary image per gallery
+CREATE UNIQUE INDEX IF NOT EXISTS dish_images_primary_idx ON dish_images (dish_id) WHERE is_primary;
+CREATE UNIQUE INDEX IF NOT EXISTS restaurant_images_primary_idx ON restaurant_images (restaurant_id) WHERE is_primary;
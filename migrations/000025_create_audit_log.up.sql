@@ -0,0 +1,20 @@
+-- =============================================================================
+-- Audit log of privileged actions taken by admins and restaurant owners
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    -- kept when the actor's account is deleted
+    actor_id BIGINT REFERENCES users ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id BIGINT NOT NULL,
+    -- the fields that changed, with their values before and after the action
+    before JSONB,
+    after JSONB,
+    ip TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log (created_at);
+CREATE INDEX IF NOT EXISTS audit_log_actor_id_created_at_idx ON audit_log (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_action_created_at_idx ON audit_log (action, created_at);
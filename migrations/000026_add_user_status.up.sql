@@ -0,0 +1,9 @@
+-- =============================================================================
+-- Account status, so admins can suspend or ban users
+-- =============================================================================
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active';
+
+ALTER TABLE users
+    ADD CONSTRAINT users_status_check
+        CHECK (status IN ('active', 'suspended', 'banned'));
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_suspended_until_check;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS suspended_until,
+    DROP COLUMN IF EXISTS status_reason;
@@ -0,0 +1,11 @@
+-- =============================================================================
+-- Why an account was suspended or banned, and when a suspension ends. A NULL
+-- suspended_until is a suspension that lasts until an admin lifts it
+-- =============================================================================
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS status_reason TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMPTZ;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_suspended_until_check
+        CHECK (suspended_until IS NULL OR status = 'suspended');
@@ -0,0 +1,28 @@
+-- =============================================================================
+-- Dropping deleted_at would turn anonymized accounts back into active ones, and
+-- their personal data is gone for good, so refuse to roll back once any exist
+-- =============================================================================
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE deleted_at IS NOT NULL) THEN
+        RAISE EXCEPTION 'cannot roll back: anonymized users exist and would become active again';
+    END IF;
+END
+$$;
+
+ALTER TABLE reviews
+    DROP CONSTRAINT reviews_user_id_fkey;
+
+ALTER TABLE reviews
+    ADD CONSTRAINT reviews_user_id_fkey
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE orders
+    DROP CONSTRAINT orders_user_id_fkey;
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_user_id_fkey
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS deleted_at;
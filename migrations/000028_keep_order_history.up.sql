@@ -0,0 +1,23 @@
+-- =============================================================================
+-- Deleted accounts are anonymized rather than removed, so restaurants keep
+-- their order history and reviews
+-- =============================================================================
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- =============================================================================
+-- Stop deleting a user from wiping their orders and reviews
+-- =============================================================================
+ALTER TABLE orders
+    DROP CONSTRAINT orders_user_id_fkey;
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_user_id_fkey
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE reviews
+    DROP CONSTRAINT reviews_user_id_fkey;
+
+ALTER TABLE reviews
+    ADD CONSTRAINT reviews_user_id_fkey
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
@@ -0,0 +1,6 @@
+-- =============================================================================
+-- An email change waits here until the new address is confirmed, and the
+-- current email keeps working in the meantime
+-- =============================================================================
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS pending_email citext NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+-- =============================================================================
+-- Only delivery orders have a destination. Clear the address and coordinates
+-- of pickup and dine-in orders accepted before this was enforced
+-- =============================================================================
+UPDATE orders
+SET address = '', latitude = 0, longitude = 0
+WHERE fulfilment_type <> 'delivery' AND (address <> '' OR latitude <> 0 OR longitude <> 0);
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_destination_check
+        CHECK (fulfilment_type = 'delivery' OR (address = '' AND latitude = 0 AND longitude = 0));
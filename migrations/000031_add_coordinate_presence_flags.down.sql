@@ -0,0 +1,12 @@
+ALTER TABLE orders
+    DROP CONSTRAINT IF EXISTS orders_destination_check;
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_destination_check
+        CHECK (fulfilment_type = 'delivery' OR (address = '' AND latitude = 0 AND longitude = 0));
+
+ALTER TABLE user_addresses
+    DROP COLUMN IF EXISTS has_coordinates;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS has_destination;
@@ -0,0 +1,25 @@
+-- =============================================================================
+-- Record whether coordinates were given instead of treating 0 as absent, so
+-- (0, 0) is a real point and a lone zero latitude no longer counts as one.
+-- Existing rows are backfilled from the old non-zero convention
+-- =============================================================================
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS has_destination BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE orders
+SET has_destination = TRUE
+WHERE latitude <> 0 OR longitude <> 0;
+
+ALTER TABLE user_addresses
+    ADD COLUMN IF NOT EXISTS has_coordinates BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE user_addresses
+SET has_coordinates = TRUE
+WHERE latitude <> 0 OR longitude <> 0;
+
+ALTER TABLE orders
+    DROP CONSTRAINT IF EXISTS orders_destination_check;
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_destination_check
+        CHECK (fulfilment_type = 'delivery' OR (address = '' AND NOT has_destination));
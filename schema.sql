@@ -2,7 +2,7 @@
 -- PostgreSQL database dump
 --
 
-\restrict H2HaMyFJnmrJzGZ7zQ0u7L1Ru53XQNYMUuJfvkl55UvfreaO9FRcX1C00wnWca9
+\restrict bu6taqq0m6V7jT2w0IQcPRiYhv7CpeIDNIPQvqGhiINQnV49w5B1wXZstYuABwb
 
 -- Dumped from database version 17.10
 -- Dumped by pg_dump version 17.10
@@ -33,6 +33,25 @@ CREATE EXTENSION IF NOT EXISTS citext WITH SCHEMA public;
 COMMENT ON EXTENSION citext IS 'data type for case-insensitive character strings';
 
 
+--
+-- Name: record_order_status(); Type: FUNCTION; Schema: public; Owner: dockerfood
+--
+
+CREATE FUNCTION public.record_order_status() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.status IS DISTINCT FROM OLD.status THEN
+        INSERT INTO order_status_history (order_id, status, changed_at)
+        VALUES (NEW.id, NEW.status, NOW());
+    END IF;
+    RETURN NEW;
+END;
+$$;
+
+
+ALTER FUNCTION public.record_order_status() OWNER TO dockerfood;
+
 --
 -- Name: set_updated_at(); Type: FUNCTION; Schema: public; Owner: dockerfood
 --
@@ -53,6 +72,182 @@ SET default_tablespace = '';
 
 SET default_table_access_method = heap;
 
+--
+-- Name: audit_log; Type: TABLE; Schema: public; Owner: dockerfood
+--
+
+CREATE TABLE public.audit_log (
+    id bigint NOT NULL,
+    actor_id bigint,
+    action text NOT NULL,
+    target_type text NOT NULL,
+    target_id bigint NOT NULL,
+    before jsonb,
+    after jsonb,
+    ip text DEFAULT ''::text NOT NULL,
+    created_at timestamp(0) with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.audit_log OWNER TO dockerfood;
+
+--
+-- Name: audit_log_id_seq; Type: SEQUENCE; Schema: public; Owner: dockerfood
+--
+
+CREATE SEQUENCE public.audit_log_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+ALTER SEQUENCE public.audit_log_id_seq OWNER TO dockerfood;
+
+--
+-- Name: audit_log_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: dockerfood
+--
+
+ALTER SEQUENCE public.audit_log_id_seq OWNED BY public.audit_log.id;
+
+
+--
+-- Name: courier_locations; Type: TABLE; Schema: public; Owner: dockerfood
+--
+
+CREATE TABLE public.courier_locations (
+    id bigint NOT NULL,
+    order_id bigint NOT NULL,
+    courier_id bigint NOT NULL,
+    latitude numeric(9,6) NOT NULL,
+    longitude numeric(9,6) NOT NULL,
+    recorded_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.courier_locations OWNER TO dockerfood;
+
+--
+-- Name: courier_locations_id_seq; Type: SEQUENCE; Schema: public; Owner: dockerfood
+--
+
+CREATE SEQUENCE public.courier_locations_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+ALTER SEQUENCE public.courier_locations_id_seq OWNER TO dockerfood;
+
+--
+-- Name: courier_locations_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: dockerfood
+--
+
+ALTER SEQUENCE public.courier_locations_id_seq OWNED BY public.courier_locations.id;
+
+
+--
+-- Name: delivery_zones; Type: TABLE; Schema: public; Owner: dockerfood
+--
+
+CREATE TABLE public.delivery_zones (
+    id bigint NOT NULL,
+    restaurant_id bigint NOT NULL,
+    name text NOT NULL,
+    kind text NOT NULL,
+    polygon jsonb DEFAULT '[]'::jsonb NOT NULL,
+    center_latitude numeric(9,6) DEFAULT 0 NOT NULL,
+    center_longitude numeric(9,6) DEFAULT 0 NOT NULL,
+    radius_meters integer DEFAULT 0 NOT NULL,
+    fee bigint DEFAULT 0 NOT NULL,
+    minimum_order bigint DEFAULT 0 NOT NULL,
+    created_at timestamp(0) with time zone DEFAULT now() NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    CONSTRAINT delivery_zones_fee_check CHECK ((fee >= 0)),
+    CONSTRAINT delivery_zones_kind_check CHECK ((kind = ANY (ARRAY['polygon'::text, 'circle'::text]))),
+    CONSTRAINT delivery_zones_minimum_order_check CHECK ((minimum_order >= 0)),
+    CONSTRAINT delivery_zones_shape_check CHECK ((((kind = 'polygon'::text) AND (jsonb_array_length(polygon) >= 3)) OR ((kind = 'circle'::text) AND (radius_meters > 0))))
+);
+
+
+ALTER TABLE public.delivery_zones OWNER TO dockerfood;
+
+--
+-- Name: delivery_zones_id_seq; Type: SEQUENCE; Schema: public; Owner: dockerfood
+--
+
+CREATE SEQUENCE public.delivery_zones_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+ALTER SEQUENCE public.delivery_zones_id_seq OWNER TO dockerfood;
+
+--
+-- Name: delivery_zones_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: dockerfood
+--
+
+ALTER SEQUENCE public.delivery_zones_id_seq OWNED BY public.delivery_zones.id;
+
+
+--
+-- Name: dish_images; Type: TABLE; Schema: public; Owner: dockerfood
+--
+
+CREATE TABLE public.dish_images (
+    id bigint NOT NULL,
+    dish_id bigint NOT NULL,
+    key text NOT NULL,
+    caption text DEFAULT ''::text NOT NULL,
+    "position" integer NOT NULL,
+    is_primary boolean DEFAULT false NOT NULL,
+    created_at timestamp(0) with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.dish_images OWNER TO dockerfood;
+
+--
+-- Name: dish_images_id_seq; Type: SEQUENCE; Schema: public; Owner: dockerfood
+--
+
+CREATE SEQUENCE public.dish_images_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+ALTER SEQUENCE public.dish_images_id_seq OWNER TO dockerfood;
+
+--
+-- Name: dish_images_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: dockerfood
+--
+
+ALTER SEQUENCE public.dish_images_id_seq OWNED BY public.dish_images.id;
+
+
+--
+-- Name: dish_reviews; Type: TABLE; Schema: public; Owner: dockerfood
+--
+
+CREATE TABLE public.dish_reviews (
+    review_id bigint NOT NULL,
+    dish_id bigint NOT NULL,
+    rating smallint NOT NULL,
+    CONSTRAINT dish_reviews_rating_check CHECK (((rating >= 1) AND (rating <= 5)))
+);
+
+
+ALTER TABLE public.dish_reviews OWNER TO dockerfood;
+
 --
 -- Name: dishes; Type: TABLE; Schema: public; Owner: dockerfood
 --
@@ -96,6 +291,32 @@ ALTER SEQUENCE public.dishes_id_seq OWNER TO dockerfood;
 ALTER SEQUENCE public.dishes_id_seq OWNED BY public.dishes.id;
 
 
+--
+-- Name: favorite_dishes; Type: TABLE; Schema: public; Owner: dockerfood
+--
+
+CREATE TABLE public.favorite_dishes (
+    user_id bigint NOT NULL,
+    dish_id bigint NOT NULL,
+    created_at timestamp(0) with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.favorite_dishes OWNER TO dockerfood;
+
+--
+-- Name: favorite_restaurants; Type: TABLE; Schema: public; Owner: dockerfood
+--
+
+CREATE TABLE public.favorite_restaurants (
+    user_id bigint NOT NULL,
+    restaurant_id bigint NOT NULL,
+    created_at timestamp(0) with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.favorite_restaurants OWNER TO dockerfood;
+
 --
 -- Name: order_items; Type: TABLE; Schema: public; Owner: dockerfood
 --
@@ -103,7 +324,7 @@ ALTER SEQUENCE public.dishes_id_seq OWNED BY public.dishes.id;
 CREATE TABLE public.order_items (
     id bigint NOT NULL,
     order_id bigint NOT NULL,
-    dish_id bigint NOT NULL,
+    dish_id bigint,
     quantity integer NOT NULL,
     subtotal bigint NOT NULL,
     dish_name text NOT NULL,
@@ -134,6 +355,41 @@ ALTER SEQUENCE public.order_items_id_seq OWNER TO dockerfood;
 ALTER SEQUENCE public.order_items_id_seq OWNED BY public.order_items.id;
 
 
+--
+-- Name: order_status_history; Type: TABLE; Schema: public; Owner: dockerfood
+--
+
+CREATE TABLE public.order_status_history (
+    id bigint NOT NULL,
+    order_id bigint NOT NULL,
+    status text NOT NULL,
+    changed_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.order_status_history OWNER TO dockerfood;
+
+--
+-- Name: order_status_history_id_seq; Type: SEQUENCE; Schema: public; Owner: dockerfood
+--
+
+CREATE SEQUENCE public.order_status_history_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+ALTER SEQUENCE public.order_status_history_id_seq OWNER TO dockerfood;
+
+--
+-- Name: order_status_history_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: dockerfood
+--
+
+ALTER SEQUENCE public.order_status_history_id_seq OWNED BY public.order_status_history.id;
+
+
 --
 -- Name: orders; Type: TABLE; Schema: public; Owner: dockerfood
 --
@@ -147,7 +403,19 @@ CREATE TABLE public.orders (
     created_at timestamp(0) with time zone DEFAULT now() NOT NULL,
     status text NOT NULL,
     updated_at timestamp(0) with time zone DEFAULT now() NOT NULL,
-    CONSTRAINT orders_status_check CHECK ((status = ANY (ARRAY['pending'::text, 'confirmed'::text, 'preparing'::text, 'ready'::text, 'delivered'::text, 'cancelled'::text])))
+    fulfilment_type text DEFAULT 'delivery'::text NOT NULL,
+    table_number integer DEFAULT 0 NOT NULL,
+    courier_id bigint,
+    latitude numeric(9,6) DEFAULT 0 NOT NULL,
+    longitude numeric(9,6) DEFAULT 0 NOT NULL,
+    delivery_instructions text DEFAULT ''::text NOT NULL,
+    delivery_fee bigint DEFAULT 0 NOT NULL,
+    minimum_order bigint DEFAULT 0 NOT NULL,
+    has_destination boolean DEFAULT false NOT NULL,
+    CONSTRAINT orders_destination_check CHECK (((fulfilment_type = 'delivery'::text) OR ((address = ''::text) AND (NOT has_destination)))),
+    CONSTRAINT orders_fulfilment_details_check CHECK ((((fulfilment_type <> 'delivery'::text) OR (address <> ''::text)) AND ((fulfilment_type <> 'dine_in'::text) OR (table_number > 0)))),
+    CONSTRAINT orders_fulfilment_type_check CHECK ((fulfilment_type = ANY (ARRAY['delivery'::text, 'pickup'::text, 'dine_in'::text]))),
+    CONSTRAINT orders_status_check CHECK ((status = ANY (ARRAY['pending'::text, 'confirmed'::text, 'preparing'::text, 'ready'::text, 'out_for_delivery'::text, 'delivered'::text, 'picked_up'::text, 'served'::text, 'cancelled'::text])))
 );
 
 
@@ -207,6 +475,44 @@ ALTER SEQUENCE public.permissions_id_seq OWNER TO dockerfood;
 ALTER SEQUENCE public.permissions_id_seq OWNED BY public.permissions.id;
 
 
+--
+-- Name: restaurant_images; Type: TABLE; Schema: public; Owner: dockerfood
+--
+
+CREATE TABLE public.restaurant_images (
+    id bigint NOT NULL,
+    restaurant_id bigint NOT NULL,
+    key text NOT NULL,
+    caption text DEFAULT ''::text NOT NULL,
+    "position" integer NOT NULL,
+    is_primary boolean DEFAULT false NOT NULL,
+    created_at timestamp(0) with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.restaurant_images OWNER TO dockerfood;
+
+--
+-- Name: restaurant_images_id_seq; Type: SEQUENCE; Schema: public; Owner: dockerfood
+--
+
+CREATE SEQUENCE public.restaurant_images_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+ALTER SEQUENCE public.restaurant_images_id_seq OWNER TO dockerfood;
+
+--
+-- Name: restaurant_images_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: dockerfood
+--
+
+ALTER SEQUENCE public.restaurant_images_id_seq OWNED BY public.restaurant_images.id;
+
+
 --
 -- Name: restaurant_staff; Type: TABLE; Schema: public; Owner: dockerfood
 --
@@ -263,6 +569,50 @@ ALTER SEQUENCE public.restaurants_id_seq OWNER TO dockerfood;
 ALTER SEQUENCE public.restaurants_id_seq OWNED BY public.restaurants.id;
 
 
+--
+-- Name: reviews; Type: TABLE; Schema: public; Owner: dockerfood
+--
+
+CREATE TABLE public.reviews (
+    id bigint NOT NULL,
+    order_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    restaurant_id bigint NOT NULL,
+    rating smallint NOT NULL,
+    comment text DEFAULT ''::text NOT NULL,
+    reply text DEFAULT ''::text NOT NULL,
+    replied_at timestamp(0) with time zone,
+    hidden boolean DEFAULT false NOT NULL,
+    hidden_reason text DEFAULT ''::text NOT NULL,
+    created_at timestamp(0) with time zone DEFAULT now() NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    CONSTRAINT reviews_rating_check CHECK (((rating >= 1) AND (rating <= 5)))
+);
+
+
+ALTER TABLE public.reviews OWNER TO dockerfood;
+
+--
+-- Name: reviews_id_seq; Type: SEQUENCE; Schema: public; Owner: dockerfood
+--
+
+CREATE SEQUENCE public.reviews_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+ALTER SEQUENCE public.reviews_id_seq OWNER TO dockerfood;
+
+--
+-- Name: reviews_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: dockerfood
+--
+
+ALTER SEQUENCE public.reviews_id_seq OWNED BY public.reviews.id;
+
+
 --
 -- Name: schema_migrations; Type: TABLE; Schema: public; Owner: dockerfood
 --
@@ -289,6 +639,50 @@ CREATE TABLE public.tokens (
 
 ALTER TABLE public.tokens OWNER TO dockerfood;
 
+--
+-- Name: user_addresses; Type: TABLE; Schema: public; Owner: dockerfood
+--
+
+CREATE TABLE public.user_addresses (
+    id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    label text NOT NULL,
+    street text NOT NULL,
+    city text NOT NULL,
+    postal_code text DEFAULT ''::text NOT NULL,
+    latitude numeric(9,6) DEFAULT 0 NOT NULL,
+    longitude numeric(9,6) DEFAULT 0 NOT NULL,
+    instructions text DEFAULT ''::text NOT NULL,
+    is_default boolean DEFAULT false NOT NULL,
+    created_at timestamp(0) with time zone DEFAULT now() NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    has_coordinates boolean DEFAULT false NOT NULL
+);
+
+
+ALTER TABLE public.user_addresses OWNER TO dockerfood;
+
+--
+-- Name: user_addresses_id_seq; Type: SEQUENCE; Schema: public; Owner: dockerfood
+--
+
+CREATE SEQUENCE public.user_addresses_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+ALTER SEQUENCE public.user_addresses_id_seq OWNER TO dockerfood;
+
+--
+-- Name: user_addresses_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: dockerfood
+--
+
+ALTER SEQUENCE public.user_addresses_id_seq OWNED BY public.user_addresses.id;
+
+
 --
 -- Name: users; Type: TABLE; Schema: public; Owner: dockerfood
 --
@@ -303,7 +697,14 @@ CREATE TABLE public.users (
     activated boolean NOT NULL,
     version integer DEFAULT 1 NOT NULL,
     role text NOT NULL,
-    CONSTRAINT users_role_check CHECK ((role = ANY (ARRAY['customer'::text, 'admin'::text])))
+    status text DEFAULT 'active'::text NOT NULL,
+    status_reason text DEFAULT ''::text NOT NULL,
+    suspended_until timestamp with time zone,
+    deleted_at timestamp with time zone,
+    pending_email public.citext DEFAULT ''::public.citext NOT NULL,
+    CONSTRAINT users_role_check CHECK ((role = ANY (ARRAY['customer'::text, 'admin'::text, 'courier'::text]))),
+    CONSTRAINT users_status_check CHECK ((status = ANY (ARRAY['active'::text, 'suspended'::text, 'banned'::text]))),
+    CONSTRAINT users_suspended_until_check CHECK (((suspended_until IS NULL) OR (status = 'suspended'::text)))
 );
 
 
@@ -343,33 +744,75 @@ CREATE TABLE public.users_permissions (
 ALTER TABLE public.users_permissions OWNER TO dockerfood;
 
 --
--- Name: dishes id; Type: DEFAULT; Schema: public; Owner: dockerfood
+-- Name: audit_log id; Type: DEFAULT; Schema: public; Owner: dockerfood
 --
 
-ALTER TABLE ONLY public.dishes ALTER COLUMN id SET DEFAULT nextval('public.dishes_id_seq'::regclass);
+ALTER TABLE ONLY public.audit_log ALTER COLUMN id SET DEFAULT nextval('public.audit_log_id_seq'::regclass);
 
 
 --
--- Name: order_items id; Type: DEFAULT; Schema: public; Owner: dockerfood
+-- Name: courier_locations id; Type: DEFAULT; Schema: public; Owner: dockerfood
 --
 
-ALTER TABLE ONLY public.order_items ALTER COLUMN id SET DEFAULT nextval('public.order_items_id_seq'::regclass);
+ALTER TABLE ONLY public.courier_locations ALTER COLUMN id SET DEFAULT nextval('public.courier_locations_id_seq'::regclass);
 
 
 --
--- Name: orders id; Type: DEFAULT; Schema: public; Owner: dockerfood
+-- Name: delivery_zones id; Type: DEFAULT; Schema: public; Owner: dockerfood
 --
 
-ALTER TABLE ONLY public.orders ALTER COLUMN id SET DEFAULT nextval('public.orders_id_seq'::regclass);
+ALTER TABLE ONLY public.delivery_zones ALTER COLUMN id SET DEFAULT nextval('public.delivery_zones_id_seq'::regclass);
 
 
 --
--- Name: permissions id; Type: DEFAULT; Schema: public; Owner: dockerfood
+-- Name: dish_images id; Type: DEFAULT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.dish_images ALTER COLUMN id SET DEFAULT nextval('public.dish_images_id_seq'::regclass);
+
+
+--
+-- Name: dishes id; Type: DEFAULT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.dishes ALTER COLUMN id SET DEFAULT nextval('public.dishes_id_seq'::regclass);
+
+
+--
+-- Name: order_items id; Type: DEFAULT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.order_items ALTER COLUMN id SET DEFAULT nextval('public.order_items_id_seq'::regclass);
+
+
+--
+-- Name: order_status_history id; Type: DEFAULT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.order_status_history ALTER COLUMN id SET DEFAULT nextval('public.order_status_history_id_seq'::regclass);
+
+
+--
+-- Name: orders id; Type: DEFAULT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.orders ALTER COLUMN id SET DEFAULT nextval('public.orders_id_seq'::regclass);
+
+
+--
+-- Name: permissions id; Type: DEFAULT; Schema: public; Owner: dockerfood
 --
 
 ALTER TABLE ONLY public.permissions ALTER COLUMN id SET DEFAULT nextval('public.permissions_id_seq'::regclass);
 
 
+--
+-- Name: restaurant_images id; Type: DEFAULT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.restaurant_images ALTER COLUMN id SET DEFAULT nextval('public.restaurant_images_id_seq'::regclass);
+
+
 --
 -- Name: restaurants id; Type: DEFAULT; Schema: public; Owner: dockerfood
 --
@@ -377,6 +820,20 @@ ALTER TABLE ONLY public.permissions ALTER COLUMN id SET DEFAULT nextval('public.
 ALTER TABLE ONLY public.restaurants ALTER COLUMN id SET DEFAULT nextval('public.restaurants_id_seq'::regclass);
 
 
+--
+-- Name: reviews id; Type: DEFAULT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.reviews ALTER COLUMN id SET DEFAULT nextval('public.reviews_id_seq'::regclass);
+
+
+--
+-- Name: user_addresses id; Type: DEFAULT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.user_addresses ALTER COLUMN id SET DEFAULT nextval('public.user_addresses_id_seq'::regclass);
+
+
 --
 -- Name: users id; Type: DEFAULT; Schema: public; Owner: dockerfood
 --
@@ -384,6 +841,54 @@ ALTER TABLE ONLY public.restaurants ALTER COLUMN id SET DEFAULT nextval('public.
 ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
 
 
+--
+-- Name: audit_log audit_log_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.audit_log
+    ADD CONSTRAINT audit_log_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: courier_locations courier_locations_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.courier_locations
+    ADD CONSTRAINT courier_locations_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: delivery_zones delivery_zones_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.delivery_zones
+    ADD CONSTRAINT delivery_zones_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: dish_images dish_images_dish_id_position_key; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.dish_images
+    ADD CONSTRAINT dish_images_dish_id_position_key UNIQUE (dish_id, "position") DEFERRABLE INITIALLY DEFERRED;
+
+
+--
+-- Name: dish_images dish_images_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.dish_images
+    ADD CONSTRAINT dish_images_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: dish_reviews dish_reviews_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.dish_reviews
+    ADD CONSTRAINT dish_reviews_pkey PRIMARY KEY (review_id, dish_id);
+
+
 --
 -- Name: dishes dishes_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
 --
@@ -392,6 +897,22 @@ ALTER TABLE ONLY public.dishes
     ADD CONSTRAINT dishes_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: favorite_dishes favorite_dishes_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.favorite_dishes
+    ADD CONSTRAINT favorite_dishes_pkey PRIMARY KEY (user_id, dish_id);
+
+
+--
+-- Name: favorite_restaurants favorite_restaurants_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.favorite_restaurants
+    ADD CONSTRAINT favorite_restaurants_pkey PRIMARY KEY (user_id, restaurant_id);
+
+
 --
 -- Name: order_items order_items_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
 --
@@ -400,6 +921,14 @@ ALTER TABLE ONLY public.order_items
     ADD CONSTRAINT order_items_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: order_status_history order_status_history_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.order_status_history
+    ADD CONSTRAINT order_status_history_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: orders orders_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
 --
@@ -416,6 +945,22 @@ ALTER TABLE ONLY public.permissions
     ADD CONSTRAINT permissions_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: restaurant_images restaurant_images_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.restaurant_images
+    ADD CONSTRAINT restaurant_images_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: restaurant_images restaurant_images_restaurant_id_position_key; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.restaurant_images
+    ADD CONSTRAINT restaurant_images_restaurant_id_position_key UNIQUE (restaurant_id, "position") DEFERRABLE INITIALLY DEFERRED;
+
+
 --
 -- Name: restaurant_staff restaurant_staff_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
 --
@@ -432,6 +977,22 @@ ALTER TABLE ONLY public.restaurants
     ADD CONSTRAINT restaurants_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: reviews reviews_order_id_key; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.reviews
+    ADD CONSTRAINT reviews_order_id_key UNIQUE (order_id);
+
+
+--
+-- Name: reviews reviews_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.reviews
+    ADD CONSTRAINT reviews_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: schema_migrations schema_migrations_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
 --
@@ -448,6 +1009,14 @@ ALTER TABLE ONLY public.tokens
     ADD CONSTRAINT tokens_pkey PRIMARY KEY (hash);
 
 
+--
+-- Name: user_addresses user_addresses_pkey; Type: CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.user_addresses
+    ADD CONSTRAINT user_addresses_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: users users_email_key; Type: CONSTRAINT; Schema: public; Owner: dockerfood
 --
@@ -472,6 +1041,62 @@ ALTER TABLE ONLY public.users
     ADD CONSTRAINT users_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: audit_log_action_created_at_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX audit_log_action_created_at_idx ON public.audit_log USING btree (action, created_at);
+
+
+--
+-- Name: audit_log_actor_id_created_at_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX audit_log_actor_id_created_at_idx ON public.audit_log USING btree (actor_id, created_at);
+
+
+--
+-- Name: audit_log_created_at_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX audit_log_created_at_idx ON public.audit_log USING btree (created_at);
+
+
+--
+-- Name: courier_locations_order_id_recorded_at_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX courier_locations_order_id_recorded_at_idx ON public.courier_locations USING btree (order_id, recorded_at DESC);
+
+
+--
+-- Name: courier_locations_recorded_at_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX courier_locations_recorded_at_idx ON public.courier_locations USING btree (recorded_at);
+
+
+--
+-- Name: delivery_zones_restaurant_id_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX delivery_zones_restaurant_id_idx ON public.delivery_zones USING btree (restaurant_id);
+
+
+--
+-- Name: dish_images_primary_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE UNIQUE INDEX dish_images_primary_idx ON public.dish_images USING btree (dish_id) WHERE is_primary;
+
+
+--
+-- Name: dish_reviews_dish_id_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX dish_reviews_dish_id_idx ON public.dish_reviews USING btree (dish_id, review_id, rating);
+
+
 --
 -- Name: dishes_categories_idx; Type: INDEX; Schema: public; Owner: dockerfood
 --
@@ -494,10 +1119,52 @@ CREATE INDEX dishes_restaurant_id_idx ON public.dishes USING btree (restaurant_i
 
 
 --
--- Name: order_items_order_id_idx; Type: INDEX; Schema: public; Owner: dockerfood
+-- Name: favorite_dishes_dish_id_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX favorite_dishes_dish_id_idx ON public.favorite_dishes USING btree (dish_id);
+
+
+--
+-- Name: favorite_restaurants_restaurant_id_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX favorite_restaurants_restaurant_id_idx ON public.favorite_restaurants USING btree (restaurant_id);
+
+
+--
+-- Name: order_items_order_id_id_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX order_items_order_id_id_idx ON public.order_items USING btree (order_id, id);
+
+
+--
+-- Name: order_status_history_order_id_changed_at_idx; Type: INDEX; Schema: public; Owner: dockerfood
 --
 
-CREATE INDEX order_items_order_id_idx ON public.order_items USING btree (order_id);
+CREATE INDEX order_status_history_order_id_changed_at_idx ON public.order_status_history USING btree (order_id, changed_at);
+
+
+--
+-- Name: orders_courier_id_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX orders_courier_id_idx ON public.orders USING btree (courier_id);
+
+
+--
+-- Name: orders_restaurant_id_created_at_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX orders_restaurant_id_created_at_idx ON public.orders USING btree (restaurant_id, created_at);
+
+
+--
+-- Name: orders_restaurant_id_fulfilment_type_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX orders_restaurant_id_fulfilment_type_idx ON public.orders USING btree (restaurant_id, fulfilment_type);
 
 
 --
@@ -514,6 +1181,13 @@ CREATE INDEX orders_restaurant_id_idx ON public.orders USING btree (restaurant_i
 CREATE INDEX orders_status_idx ON public.orders USING btree (status);
 
 
+--
+-- Name: orders_unclaimed_delivery_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX orders_unclaimed_delivery_idx ON public.orders USING btree (id) WHERE ((status = 'ready'::text) AND (fulfilment_type = 'delivery'::text) AND (courier_id IS NULL));
+
+
 --
 -- Name: orders_user_id_idx; Type: INDEX; Schema: public; Owner: dockerfood
 --
@@ -521,6 +1195,20 @@ CREATE INDEX orders_status_idx ON public.orders USING btree (status);
 CREATE INDEX orders_user_id_idx ON public.orders USING btree (user_id);
 
 
+--
+-- Name: restaurant_images_primary_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE UNIQUE INDEX restaurant_images_primary_idx ON public.restaurant_images USING btree (restaurant_id) WHERE is_primary;
+
+
+--
+-- Name: reviews_restaurant_id_rating_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX reviews_restaurant_id_rating_idx ON public.reviews USING btree (restaurant_id, rating) WHERE (NOT hidden);
+
+
 --
 -- Name: tokens_user_id_scope_idx; Type: INDEX; Schema: public; Owner: dockerfood
 --
@@ -528,6 +1216,20 @@ CREATE INDEX orders_user_id_idx ON public.orders USING btree (user_id);
 CREATE INDEX tokens_user_id_scope_idx ON public.tokens USING btree (user_id, scope);
 
 
+--
+-- Name: user_addresses_one_default_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE UNIQUE INDEX user_addresses_one_default_idx ON public.user_addresses USING btree (user_id) WHERE is_default;
+
+
+--
+-- Name: user_addresses_user_id_idx; Type: INDEX; Schema: public; Owner: dockerfood
+--
+
+CREATE INDEX user_addresses_user_id_idx ON public.user_addresses USING btree (user_id);
+
+
 --
 -- Name: dishes dishes_set_updated_at; Type: TRIGGER; Schema: public; Owner: dockerfood
 --
@@ -535,6 +1237,13 @@ CREATE INDEX tokens_user_id_scope_idx ON public.tokens USING btree (user_id, sco
 CREATE TRIGGER dishes_set_updated_at BEFORE UPDATE ON public.dishes FOR EACH ROW EXECUTE FUNCTION public.set_updated_at();
 
 
+--
+-- Name: orders orders_record_status; Type: TRIGGER; Schema: public; Owner: dockerfood
+--
+
+CREATE TRIGGER orders_record_status AFTER INSERT OR UPDATE OF status ON public.orders FOR EACH ROW EXECUTE FUNCTION public.record_order_status();
+
+
 --
 -- Name: orders orders_set_updated_at; Type: TRIGGER; Schema: public; Owner: dockerfood
 --
@@ -542,6 +1251,62 @@ CREATE TRIGGER dishes_set_updated_at BEFORE UPDATE ON public.dishes FOR EACH ROW
 CREATE TRIGGER orders_set_updated_at BEFORE UPDATE ON public.orders FOR EACH ROW EXECUTE FUNCTION public.set_updated_at();
 
 
+--
+-- Name: audit_log audit_log_actor_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.audit_log
+    ADD CONSTRAINT audit_log_actor_id_fkey FOREIGN KEY (actor_id) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: courier_locations courier_locations_courier_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.courier_locations
+    ADD CONSTRAINT courier_locations_courier_id_fkey FOREIGN KEY (courier_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: courier_locations courier_locations_order_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.courier_locations
+    ADD CONSTRAINT courier_locations_order_id_fkey FOREIGN KEY (order_id) REFERENCES public.orders(id) ON DELETE CASCADE;
+
+
+--
+-- Name: delivery_zones delivery_zones_restaurant_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.delivery_zones
+    ADD CONSTRAINT delivery_zones_restaurant_id_fkey FOREIGN KEY (restaurant_id) REFERENCES public.restaurants(id) ON DELETE CASCADE;
+
+
+--
+-- Name: dish_images dish_images_dish_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.dish_images
+    ADD CONSTRAINT dish_images_dish_id_fkey FOREIGN KEY (dish_id) REFERENCES public.dishes(id) ON DELETE CASCADE;
+
+
+--
+-- Name: dish_reviews dish_reviews_dish_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.dish_reviews
+    ADD CONSTRAINT dish_reviews_dish_id_fkey FOREIGN KEY (dish_id) REFERENCES public.dishes(id) ON DELETE CASCADE;
+
+
+--
+-- Name: dish_reviews dish_reviews_review_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.dish_reviews
+    ADD CONSTRAINT dish_reviews_review_id_fkey FOREIGN KEY (review_id) REFERENCES public.reviews(id) ON DELETE CASCADE;
+
+
 --
 -- Name: dishes dishes_restaurant_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
 --
@@ -550,12 +1315,44 @@ ALTER TABLE ONLY public.dishes
     ADD CONSTRAINT dishes_restaurant_id_fkey FOREIGN KEY (restaurant_id) REFERENCES public.restaurants(id) ON DELETE CASCADE;
 
 
+--
+-- Name: favorite_dishes favorite_dishes_dish_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.favorite_dishes
+    ADD CONSTRAINT favorite_dishes_dish_id_fkey FOREIGN KEY (dish_id) REFERENCES public.dishes(id) ON DELETE CASCADE;
+
+
+--
+-- Name: favorite_dishes favorite_dishes_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.favorite_dishes
+    ADD CONSTRAINT favorite_dishes_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: favorite_restaurants favorite_restaurants_restaurant_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.favorite_restaurants
+    ADD CONSTRAINT favorite_restaurants_restaurant_id_fkey FOREIGN KEY (restaurant_id) REFERENCES public.restaurants(id) ON DELETE CASCADE;
+
+
+--
+-- Name: favorite_restaurants favorite_restaurants_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.favorite_restaurants
+    ADD CONSTRAINT favorite_restaurants_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
 --
 -- Name: order_items order_items_dish_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
 --
 
 ALTER TABLE ONLY public.order_items
-    ADD CONSTRAINT order_items_dish_id_fkey FOREIGN KEY (dish_id) REFERENCES public.dishes(id) ON DELETE CASCADE;
+    ADD CONSTRAINT order_items_dish_id_fkey FOREIGN KEY (dish_id) REFERENCES public.dishes(id) ON DELETE SET NULL;
 
 
 --
@@ -566,6 +1363,22 @@ ALTER TABLE ONLY public.order_items
     ADD CONSTRAINT order_items_order_id_fkey FOREIGN KEY (order_id) REFERENCES public.orders(id) ON DELETE CASCADE;
 
 
+--
+-- Name: order_status_history order_status_history_order_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.order_status_history
+    ADD CONSTRAINT order_status_history_order_id_fkey FOREIGN KEY (order_id) REFERENCES public.orders(id) ON DELETE CASCADE;
+
+
+--
+-- Name: orders orders_courier_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_courier_id_fkey FOREIGN KEY (courier_id) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
 --
 -- Name: orders orders_restaurant_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
 --
@@ -579,7 +1392,15 @@ ALTER TABLE ONLY public.orders
 --
 
 ALTER TABLE ONLY public.orders
-    ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+    ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE RESTRICT;
+
+
+--
+-- Name: restaurant_images restaurant_images_restaurant_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.restaurant_images
+    ADD CONSTRAINT restaurant_images_restaurant_id_fkey FOREIGN KEY (restaurant_id) REFERENCES public.restaurants(id) ON DELETE CASCADE;
 
 
 --
@@ -598,6 +1419,30 @@ ALTER TABLE ONLY public.restaurant_staff
     ADD CONSTRAINT restaurant_staff_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
+--
+-- Name: reviews reviews_order_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.reviews
+    ADD CONSTRAINT reviews_order_id_fkey FOREIGN KEY (order_id) REFERENCES public.orders(id) ON DELETE CASCADE;
+
+
+--
+-- Name: reviews reviews_restaurant_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.reviews
+    ADD CONSTRAINT reviews_restaurant_id_fkey FOREIGN KEY (restaurant_id) REFERENCES public.restaurants(id) ON DELETE CASCADE;
+
+
+--
+-- Name: reviews reviews_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.reviews
+    ADD CONSTRAINT reviews_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE RESTRICT;
+
+
 --
 -- Name: tokens tokens_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
 --
@@ -606,6 +1451,14 @@ ALTER TABLE ONLY public.tokens
     ADD CONSTRAINT tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
+--
+-- Name: user_addresses user_addresses_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
+--
+
+ALTER TABLE ONLY public.user_addresses
+    ADD CONSTRAINT user_addresses_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
 --
 -- Name: users_permissions users_permissions_permission_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: dockerfood
 --
@@ -626,5 +1479,5 @@ ALTER TABLE ONLY public.users_permissions
 -- PostgreSQL database dump complete
 --
 
-\unrestrict H2HaMyFJnmrJzGZ7zQ0u7L1Ru53XQNYMUuJfvkl55UvfreaO9FRcX1C00wnWca9
+\unrestrict bu6taqq0m6V7jT2w0IQcPRiYhv7CpeIDNIPQvqGhiINQnV49w5B1wXZstYuABwb
 